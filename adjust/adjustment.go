@@ -13,23 +13,49 @@ import (
 // Brightness returns a copy of the image with the adjusted brightness.
 // Change is the normalized amount of change to be applied (range -1.0 to 1.0).
 func Brightness(src image.Image, change float64) *image.RGBA {
+	return Apply(src, brightness(change))
+}
+
+// BrightnessInto writes the image with the adjusted brightness into dst, like Brightness, rather than
+// allocating a new image. It panics if dst and src don't have the same size. Dst can be src itself.
+//
+// Usage example:
+//
+//	adjust.BrightnessInto(dst, img, 0.25)
+func BrightnessInto(dst *image.RGBA, src image.Image, change float64) {
+	ApplyInto(dst, src, brightness(change))
+}
+
+// brightness returns the color function adjusting the brightness by the change.
+func brightness(change float64) func(color.RGBA) color.RGBA {
 	lookup := make([]uint8, 256)
 
 	for i := 0; i < 256; i++ {
 		lookup[i] = uint8(f64.Clamp(float64(i)*(1+change), 0, 255))
 	}
 
-	fn := func(c color.RGBA) color.RGBA {
+	return func(c color.RGBA) color.RGBA {
 		return color.RGBA{lookup[c.R], lookup[c.G], lookup[c.B], c.A}
 	}
-
-	img := Apply(src, fn)
-
-	return img
 }
 
 // Gamma returns a gamma corrected copy of the image. Provided gamma param must be larger than 0.
 func Gamma(src image.Image, gamma float64) *image.RGBA {
+	return Apply(src, gammaCorrection(gamma))
+}
+
+// GammaInto writes the gamma corrected image into dst, like Gamma, rather than allocating a new image.
+// It panics if dst and src don't have the same size. Dst can be src itself.
+//
+// Usage example:
+//
+//	adjust.GammaInto(dst, img, 2.2)
+func GammaInto(dst *image.RGBA, src image.Image, gamma float64) {
+	ApplyInto(dst, src, gammaCorrection(gamma))
+}
+
+// gammaCorrection returns the color function correcting the gamma.
+func gammaCorrection(gamma float64) func(color.RGBA) color.RGBA {
 	gamma = math.Max(0.00001, gamma)
 
 	lookup := make([]uint8, 256)
@@ -38,60 +64,145 @@ func Gamma(src image.Image, gamma float64) *image.RGBA {
 		lookup[i] = uint8(f64.Clamp(math.Pow(float64(i)/255, 1.0/gamma)*255, 0, 255))
 	}
 
-	fn := func(c color.RGBA) color.RGBA {
+	return func(c color.RGBA) color.RGBA {
 		return color.RGBA{lookup[c.R], lookup[c.G], lookup[c.B], c.A}
 	}
-
-	img := Apply(src, fn)
-
-	return img
 }
 
 // Contrast returns a copy of the image with its difference in high and low values adjusted by the change param.
 // Change is the normalized amount of change to be applied, in the range of -1.0 to 1.0.
 // If Change is set to 0.0, then the values remain the same, if it's set to 0.5, then all values will be moved 50% away from the middle value.
 func Contrast(src image.Image, change float64) *image.RGBA {
+	return Apply(src, contrast(change))
+}
+
+// ContrastInto writes the image with its contrast adjusted into dst, like Contrast, rather than allocating
+// a new image. It panics if dst and src don't have the same size. Dst can be src itself.
+//
+// Usage example:
+//
+//	adjust.ContrastInto(dst, img, 0.5)
+func ContrastInto(dst *image.RGBA, src image.Image, change float64) {
+	ApplyInto(dst, src, contrast(change))
+}
+
+// contrast returns the color function adjusting the contrast by the change.
+func contrast(change float64) func(color.RGBA) color.RGBA {
 	lookup := make([]uint8, 256)
 
 	for i := 0; i < 256; i++ {
 		lookup[i] = uint8(f64.Clamp(((((float64(i)/255)-0.5)*(1+change))+0.5)*255, 0, 255))
 	}
 
-	fn := func(c color.RGBA) color.RGBA {
+	return func(c color.RGBA) color.RGBA {
 		return color.RGBA{lookup[c.R], lookup[c.G], lookup[c.B], c.A}
 	}
+}
+
+// Brightness64 returns a 16-bit copy of the image with the adjusted brightness, like Brightness
+// but keeping 16 bits per channel.
+// Change is the normalized amount of change to be applied (range -1.0 to 1.0).
+func Brightness64(src image.Image, change float64) *image.RGBA64 {
+	lookup := make([]uint16, 65536)
+
+	for i := 0; i < 65536; i++ {
+		lookup[i] = uint16(f64.Clamp(float64(i)*(1+change), 0, 65535))
+	}
 
-	img := Apply(src, fn)
+	fn := func(c color.RGBA64) color.RGBA64 {
+		return color.RGBA64{lookup[c.R], lookup[c.G], lookup[c.B], c.A}
+	}
 
-	return img
+	return Apply64(src, fn)
+}
+
+// Gamma64 returns a gamma corrected 16-bit copy of the image, like Gamma but keeping 16 bits per channel.
+// Provided gamma param must be larger than 0.
+func Gamma64(src image.Image, gamma float64) *image.RGBA64 {
+	gamma = math.Max(0.00001, gamma)
+
+	lookup := make([]uint16, 65536)
+
+	for i := 0; i < 65536; i++ {
+		lookup[i] = uint16(f64.Clamp(math.Pow(float64(i)/65535, 1.0/gamma)*65535, 0, 65535))
+	}
+
+	fn := func(c color.RGBA64) color.RGBA64 {
+		return color.RGBA64{lookup[c.R], lookup[c.G], lookup[c.B], c.A}
+	}
+
+	return Apply64(src, fn)
+}
+
+// Contrast64 returns a 16-bit copy of the image with its difference in high and low values adjusted by
+// the change param, like Contrast but keeping 16 bits per channel.
+// Change is the normalized amount of change to be applied, in the range of -1.0 to 1.0.
+func Contrast64(src image.Image, change float64) *image.RGBA64 {
+	lookup := make([]uint16, 65536)
+
+	for i := 0; i < 65536; i++ {
+		lookup[i] = uint16(f64.Clamp(((((float64(i)/65535)-0.5)*(1+change))+0.5)*65535, 0, 65535))
+	}
+
+	fn := func(c color.RGBA64) color.RGBA64 {
+		return color.RGBA64{lookup[c.R], lookup[c.G], lookup[c.B], c.A}
+	}
+
+	return Apply64(src, fn)
 }
 
 // Hue adjusts the overall hue of the provided image and returns the result.
 // Parameter change is the amount of change to be applied and is of the range
 // -360 to 360. It corresponds to the hue angle in the HSL color model.
 func Hue(img image.Image, change int) *image.RGBA {
-	fn := func(c color.RGBA) color.RGBA {
+	return Apply(img, hue(change))
+}
+
+// HueInto writes the image with its hue adjusted into dst, like Hue, rather than allocating a new image.
+// It panics if dst and img don't have the same size. Dst can be img itself.
+//
+// Usage example:
+//
+//	adjust.HueInto(dst, img, -90)
+func HueInto(dst *image.RGBA, img image.Image, change int) {
+	ApplyInto(dst, img, hue(change))
+}
+
+// hue returns the color function rotating the hue by the change.
+func hue(change int) func(color.RGBA) color.RGBA {
+	return func(c color.RGBA) color.RGBA {
 		h, s, l := util.RGBToHSL(c)
 		h = float64((int(h) + change) % 360)
 		outColor := util.HSLToRGB(h, s, l)
 		outColor.A = c.A
 		return outColor
 	}
-
-	return Apply(img, fn)
 }
 
 // Saturation adjusts the saturation of the image and returns the result.
 // Parameter change is the amount of change to be applied and is of the range
 // -1.0 to 1.0 (-1.0 being -100% and 1.0 being 100%).
 func Saturation(img image.Image, change float64) *image.RGBA {
-	fn := func(c color.RGBA) color.RGBA {
+	return Apply(img, saturation(change))
+}
+
+// SaturationInto writes the image with its saturation adjusted into dst, like Saturation, rather than
+// allocating a new image. It panics if dst and img don't have the same size. Dst can be img itself.
+//
+// Usage example:
+//
+//	adjust.SaturationInto(dst, img, 0.5)
+func SaturationInto(dst *image.RGBA, img image.Image, change float64) {
+	ApplyInto(dst, img, saturation(change))
+}
+
+// saturation returns the color function adjusting the saturation by the change.
+func saturation(change float64) func(color.RGBA) color.RGBA {
+	return func(c color.RGBA) color.RGBA {
 		h, s, l := util.RGBToHSL(c)
 		s = f64.Clamp(s*(1+change), 0.0, 1.0)
 		outColor := util.HSLToRGB(h, s, l)
 		outColor.A = c.A
 		return outColor
 	}
-
-	return Apply(img, fn)
 }
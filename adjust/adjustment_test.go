@@ -4,6 +4,7 @@ import (
 	"image"
 	"testing"
 
+	"github.com/anthonynsimon/bild/clone"
 	"github.com/anthonynsimon/bild/util"
 )
 
@@ -539,3 +540,82 @@ func TestHue(t *testing.T) {
 		}
 	}
 }
+
+func TestAdjust64(t *testing.T) {
+	src := &image.RGBA{
+		Rect:   image.Rect(0, 0, 2, 2),
+		Stride: 8,
+		Pix: []uint8{
+			0x80, 0x40, 0x20, 0xFF, 0x10, 0xC0, 0xF0, 0xFF,
+			0xFF, 0xFF, 0xFF, 0xFF, 0x0, 0x0, 0x0, 0xFF,
+		},
+	}
+
+	cases := []struct {
+		desc       string
+		adjust64   func(image.Image, float64) *image.RGBA64
+		adjust     func(image.Image, float64) *image.RGBA
+		parameters []float64
+	}{
+		{"Brightness", Brightness64, Brightness, []float64{-0.5, 0, 0.5, 1}},
+		{"Gamma", Gamma64, Gamma, []float64{0.5, 1, 2.2}},
+		{"Contrast", Contrast64, Contrast, []float64{-0.5, 0, 0.5, 1}},
+	}
+
+	// Matches the 8-bit adjustments, up to their rounding
+	for _, c := range cases {
+		for _, p := range c.parameters {
+			actual := c.adjust64(src, p)
+			expected := clone.AsRGBA64(c.adjust(src, p))
+			if !util.RGBA64ImageApproxEqual(actual, expected, 2*0x101) {
+				t.Errorf("%s: expected: %v, actual: %v", c.desc+"64", expected.Pix, actual.Pix)
+			}
+		}
+	}
+
+	// Values between those of 8 bits are kept
+	value := &image.RGBA64{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 16,
+		Pix: []uint8{
+			0x20, 0x01, 0x00, 0x03, 0xC0, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	}
+	expected := &image.RGBA64{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 16,
+		Pix: []uint8{
+			0x30, 0x01, 0x00, 0x04, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	}
+	if actual := Brightness64(value, 0.5); !util.RGBA64ImageEqual(actual, expected) {
+		t.Errorf("%s: expected: %v, actual: %v", "Brightness64 16-bit values", expected.Pix, actual.Pix)
+	}
+}
+
+func TestAdjustmentInto(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 15)
+	}
+
+	cases := []struct {
+		description string
+		expected    *image.RGBA
+		into        func(dst *image.RGBA)
+	}{
+		{"Brightness", Brightness(img, 0.3), func(dst *image.RGBA) { BrightnessInto(dst, img, 0.3) }},
+		{"Gamma", Gamma(img, 2.2), func(dst *image.RGBA) { GammaInto(dst, img, 2.2) }},
+		{"Contrast", Contrast(img, -0.4), func(dst *image.RGBA) { ContrastInto(dst, img, -0.4) }},
+		{"Hue", Hue(img, 90), func(dst *image.RGBA) { HueInto(dst, img, 90) }},
+		{"Saturation", Saturation(img, 0.5), func(dst *image.RGBA) { SaturationInto(dst, img, 0.5) }},
+	}
+
+	for _, c := range cases {
+		dst := image.NewRGBA(img.Bounds())
+		c.into(dst)
+		if !util.RGBAImageEqual(dst, c.expected) {
+			t.Errorf("%s: expected: %v actual: %v", c.description+"Into", c.expected, dst)
+		}
+	}
+}
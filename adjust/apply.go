@@ -3,40 +3,131 @@ package adjust
 import (
 	"image"
 	"image/color"
+	"image/draw"
 
 	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/fcolor"
 	"github.com/anthonynsimon/bild/parallel"
 )
 
 // Apply returns a copy of the provided image after applying the provided color function to each pixel.
 func Apply(img image.Image, fn func(color.RGBA) color.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	ApplyInto(dst, img, fn)
+	return dst
+}
+
+// ApplyInto writes the provided image into dst after applying the provided color function to each pixel,
+// like Apply, rather than allocating a new image. It panics if dst and img don't have the same size.
+// Dst can be img itself, to adjust an image in place.
+//
+// Usage example:
+//
+//	// Inverts the image in place
+//	adjust.ApplyInto(img, img, func(c color.RGBA) color.RGBA {
+//		return color.RGBA{c.A - c.R, c.A - c.G, c.A - c.B, c.A}
+//	})
+func ApplyInto(dst *image.RGBA, img image.Image, fn func(color.RGBA) color.RGBA) {
 	bounds := img.Bounds()
-	dst := clone.AsRGBA(img)
+	if dst.Rect.Size() != bounds.Size() {
+		panic("adjust: destination and source images have different sizes")
+	}
 	w, h := bounds.Dx(), bounds.Dy()
 
+	// Other image types are converted into dst first, which is then adjusted in place
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+		src = dst
+	}
+
 	parallel.Line(h, func(start, end int) {
 		for y := start; y < end; y++ {
 			for x := 0; x < w; x++ {
+				srcPos := y*src.Stride + x*4
 				dstPos := y*dst.Stride + x*4
 
-				c := color.RGBA{}
+				c := color.RGBA{
+					R: src.Pix[srcPos+0],
+					G: src.Pix[srcPos+1],
+					B: src.Pix[srcPos+2],
+					A: src.Pix[srcPos+3],
+				}
+
+				c = fn(c)
+
+				dst.Pix[dstPos+0] = c.R
+				dst.Pix[dstPos+1] = c.G
+				dst.Pix[dstPos+2] = c.B
+				dst.Pix[dstPos+3] = c.A
+			}
+		}
+	})
+}
+
+// Apply64 returns a 16-bit copy of the provided image after applying the provided color function to each pixel,
+// so that images with more than 8 bits per channel keep their precision.
+//
+// Usage example:
+//
+//	// Inverts a 16-bit image
+//	result := adjust.Apply64(img, func(c color.RGBA64) color.RGBA64 {
+//		return color.RGBA64{c.A - c.R, c.A - c.G, c.A - c.B, c.A}
+//	})
+func Apply64(img image.Image, fn func(color.RGBA64) color.RGBA64) *image.RGBA64 {
+	bounds := img.Bounds()
+	dst := clone.AsRGBA64(img)
+	w, h := bounds.Dx(), bounds.Dy()
 
-				dr := &dst.Pix[dstPos+0]
-				dg := &dst.Pix[dstPos+1]
-				db := &dst.Pix[dstPos+2]
-				da := &dst.Pix[dstPos+3]
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				dstPos := y*dst.Stride + x*8
+				s := dst.Pix[dstPos : dstPos+8 : dstPos+8]
 
-				c.R = *dr
-				c.G = *dg
-				c.B = *db
-				c.A = *da
+				c := color.RGBA64{
+					R: uint16(s[0])<<8 | uint16(s[1]),
+					G: uint16(s[2])<<8 | uint16(s[3]),
+					B: uint16(s[4])<<8 | uint16(s[5]),
+					A: uint16(s[6])<<8 | uint16(s[7]),
+				}
 
 				c = fn(c)
 
-				*dr = c.R
-				*dg = c.G
-				*db = c.B
-				*da = c.A
+				s[0], s[1] = uint8(c.R>>8), uint8(c.R)
+				s[2], s[3] = uint8(c.G>>8), uint8(c.G)
+				s[4], s[5] = uint8(c.B>>8), uint8(c.B)
+				s[6], s[7] = uint8(c.A>>8), uint8(c.A)
+			}
+		}
+	})
+
+	return dst
+}
+
+// ApplyF64 returns a copy of the provided float image after applying the provided color function to
+// each pixel. The colors aren't rounded nor clamped, so that chaining adjustments doesn't pile up
+// quantization error.
+//
+// Usage example:
+//
+//	// Doubles the exposure of an HDR image
+//	result := adjust.ApplyF64(fimg, func(c fcolor.RGBAF64) fcolor.RGBAF64 {
+//		return fcolor.RGBAF64{R: c.R * 2, G: c.G * 2, B: c.B * 2, A: c.A}
+//	})
+func ApplyF64(img *fcolor.Image, fn func(fcolor.RGBAF64) fcolor.RGBAF64) *fcolor.Image {
+	bounds := img.Bounds()
+	dst := fcolor.NewImageFrom(img)
+	w, h := bounds.Dx(), bounds.Dy()
+
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				dstPos := y*dst.Stride + x*4
+				s := dst.Pix[dstPos : dstPos+4 : dstPos+4]
+
+				c := fn(fcolor.RGBAF64{R: s[0], G: s[1], B: s[2], A: s[3]})
+				s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
 			}
 		}
 	})
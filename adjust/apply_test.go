@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"testing"
 
+	"github.com/anthonynsimon/bild/fcolor"
 	"github.com/anthonynsimon/bild/math/f64"
 	"github.com/anthonynsimon/bild/util"
 )
@@ -92,6 +93,77 @@ func TestApply(t *testing.T) {
 	}
 }
 
+func TestApply64(t *testing.T) {
+	value := &image.RGBA64{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 8,
+		Pix: []uint8{
+			0x12, 0x34, 0x00, 0x01, 0x80, 0x00, 0xFF, 0xFF, 0x00, 0x10, 0x00, 0x20, 0x00, 0x30, 0x80, 0x00,
+		},
+	}
+	invert := func(c color.RGBA64) color.RGBA64 {
+		return color.RGBA64{c.A - c.R, c.A - c.G, c.A - c.B, c.A}
+	}
+	expected := &image.RGBA64{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 8,
+		Pix: []uint8{
+			0xED, 0xCB, 0xFF, 0xFE, 0x7F, 0xFF, 0xFF, 0xFF, 0x7F, 0xF0, 0x7F, 0xE0, 0x7F, 0xD0, 0x80, 0x00,
+		},
+	}
+
+	actual := Apply64(value, invert)
+	if !util.RGBA64ImageEqual(actual, expected) {
+		t.Errorf("%s: expected: %#v, actual: %#v", "apply64 invert", expected, actual)
+	}
+	if actual := Apply64(actual, invert); !util.RGBA64ImageEqual(actual, value) {
+		t.Errorf("%s: expected: %#v, actual: %#v", "apply64 invert twice", value, actual)
+	}
+}
+
+func TestApplyF64(t *testing.T) {
+	value := &fcolor.Image{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 4,
+		Pix: []float64{
+			0.5, 0.2, 0.004, 1, 3, -0.25, 0, 0.5,
+		},
+	}
+	scale := func(f float64) func(fcolor.RGBAF64) fcolor.RGBAF64 {
+		return func(c fcolor.RGBAF64) fcolor.RGBAF64 {
+			return fcolor.RGBAF64{R: c.R * f, G: c.G * f, B: c.B * f, A: c.A}
+		}
+	}
+
+	cases := []struct {
+		desc     string
+		actual   func() *fcolor.Image
+		expected []float64
+	}{
+		{
+			desc:     "values out of range",
+			actual:   func() *fcolor.Image { return ApplyF64(value, scale(10)) },
+			expected: []float64{5, 2, 0.04, 1, 30, -2.5, 0, 0.5},
+		},
+		{
+			desc:     "chained without quantization",
+			actual:   func() *fcolor.Image { return ApplyF64(ApplyF64(value, scale(100)), scale(0.01)) },
+			expected: []float64{0.5, 0.2, 0.004, 1, 3, -0.25, 0, 0.5},
+		},
+	}
+
+	for _, c := range cases {
+		actual := c.actual()
+		expected := &fcolor.Image{Rect: value.Rect, Stride: value.Stride, Pix: c.expected}
+		if !util.F64ImageApproxEqual(actual, expected, 1e-12) {
+			t.Errorf("%s: expected: %v, actual: %v", "applyF64 "+c.desc, expected.Pix, actual.Pix)
+		}
+	}
+	if value.Pix[0] != 0.5 {
+		t.Errorf("%s: expected: %v, actual: %v", "applyF64 source unchanged", 0.5, value.Pix[0])
+	}
+}
+
 func TestClampFloat64(t *testing.T) {
 	cases := []struct {
 		value    float64
@@ -129,3 +201,34 @@ func BenchmarkApply(b *testing.B) {
 		Apply(val, fn)
 	}
 }
+
+func TestApplyInto(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 10)
+	}
+	invert := func(c color.RGBA) color.RGBA {
+		return color.RGBA{c.A - c.R, c.A - c.G, c.A - c.B, c.A}
+	}
+	expected := Apply(img, invert)
+
+	dst := image.NewRGBA(image.Rect(5, 5, 8, 7))
+	ApplyInto(dst, img, invert)
+	if actual := (&image.RGBA{Pix: dst.Pix, Stride: dst.Stride, Rect: img.Rect}); !util.RGBAImageEqual(actual, expected) {
+		t.Errorf("%s: expected: %v actual: %v", "ApplyInto", expected, actual)
+	}
+
+	// Adjusting in place
+	ApplyInto(img, img, invert)
+	if !util.RGBAImageEqual(img, expected) {
+		t.Errorf("%s: expected: %v actual: %v", "ApplyInto in place", expected, img)
+	}
+
+	// Other image types are converted first
+	gray := &image.Gray{Pix: []uint8{0, 100, 200, 255}, Stride: 2, Rect: image.Rect(0, 0, 2, 2)}
+	dst = image.NewRGBA(gray.Rect)
+	ApplyInto(dst, gray, invert)
+	if expected := Apply(gray, invert); !util.RGBAImageEqual(dst, expected) {
+		t.Errorf("%s: expected: %v actual: %v", "ApplyInto Gray", expected, dst)
+	}
+}
@@ -0,0 +1,87 @@
+package adjust
+
+import (
+	"image"
+	"math"
+
+	"github.com/anthonynsimon/bild/fcolor"
+	"github.com/anthonynsimon/bild/math/f64"
+	"github.com/anthonynsimon/bild/parallel"
+)
+
+// ExposureF64 returns a copy of the float image with its exposure adjusted by the given number of stops,
+// each stop doubling or halving the color values. The values aren't clamped, so that highlights above 1.0
+// are kept for a later tone mapping.
+//
+// Usage example:
+//
+//	// Brighten an HDR image by one and a half stops
+//	result := adjust.ExposureF64(fimg, 1.5)
+func ExposureF64(img *fcolor.Image, stops float64) *fcolor.Image {
+	scale := math.Exp2(stops)
+	return ApplyF64(img, func(c fcolor.RGBAF64) fcolor.RGBAF64 {
+		return fcolor.RGBAF64{R: c.R * scale, G: c.G * scale, B: c.B * scale, A: c.A}
+	})
+}
+
+// ToneMapReinhard maps the linear light values of the float image, such as the radiance decoded from an
+// HDR file, to an sRGB encoded 8-bit image with the Reinhard operator. The luminance L of each pixel is
+// compressed to L * (1 + L/white²) / (1 + L), so that a luminance of white is mapped to 1.0 and the
+// highlights roll off instead of being clipped. A white of 0 or less uses the basic L / (1 + L) operator,
+// which never reaches 1.0. The hue of each pixel is kept by scaling its channels by the same factor.
+//
+// Usage example:
+//
+//	// Display an HDR image whose highlights go up to a radiance of 16
+//	result := adjust.ToneMapReinhard(adjust.ExposureF64(fimg, -1), 16)
+func ToneMapReinhard(img *fcolor.Image, white float64) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	invWhite2 := 0.0
+	if white > 0 {
+		invWhite2 = 1 / (white * white)
+	}
+
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				srcPos := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				dstPos := y*dst.Stride + x*4
+				s := img.Pix[srcPos : srcPos+4 : srcPos+4]
+				d := dst.Pix[dstPos : dstPos+4 : dstPos+4]
+
+				alpha := f64.Clamp(s[3], 0, 1)
+				if alpha == 0 {
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+					continue
+				}
+
+				// The operator works on the straight colors, which are premultiplied again once encoded
+				r, g, b := s[0]/s[3], s[1]/s[3], s[2]/s[3]
+				scale := 0.0
+				if l := 0.2126*r + 0.7152*g + 0.0722*b; l > 0 {
+					scale = (1 + l*invWhite2) / (1 + l)
+				}
+
+				d[3] = uint8(alpha*255 + 0.5)
+				a := float64(d[3])
+				d[0] = uint8(linearToSRGB(r*scale)*a + 0.5)
+				d[1] = uint8(linearToSRGB(g*scale)*a + 0.5)
+				d[2] = uint8(linearToSRGB(b*scale)*a + 0.5)
+			}
+		}
+	})
+
+	return dst
+}
+
+// linearToSRGB encodes the linear light value v into sRGB, clamping it to the range 0.0 to 1.0.
+func linearToSRGB(v float64) float64 {
+	v = f64.Clamp(v, 0, 1)
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
@@ -0,0 +1,95 @@
+package adjust
+
+import (
+	"image"
+	"testing"
+
+	"github.com/anthonynsimon/bild/fcolor"
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestExposureF64(t *testing.T) {
+	value := &fcolor.Image{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 4,
+		Pix: []float64{
+			0.5, 0.2, 0.004, 1, 3, -0.25, 0, 0.5,
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		stops    float64
+		expected []float64
+	}{
+		{
+			desc:     "no change",
+			stops:    0,
+			expected: []float64{0.5, 0.2, 0.004, 1, 3, -0.25, 0, 0.5},
+		},
+		{
+			desc:     "one stop up",
+			stops:    1,
+			expected: []float64{1, 0.4, 0.008, 1, 6, -0.5, 0, 0.5},
+		},
+		{
+			desc:     "two stops down",
+			stops:    -2,
+			expected: []float64{0.125, 0.05, 0.001, 1, 0.75, -0.0625, 0, 0.5},
+		},
+	}
+
+	for _, c := range cases {
+		actual := ExposureF64(value, c.stops)
+		expected := &fcolor.Image{Rect: value.Rect, Stride: value.Stride, Pix: c.expected}
+		if !util.F64ImageApproxEqual(actual, expected, 1e-12) {
+			t.Errorf("%s: expected: %v, actual: %v", "ExposureF64 "+c.desc, expected.Pix, actual.Pix)
+		}
+	}
+}
+
+func TestToneMapReinhard(t *testing.T) {
+	value := &fcolor.Image{
+		Rect:   image.Rect(1, 1, 6, 2),
+		Stride: 5 * 4,
+		Pix: []float64{
+			1, 1, 1, 1, 4, 4, 4, 1, 1, 0, 0, 0.5, 0.25, 0.5, 0.1, 1, 0, 0, 0, 0,
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		white    float64
+		expected *image.RGBA
+	}{
+		{
+			desc:  "basic",
+			white: 0,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 5, 1),
+				Stride: 5 * 4,
+				Pix: []uint8{
+					0xBC, 0xBC, 0xBC, 0xFF, 0xE7, 0xE7, 0xE7, 0xFF, 0x80, 0x00, 0x00, 0x80, 0x75, 0xA0, 0x4B, 0xFF, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+		},
+		{
+			desc:  "white point",
+			white: 4,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 5, 1),
+				Stride: 5 * 4,
+				Pix: []uint8{
+					0xC1, 0xC1, 0xC1, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x80, 0x00, 0x00, 0x80, 0x76, 0xA2, 0x4C, 0xFF, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := ToneMapReinhard(value, c.white)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %#v, actual: %#v", "ToneMapReinhard "+c.desc, c.expected.Pix, actual.Pix)
+		}
+	}
+}
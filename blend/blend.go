@@ -374,6 +374,44 @@ func Blend(bg image.Image, fg image.Image, fn func(fcolor.RGBAF64, fcolor.RGBAF6
 	return dst
 }
 
+// BlendF64 blends the two float images using the provided function, like Blend but without rounding
+// nor clamping the resulting colors.
+//
+// Usage example:
+//
+//	result := blend.BlendF64(bg, fg, func(c0, c1 fcolor.RGBAF64) fcolor.RGBAF64 {
+//		return fcolor.RGBAF64{R: c0.R + c1.R, G: c0.G + c1.G, B: c0.B + c1.B, A: c1.A}
+//	})
+func BlendF64(bg *fcolor.Image, fg *fcolor.Image, fn func(fcolor.RGBAF64, fcolor.RGBAF64) fcolor.RGBAF64) *fcolor.Image {
+	bgBounds := bg.Bounds()
+	fgBounds := fg.Bounds()
+
+	w := min(bgBounds.Dx(), fgBounds.Dx())
+	h := min(bgBounds.Dy(), fgBounds.Dy())
+
+	dst := fcolor.NewImage(image.Rect(0, 0, w, h))
+
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				bgPos := y*bg.Stride + x*4
+				fgPos := y*fg.Stride + x*4
+				result := fn(
+					fcolor.RGBAF64{R: bg.Pix[bgPos+0], G: bg.Pix[bgPos+1], B: bg.Pix[bgPos+2], A: bg.Pix[bgPos+3]},
+					fcolor.RGBAF64{R: fg.Pix[fgPos+0], G: fg.Pix[fgPos+1], B: fg.Pix[fgPos+2], A: fg.Pix[fgPos+3]})
+
+				dstPos := y*dst.Stride + x*4
+				dst.Pix[dstPos+0] = result.R
+				dst.Pix[dstPos+1] = result.G
+				dst.Pix[dstPos+2] = result.B
+				dst.Pix[dstPos+3] = result.A
+			}
+		}
+	})
+
+	return dst
+}
+
 // alphaComp returns a new color after compositing the two colors
 // based on the foreground's alpha channel.
 func alphaComp(bg, fg fcolor.RGBAF64) fcolor.RGBAF64 {
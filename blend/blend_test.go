@@ -4,6 +4,7 @@ import (
 	"image"
 	"testing"
 
+	"github.com/anthonynsimon/bild/fcolor"
 	"github.com/anthonynsimon/bild/util"
 )
 
@@ -1572,3 +1573,57 @@ func TestLighten(t *testing.T) {
 		}
 	}
 }
+
+func TestBlendF64(t *testing.T) {
+	add := func(c0, c1 fcolor.RGBAF64) fcolor.RGBAF64 {
+		return fcolor.RGBAF64{R: c0.R + c1.R, G: c0.G + c1.G, B: c0.B + c1.B, A: c1.A}
+	}
+
+	cases := []struct {
+		value0   *fcolor.Image
+		value1   *fcolor.Image
+		expected *fcolor.Image
+	}{
+		{
+			value0: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []float64{0.75, 0.5, 0, 1, 4, 0, 0, 1},
+			},
+			value1: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []float64{0.75, 0.25, 0, 1, 1.5, -1, 0, 1},
+			},
+			expected: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []float64{1.5, 0.75, 0, 1, 5.5, -1, 0, 1},
+			},
+		},
+		{
+			value0: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []float64{2, 2, 2, 1, 3, 3, 3, 1},
+			},
+			value1: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 1, 1),
+				Stride: 1 * 4,
+				Pix:    []float64{1, 1, 1, 0.5},
+			},
+			expected: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 1, 1),
+				Stride: 1 * 4,
+				Pix:    []float64{3, 3, 3, 0.5},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := BlendF64(c.value0, c.value1, add)
+		if !util.F64ImageApproxEqual(actual, c.expected, 1e-12) {
+			t.Errorf("%s: expected %v, actual: %v", "BlendF64", c.expected.Pix, actual.Pix)
+		}
+	}
+}
@@ -2,51 +2,269 @@
 package blur
 
 import (
+	"context"
 	"image"
+	"image/draw"
 	"math"
 
 	"github.com/anthonynsimon/bild/clone"
 	"github.com/anthonynsimon/bild/convolution"
+	"github.com/anthonynsimon/bild/fcolor"
+	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/pixel"
 )
 
 // Box returns a blurred (average) version of the image.
 // Radius must be larger than 0.
 func Box(src image.Image, radius float64) *image.RGBA {
+	result, _ := BoxCtx(context.Background(), src, radius)
+	return result
+}
+
+// BoxCtx returns a blurred (average) version of the image, like Box, but stops early and returns
+// ctx.Err() if ctx is done before it completes. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	result, err := blur.BoxCtx(ctx, img, 3.0)
+func BoxCtx(ctx context.Context, src image.Image, radius float64) (*image.RGBA, error) {
+	dst := image.NewRGBA(src.Bounds())
+	if err := BoxIntoCtx(ctx, dst, src, radius); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// BoxInto writes a blurred (average) version of the image into dst, like Box, rather than allocating a
+// new image. It panics if dst and src don't have the same size, and dst must not share its pixels with src.
+// Radius must be larger than 0.
+//
+// Usage example:
+//
+//	dst := image.NewRGBA(img.Bounds())
+//	blur.BoxInto(dst, img, 3.0)
+func BoxInto(dst *image.RGBA, src image.Image, radius float64) {
+	BoxIntoCtx(context.Background(), dst, src, radius)
+}
+
+// BoxIntoCtx writes a blurred (average) version of the image into dst, like BoxInto, but stops early and
+// returns ctx.Err() if ctx is done before it completes. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	err := blur.BoxIntoCtx(ctx, dst, img, 3.0)
+func BoxIntoCtx(ctx context.Context, dst *image.RGBA, src image.Image, radius float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if radius <= 0 {
-		return clone.AsRGBA(src)
+		copyInto(dst, src)
+		return nil
 	}
 
-	length := int(math.Ceil(2*radius + 1))
-	k := convolution.NewKernel(length, length)
+	return convolution.ConvolveIntoCtx(ctx, dst, src, boxKernel(radius), &convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false})
+}
 
-	for x := 0; x < length; x++ {
-		for y := 0; y < length; y++ {
-			k.Matrix[y*length+x] = 1
-		}
+// BoxF64 returns a blurred (average) version of the float image, without rounding nor clamping its values.
+// Radius must be larger than 0.
+//
+// Usage example:
+//
+//	result := blur.BoxF64(fimg, 3.0)
+func BoxF64(src *fcolor.Image, radius float64) *fcolor.Image {
+	if radius <= 0 {
+		return fcolor.NewImageFrom(src)
 	}
 
-	return convolution.Convolve(src, k.Normalized(), &convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false})
+	return convolution.ConvolveF64(src, boxKernel(radius), &convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false})
 }
 
 // Gaussian returns a smoothly blurred version of the image using
 // a Gaussian function. Radius must be larger than 0.
 func Gaussian(src image.Image, radius float64) *image.RGBA {
+	result, _ := GaussianCtx(context.Background(), src, radius)
+	return result
+}
+
+// GaussianCtx returns a smoothly blurred version of the image using a Gaussian function, like Gaussian,
+// but stops early and returns ctx.Err() if ctx is done before it completes. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	result, err := blur.GaussianCtx(ctx, img, 3.0)
+func GaussianCtx(ctx context.Context, src image.Image, radius float64) (*image.RGBA, error) {
+	dst := image.NewRGBA(src.Bounds())
+	if err := GaussianIntoCtx(ctx, dst, src, radius); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// GaussianInto writes a smoothly blurred version of the image into dst, like Gaussian, rather than
+// allocating a new image. The image between the two passes of the separable convolution is taken
+// from a pool, so that blurring many images of the same size allocates no pixels.
+// It panics if dst and src don't have the same size. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	dst := image.NewRGBA(img.Bounds())
+//	blur.GaussianInto(dst, img, 3.0)
+func GaussianInto(dst *image.RGBA, src image.Image, radius float64) {
+	GaussianIntoCtx(context.Background(), dst, src, radius)
+}
+
+// GaussianIntoCtx writes a smoothly blurred version of the image into dst, like GaussianInto, but stops
+// early and returns ctx.Err() if ctx is done before it completes. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	err := blur.GaussianIntoCtx(ctx, dst, img, 3.0)
+func GaussianIntoCtx(ctx context.Context, dst *image.RGBA, src image.Image, radius float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if radius <= 0 {
+		copyInto(dst, src)
+		return nil
+	}
+
+	normK := gaussianKernel(radius)
+	tmp := intermediates.Get(src.Bounds())
+	defer intermediates.Put(tmp)
+
+	// Perform separable convolution, each pass reporting half of the progress
+	options := convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false}
+	if err := convolution.ConvolveIntoCtx(parallel.ProgressRange(ctx, 0, 0.5), tmp, src, normK, &options); err != nil {
+		return err
+	}
+	return convolution.ConvolveIntoCtx(parallel.ProgressRange(ctx, 0.5, 1), dst, tmp, normK.Transposed(), &options)
+}
+
+// intermediates holds the images between the passes of the separable blurs.
+var intermediates pixel.RGBAPool
+
+// copyInto copies the pixels of src into dst, which must have the same size.
+func copyInto(dst *image.RGBA, src image.Image) {
+	if dst.Rect.Size() != src.Bounds().Size() {
+		panic("blur: destination and source images have different sizes")
+	}
+	draw.Draw(dst, dst.Rect, src, src.Bounds().Min, draw.Src)
+}
+
+// GaussianF64 returns a smoothly blurred version of the float image using a Gaussian function,
+// without rounding nor clamping its values, even between the two passes of the separable convolution.
+// Radius must be larger than 0.
+//
+// Usage example:
+//
+//	result := blur.GaussianF64(fimg, 3.0)
+func GaussianF64(src *fcolor.Image, radius float64) *fcolor.Image {
 	if radius <= 0 {
-		return clone.AsRGBA(src)
+		return fcolor.NewImageFrom(src)
 	}
 
-	// Create the 1-d gaussian kernel
+	normK := gaussianKernel(radius)
+
+	options := convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false}
+	result := convolution.ConvolveF64(src, normK, &options)
+	result = convolution.ConvolveF64(result, normK.Transposed(), &options)
+
+	return result
+}
+
+// Box64 returns a blurred (average) version of the image, keeping 16 bits per channel.
+// Radius must be larger than 0.
+//
+// Usage example:
+//
+//	result := blur.Box64(img, 3.0)
+func Box64(src image.Image, radius float64) *image.RGBA64 {
+	return BoxNative(clone.AsShallowRGBA64(src), radius)
+}
+
+// Gaussian64 returns a smoothly blurred version of the image using a Gaussian function,
+// keeping 16 bits per channel. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	result := blur.Gaussian64(img, 3.0)
+func Gaussian64(src image.Image, radius float64) *image.RGBA64 {
+	return GaussianNative(clone.AsShallowRGBA64(src), radius)
+}
+
+// BoxNative returns a blurred (average) version of the image, running directly on its pixels and
+// returning an image of the same type. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	// Blurs a grayscale image without converting it to RGBA
+//	result := blur.BoxNative(gray, 3.0)
+func BoxNative[I pixel.Image](src I, radius float64) I {
+	return pixel.Process(src, boxBuffer[uint8](radius), boxBuffer[uint16](radius))
+}
+
+// GaussianNative returns a smoothly blurred version of the image using a Gaussian function, running
+// directly on its pixels and returning an image of the same type. Radius must be larger than 0.
+//
+// Usage example:
+//
+//	// Blurs a grayscale image without converting it to RGBA
+//	result := blur.GaussianNative(gray, 3.0)
+func GaussianNative[I pixel.Image](src I, radius float64) I {
+	return pixel.Process(src, gaussianBuffer[uint8](radius), gaussianBuffer[uint16](radius))
+}
+
+// boxBuffer returns the function applying the box blur of the radius to a pixel buffer.
+func boxBuffer[T pixel.Sample](radius float64) func(*pixel.Buffer[T]) *pixel.Buffer[T] {
+	return func(src *pixel.Buffer[T]) *pixel.Buffer[T] {
+		if radius <= 0 {
+			return src.Clone()
+		}
+
+		return convolution.ConvolveBuffer(src, boxKernel(radius), &convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false})
+	}
+}
+
+// gaussianBuffer returns the function applying the gaussian blur of the radius to a pixel buffer.
+func gaussianBuffer[T pixel.Sample](radius float64) func(*pixel.Buffer[T]) *pixel.Buffer[T] {
+	return func(src *pixel.Buffer[T]) *pixel.Buffer[T] {
+		if radius <= 0 {
+			return src.Clone()
+		}
+
+		normK := gaussianKernel(radius)
+
+		// Perform separable convolution
+		options := convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false}
+		result := convolution.ConvolveBuffer(src, normK, &options)
+		result = convolution.ConvolveBuffer(result, normK.Transposed(), &options)
+
+		return result
+	}
+}
+
+// boxKernel returns the normalized square kernel averaging the pixels within the radius.
+func boxKernel(radius float64) convolution.Matrix {
+	length := int(math.Ceil(2*radius + 1))
+	k := convolution.NewKernel(length, length)
+
+	for x := 0; x < length; x++ {
+		for y := 0; y < length; y++ {
+			k.Matrix[y*length+x] = 1
+		}
+	}
+
+	return k.Normalized()
+}
+
+// gaussianKernel returns the normalized 1-d gaussian kernel of the radius, to be applied
+// horizontally and then transposed vertically.
+func gaussianKernel(radius float64) convolution.Matrix {
 	length := int(math.Ceil(2*radius + 1))
 	k := convolution.NewKernel(length, 1)
 	for i, x := 0, -radius; i < length; i, x = i+1, x+1 {
 		k.Matrix[i] = math.Exp(-(x * x / 4 / radius))
 	}
-	normK := k.Normalized()
 
-	// Perform separable convolution
-	options := convolution.Options{Bias: 0, Wrap: false, KeepAlpha: false}
-	result := convolution.Convolve(src, normK, &options)
-	result = convolution.Convolve(result, normK.Transposed(), &options)
-
-	return result
+	return k.Normalized()
 }
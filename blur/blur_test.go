@@ -1,9 +1,16 @@
 package blur
 
 import (
+	"context"
 	"image"
+	"image/color"
+	"math"
+	"runtime"
 	"testing"
 
+	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/fcolor"
+	"github.com/anthonynsimon/bild/parallel"
 	"github.com/anthonynsimon/bild/util"
 )
 
@@ -164,3 +171,235 @@ func TestGaussianBlur(t *testing.T) {
 		}
 	}
 }
+
+func TestBlurF64(t *testing.T) {
+	src := &image.RGBA{
+		Rect:   image.Rect(0, 0, 3, 3),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+			0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+			0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+		},
+	}
+	hdr := fcolor.NewImage(image.Rect(0, 0, 3, 3))
+	for i := range hdr.Pix {
+		hdr.Pix[i] = 5
+	}
+
+	cases := []struct {
+		description string
+		blurF64     func(*fcolor.Image, float64) *fcolor.Image
+		blur        func(image.Image, float64) *image.RGBA
+	}{
+		{"Box", BoxF64, Box},
+		{"Gaussian", GaussianF64, Gaussian},
+	}
+
+	for _, c := range cases {
+		for _, radius := range []float64{0, 1, 2, 3} {
+			// Matches the 8-bit blur, up to its rounding
+			actual := c.blurF64(fcolor.NewImageFrom(src), radius)
+			expected := fcolor.NewImageFrom(c.blur(src, radius))
+			if !util.F64ImageApproxEqual(actual, expected, 2.0/255) {
+				t.Errorf("%s: expected: %v actual: %v", c.description+"F64 radius", expected.Pix, actual.Pix)
+			}
+
+			// Values above 1.0 aren't clamped
+			actual = c.blurF64(hdr, radius)
+			if !util.F64ImageApproxEqual(actual, hdr, 1e-9) {
+				t.Errorf("%s: expected: %v actual: %v", c.description+"F64 high dynamic range", hdr.Pix, actual.Pix)
+			}
+		}
+	}
+}
+
+func TestBlur64(t *testing.T) {
+	src := &image.RGBA{
+		Rect:   image.Rect(0, 0, 3, 3),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+			0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+			0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+		},
+	}
+	// A gradient too fine for 8 bits, which a blur keeps as it is
+	gradient := image.NewRGBA64(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			v := uint16(0x8000 + x)
+			gradient.SetRGBA64(x, y, color.RGBA64{v, v, v, 0xFFFF})
+		}
+	}
+
+	cases := []struct {
+		description string
+		blur64      func(image.Image, float64) *image.RGBA64
+		blur        func(image.Image, float64) *image.RGBA
+	}{
+		{"Box", Box64, Box},
+		{"Gaussian", Gaussian64, Gaussian},
+	}
+
+	for _, c := range cases {
+		for _, radius := range []float64{0, 1, 2, 3} {
+			// Matches the 8-bit blur, up to its rounding
+			actual := c.blur64(src, radius)
+			expected := clone.AsRGBA64(c.blur(src, radius))
+			if !util.RGBA64ImageApproxEqual(actual, expected, 2*0x101) {
+				t.Errorf("%s: expected: %v actual: %v", c.description+"64 radius", expected.Pix, actual.Pix)
+			}
+		}
+
+		actual := c.blur64(gradient, 0.5)
+		for x := 0; x < 3; x++ {
+			if v := actual.RGBA64At(x, 1).R; v < 0x8000 || v > 0x8002 {
+				t.Errorf("%s: expected: %v actual: %v", c.description+"64 fine gradient", "0x8000 to 0x8002", v)
+			}
+		}
+		if actual.RGBA64At(0, 1).R >= actual.RGBA64At(2, 1).R {
+			t.Errorf("%s: expected: %v actual: %v", c.description+"64 fine gradient", "increasing values", actual.Pix)
+		}
+	}
+}
+
+func TestBlurNative(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 4, 3))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 20)
+	}
+	nrgba64 := image.NewNRGBA64(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			nrgba64.SetNRGBA64(x, y, color.NRGBA64{0x8001, 0x1234, 0x0007, 0x4000})
+		}
+	}
+
+	cases := []struct {
+		description string
+		blurGray    func(*image.Gray, float64) *image.Gray
+		blur64      func(*image.NRGBA64, float64) *image.NRGBA64
+		blurRGBA    func(*image.RGBA, float64) *image.RGBA
+		blur        func(image.Image, float64) *image.RGBA
+	}{
+		{"Box", BoxNative[*image.Gray], BoxNative[*image.NRGBA64], BoxNative[*image.RGBA], Box},
+		{"Gaussian", GaussianNative[*image.Gray], GaussianNative[*image.NRGBA64], GaussianNative[*image.RGBA], Gaussian},
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 9, 7))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i * 37)
+	}
+
+	for _, c := range cases {
+		for _, radius := range []float64{0, 1, 2, 3} {
+			// RGBA images give the same result as the RGBA blur
+			if actual, expected := c.blurRGBA(rgba, radius), c.blur(rgba, radius); !util.RGBAImageEqual(actual, expected) {
+				t.Errorf("%s: expected: %v actual: %v", c.description+"Native RGBA", expected.Pix, actual.Pix)
+			}
+
+			// Matches the luminance of the RGBA blur
+			actual := c.blurGray(gray, radius)
+			expected := c.blur(gray, radius)
+			for i, v := range actual.Pix {
+				if v != expected.Pix[i*4] {
+					t.Errorf("%s: expected: %v actual: %v", c.description+"Native Gray", expected.Pix[i*4], v)
+				}
+			}
+
+			// Translucent 16-bit colors are kept, up to the truncation of the sums which dividing
+			// by an alpha of a quarter scales up
+			actual64 := c.blur64(nrgba64, radius)
+			for y := 0; y < 3; y++ {
+				for x := 0; x < 4; x++ {
+					a, e := actual64.NRGBA64At(x, y), nrgba64.NRGBA64At(x, y)
+					for i, d := range [4]int{int(a.R) - int(e.R), int(a.G) - int(e.G), int(a.B) - int(e.B), int(a.A) - int(e.A)} {
+						if d < -4 || d > 4 {
+							t.Errorf("%s: expected: %v actual: %v channel %d", c.description+"Native NRGBA64", e, a, i)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBlurCtx(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 13)
+	}
+
+	cases := []struct {
+		description string
+		blur        func(image.Image, float64) *image.RGBA
+		blurCtx     func(context.Context, image.Image, float64) (*image.RGBA, error)
+	}{
+		{"Box", Box, BoxCtx},
+		{"Gaussian", Gaussian, GaussianCtx},
+	}
+
+	for _, c := range cases {
+		var reports []float64
+		ctx := parallel.WithProgress(context.Background(), func(d float64) { reports = append(reports, d) })
+		actual, err := c.blurCtx(ctx, img, 2)
+		if err != nil || !util.RGBAImageEqual(actual, c.blur(img, 2)) {
+			t.Errorf("%s: expected: %v actual: %v", c.description+"Ctx", "same result as "+c.description, err)
+		}
+		if len(reports) == 0 || reports[len(reports)-1] != 1 {
+			t.Errorf("%s: expected: %v actual: %v", c.description+"Ctx progress", 1.0, reports)
+		}
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		if actual, err := c.blurCtx(canceled, img, 2); err != context.Canceled || actual != nil {
+			t.Errorf("%s: expected: %v actual: %v %v", c.description+"Ctx canceled", context.Canceled, err, actual)
+		}
+	}
+}
+
+func TestBlurInto(t *testing.T) {
+	img := image.NewRGBA(image.Rect(2, 3, 66, 51))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 17)
+	}
+
+	cases := []struct {
+		description string
+		blur        func(image.Image, float64) *image.RGBA
+		blurInto    func(*image.RGBA, image.Image, float64)
+	}{
+		{"Box", Box, BoxInto},
+		{"Gaussian", Gaussian, GaussianInto},
+	}
+
+	for _, c := range cases {
+		for _, radius := range []float64{0, 1.5, 4} {
+			dst := image.NewRGBA(img.Bounds())
+			for i := range dst.Pix {
+				dst.Pix[i] = 0xFF
+			}
+			c.blurInto(dst, img, radius)
+			if expected := c.blur(img, radius); !util.RGBAImageEqual(dst, expected) {
+				t.Errorf("%s: expected: %v actual: %v", c.description+"Into", expected, dst)
+			}
+		}
+	}
+
+	// Once the pool holds an intermediate image, blurring another image of the same size allocates no pixels.
+	// The pool may drop its images at any time, so the run allocating the least is checked.
+	dst := image.NewRGBA(img.Bounds())
+	GaussianInto(dst, img, 3)
+	allocated := uint64(math.MaxUint64)
+	for i := 0; i < 50; i++ {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		GaussianInto(dst, img, 3)
+		runtime.ReadMemStats(&after)
+		allocated = min(allocated, after.TotalAlloc-before.TotalAlloc)
+	}
+	if allocated >= uint64(len(img.Pix)) {
+		t.Errorf("%s: expected: %v actual: %v", "GaussianInto allocated bytes", "less than an image", allocated)
+	}
+}
@@ -36,6 +36,27 @@ func AsShallowRGBA(src image.Image) *image.RGBA {
 	return AsRGBA(src)
 }
 
+// AsRGBA64 returns an RGBA64 copy of the supplied image, keeping 16 bits per channel.
+//
+// Usage example:
+//
+//	// Keeps the full precision of a 16-bit PNG
+//	result := clone.AsRGBA64(img)
+func AsRGBA64(src image.Image) *image.RGBA64 {
+	bounds := src.Bounds()
+	img := image.NewRGBA64(bounds)
+	draw.Draw(img, bounds, src, bounds.Min, draw.Src)
+	return img
+}
+
+// AsShallowRGBA64 tries to cast to image.RGBA64 to get reference. Otherwise makes a copy
+func AsShallowRGBA64(src image.Image) *image.RGBA64 {
+	if rgba64, ok := src.(*image.RGBA64); ok {
+		return rgba64
+	}
+	return AsRGBA64(src)
+}
+
 // Pad returns an RGBA copy of the src image parameter with its edges padded
 // using the supplied PadMethod.
 // Parameter padX and padY correspond to the amount of padding to be applied
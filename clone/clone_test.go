@@ -222,6 +222,92 @@ func TestShallowRGBAReturnRef(t *testing.T) {
 	}
 }
 
+func TestCloneAsRGBA64(t *testing.T) {
+	cases := []struct {
+		desc     string
+		value    image.Image
+		expected *image.RGBA64
+	}{
+		{
+			desc: "RGBA64",
+			value: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xFF, 0xFF,
+					0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x80, 0x00,
+				},
+			},
+			expected: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xFF, 0xFF,
+					0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x80, 0x00,
+				},
+			},
+		},
+		{
+			desc: "NRGBA64",
+			value: &image.NRGBA64{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xFF, 0xFF,
+					0xFF, 0xFF, 0x00, 0x00, 0x80, 0x00, 0x80, 0x00,
+				},
+			},
+			expected: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xFF, 0xFF,
+					0x80, 0x00, 0x00, 0x00, 0x40, 0x00, 0x80, 0x00,
+				},
+			},
+		},
+		{
+			desc: "RGBA",
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 4,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0x80,
+					0x12, 0x34, 0x56, 0xFF,
+				},
+			},
+			expected: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80,
+					0x12, 0x12, 0x34, 0x34, 0x56, 0x56, 0xFF, 0xFF,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := AsRGBA64(c.value)
+		if !util.RGBA64ImageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %#v, actual: %#v", "CloneAsRGBA64 from "+c.desc, c.expected, actual)
+		}
+	}
+
+	//shallow copy should work the same
+	for _, c := range cases {
+		actual := AsShallowRGBA64(c.value)
+		if !util.RGBA64ImageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %#v, actual: %#v", "CloneAsRGBA64 from "+c.desc, c.expected, actual)
+		}
+	}
+
+	src := cases[0].value.(*image.RGBA64)
+	if copyRef := AsShallowRGBA64(src); copyRef != src {
+		t.Errorf("ShallowRGBA64 should return the same ref (src=%p, copy=%p)", src, copyRef)
+	}
+}
+
 func TestPad(t *testing.T) {
 	cases := []struct {
 		desc     string
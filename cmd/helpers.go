@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -12,10 +18,8 @@ import (
 	"github.com/anthonynsimon/bild/transform"
 )
 
-var jpgExtensions = []string{".jpg", ".jpeg"}
-var pngExtensions = []string{".png"}
-var bmpExtensions = []string{".bmp"}
-var webpExtensions = []string{".webp"}
+// stdio is the path that reads the input image from the standard input, or writes the output image to the standard output.
+const stdio = "-"
 
 var (
 	// ErrWrongSize is thrown when the provided size string does not match the expected form.
@@ -24,6 +28,28 @@ var (
 	errWrongRect = errors.New("rect must be of form [x0]x[y0]+[x1]x[y1], i.e. 0x0+512x256")
 	// errUnknownFilter is thrown when an unknown resample filter name is provided.
 	errUnknownFilter = errors.New("unknown filter, options: nearestneighbor, box, linear, gaussian, mitchellnetravali, catmullrom, lanczos")
+	// errWrongPoints is thrown when the provided points string does not match the expected form.
+	errWrongPoints = errors.New("points must be of form [x]x[y],[x]x[y],[x]x[y],[x]x[y], i.e. 0x0,512x0,512x256,0x256.5")
+	// errWrongColor is thrown when the provided color string does not match the expected form.
+	errWrongColor = errors.New("color must be a hex value of form RRGGBB or RRGGBBAA, i.e. ff0000 or ff000080")
+	// errUnknownAnchor is thrown when an unknown anchor name is provided.
+	errUnknownAnchor = errors.New("unknown anchor, options: center, top-left, top, top-right, left, right, bottom-left, bottom, bottom-right")
+	// errUnknownResizeMode is thrown when an unknown resize mode name is provided.
+	errUnknownResizeMode = errors.New("unknown resize mode, options: stretch, fit, fill, thumbnail")
+	// errLinearResizeMode is thrown when linear light resampling is requested for a mode that doesn't support it.
+	errLinearResizeMode = errors.New("linear light resampling is only supported with the stretch resize mode")
+	// errUnknownFormat is thrown when an unknown output format name is provided.
+	errUnknownFormat = errors.New("unknown format, options: png, jpg, bmp, webp, gif, tiff, pbm, pgm, ppm, pam, ff, hdr")
+	// errUnknownStrip is thrown when an unknown metadata block name is provided.
+	errUnknownStrip = errors.New("unknown metadata, options: all, exif, gps, icc, xmp")
+	// errWrongQuality is thrown when the provided quality is out of range.
+	errWrongQuality = errors.New("quality must be between 1 and 100")
+	// errWrongWorkers is thrown when a negative number of workers is provided.
+	errWrongWorkers = errors.New("workers must be 0 or more")
+	// errUnknownCompression is thrown when an unknown PNG compression level name is provided.
+	errUnknownCompression = errors.New("unknown png compression, options: default, none, fast, best")
+	// errUnknownTIFFCompression is thrown when an unknown TIFF compression scheme name is provided.
+	errUnknownTIFFCompression = errors.New("unknown tiff compression, options: none, lzw, deflate")
 )
 
 type size struct {
@@ -31,66 +57,242 @@ type size struct {
 	Height int
 }
 
-func resolveEncoder(outputfile string, defaultEncoding imgio.Encoder) imgio.Encoder {
-	lower := strings.ToLower(outputfile)
+// resolveFormat returns the output format set by the format flag, or otherwise the one matching the
+// extension of outputfile, defaulting to PNG.
+func resolveFormat(outputfile string) (imgio.Format, error) {
+	if outputFormat != "" {
+		return imgio.ParseFormat(outputFormat)
+	}
 
-	for _, ext := range jpgExtensions {
-		if strings.HasSuffix(lower, ext) {
-			return imgio.JPEGEncoder(100)
-		}
+	if format, err := imgio.FormatFromFilename(outputfile); err == nil {
+		return format, nil
 	}
+	return imgio.PNG, nil
+}
 
-	for _, ext := range pngExtensions {
-		if strings.HasSuffix(lower, ext) {
-			return imgio.PNGEncoder()
-		}
+// resolveEncoder returns the encoder of the format, configured by the quality, png-compression,
+// webp-lossless, progressive, tiff-compression and netpbm-ascii flags.
+func resolveEncoder(format imgio.Format) imgio.Encoder {
+	switch format {
+	case imgio.JPEG:
+		return imgio.JPEGEncoderWithOptions(&imgio.JPEGOptions{Quality: quality, Progressive: progressive})
+	case imgio.BMP:
+		return imgio.BMPEncoder()
+	case imgio.WEBP:
+		return imgio.WEBPEncoderWithOptions(&imgio.WEBPOptions{Lossless: webpLossless, Quality: quality})
+	case imgio.GIF:
+		return imgio.GIFEncoder(nil)
+	case imgio.TIFF:
+		return imgio.TIFFEncoder(resolveTIFFOptions())
+	case imgio.PBM:
+		return imgio.PBMEncoder(&imgio.NetpbmOptions{ASCII: netpbmASCII})
+	case imgio.PGM:
+		return imgio.PGMEncoder(&imgio.NetpbmOptions{ASCII: netpbmASCII})
+	case imgio.PPM:
+		return imgio.PPMEncoder(&imgio.NetpbmOptions{ASCII: netpbmASCII})
+	case imgio.PAM:
+		return imgio.PAMEncoder()
+	case imgio.Farbfeld:
+		return imgio.FarbfeldEncoder()
+	case imgio.HDR:
+		return imgio.HDREncoder()
 	}
+	level, err := parsePNGCompression(pngCompression)
+	exitIfNotNil(err)
+	return imgio.PNGEncoderWithOptions(&imgio.PNGOptions{CompressionLevel: level})
+}
 
-	for _, ext := range bmpExtensions {
-		if strings.HasSuffix(lower, ext) {
-			return imgio.BMPEncoder()
-		}
+// parsePNGCompression returns the PNG compression level of the given name.
+func parsePNGCompression(name string) (png.CompressionLevel, error) {
+	switch strings.ToLower(name) {
+	case "default":
+		return png.DefaultCompression, nil
+	case "none":
+		return png.NoCompression, nil
+	case "fast":
+		return png.BestSpeed, nil
+	case "best":
+		return png.BestCompression, nil
+	default:
+		return png.DefaultCompression, errUnknownCompression
+	}
+}
+
+// resolveTIFFOptions returns the TIFF encoding options set by the tiff-compression flag, using the
+// predictor along with compression.
+func resolveTIFFOptions() *imgio.TIFFOptions {
+	compression, err := parseTIFFCompression(tiffCompression)
+	exitIfNotNil(err)
+	return &imgio.TIFFOptions{Compression: compression, Predictor: compression != imgio.TIFFUncompressed}
+}
+
+// parseTIFFCompression returns the TIFF compression scheme of the given name.
+func parseTIFFCompression(name string) (imgio.TIFFCompression, error) {
+	switch strings.ToLower(name) {
+	case "none":
+		return imgio.TIFFUncompressed, nil
+	case "lzw":
+		return imgio.TIFFLZW, nil
+	case "deflate":
+		return imgio.TIFFDeflate, nil
+	default:
+		return imgio.TIFFUncompressed, errUnknownTIFFCompression
+	}
+}
+
+// readInput returns the content of the file at path, or of the standard input if path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == stdio {
+		return io.ReadAll(os.Stdin)
 	}
+	return os.ReadFile(path)
+}
+
+// openImage loads the image file at path, or the standard input if path is "-",
+// upright as described by its EXIF orientation unless disabled.
+func openImage(path string) (image.Image, *imgio.Metadata, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeImage(data)
+}
 
-	for _, ext := range webpExtensions {
-		if strings.HasSuffix(lower, ext) {
-			return imgio.WEBPEncoder(nil)
+// decodeImage decodes the image data, upright as described by its EXIF orientation unless disabled.
+func decodeImage(data []byte) (image.Image, *imgio.Metadata, error) {
+	return imgio.DecodeWithMetadata(bytes.NewReader(data), &imgio.OpenOptions{AutoOrient: !noAutoOrient})
+}
+
+// parseStrip returns the options to remove the named metadata blocks.
+func parseStrip(names []string) (*imgio.StripOptions, error) {
+	options := &imgio.StripOptions{}
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "all":
+			options.EXIF, options.ICC, options.XMP = true, true, true
+		case "exif":
+			options.EXIF = true
+		case "gps":
+			options.GPS = true
+		case "icc":
+			options.ICC = true
+		case "xmp":
+			options.XMP = true
+		default:
+			return nil, errUnknownStrip
 		}
 	}
+	return options, nil
+}
+
+// save writes the image to fout, or the standard output if fout is "-", in the format resolved from
+// the format flag or its extension, along with the metadata not removed by the strip flag.
+func save(fout string, img image.Image, md *imgio.Metadata) {
+	options, err := parseStrip(strip)
+	exitIfNotNil(err)
+
+	format, err := resolveFormat(fout)
+	exitIfNotNil(err)
+
+	encoder := imgio.WithMetadata(resolveEncoder(format), md.Strip(options))
+	if fout == stdio {
+		err = encoder(os.Stdout, img)
+	} else {
+		err = imgio.Save(fout, img, encoder)
+	}
+	exitIfNotNil(err)
+}
+
+// saveAnimation writes the animation as a GIF to fout, or the standard output if fout is "-".
+func saveAnimation(fout string, anim *imgio.Animation) {
+	var err error
+	if fout == stdio {
+		err = imgio.EncodeAnimation(os.Stdout, anim, nil)
+	} else {
+		err = imgio.SaveAnimation(fout, anim, nil)
+	}
+	exitIfNotNil(err)
+}
 
-	return defaultEncoding
+// saveTIFFPages writes the pages as a TIFF document to fout, or the standard output if fout is "-".
+func saveTIFFPages(fout string, pages []image.Image) {
+	var err error
+	if fout == stdio {
+		err = imgio.EncodeTIFFPages(os.Stdout, pages, resolveTIFFOptions())
+	} else {
+		err = imgio.SaveTIFFPages(fout, pages, resolveTIFFOptions())
+	}
+	exitIfNotNil(err)
 }
 
+// apply processes the input image and saves the result. When both the input and the output are GIFs,
+// every frame of an animated input is processed, and likewise every page of a multi-page TIFF.
 func apply(fin, fout string, process func(image.Image) (image.Image, error)) {
-	in, err := imgio.Open(fin)
+	data, err := readInput(fin)
 	exitIfNotNil(err)
 
-	result, err := process(in)
+	format, err := resolveFormat(fout)
+	exitIfNotNil(err)
+	switch format {
+	case imgio.GIF:
+		if anim, err := imgio.DecodeAnimation(bytes.NewReader(data)); err == nil && len(anim.Frames) > 1 {
+			result := anim.Map(func(frame image.Image) image.Image {
+				result, err := process(frame)
+				exitIfNotNil(err)
+				return result
+			})
+			saveAnimation(fout, result)
+			return
+		}
+	case imgio.TIFF:
+		if pages, err := imgio.DecodeTIFFPages(bytes.NewReader(data)); err == nil && len(pages) > 1 {
+			for i, page := range pages {
+				pages[i], err = process(page)
+				exitIfNotNil(err)
+			}
+			saveTIFFPages(fout, pages)
+			return
+		}
+	}
+
+	in, md, err := decodeImage(data)
 	exitIfNotNil(err)
 
-	encoder := resolveEncoder(fout, imgio.PNGEncoder())
-	err = imgio.Save(fout, result, encoder)
+	result, err := process(in)
 	exitIfNotNil(err)
+
+	save(fout, result, md)
 }
 
+// apply2 is like apply with two input images, the metadata of the first one is copied to the output image.
 func apply2(fin1, fin2, fout string, process func(image.Image, image.Image) (image.Image, error)) {
-	in1, err := imgio.Open(fin1)
+	in1, md, err := openImage(fin1)
 	exitIfNotNil(err)
 
-	in2, err := imgio.Open(fin2)
+	in2, _, err := openImage(fin2)
 	exitIfNotNil(err)
 
 	result, err := process(in1, in2)
 	exitIfNotNil(err)
 
-	encoder := resolveEncoder(fout, imgio.PNGEncoder())
-	err = imgio.Save(fout, result, encoder)
-	exitIfNotNil(err)
+	save(fout, result, md)
+}
+
+// openMask loads the image file at path as a grayscale mask.
+func openMask(path string) (*image.Gray, error) {
+	img, _, err := openImage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := image.NewGray(img.Bounds())
+	draw.Draw(mask, mask.Bounds(), img, img.Bounds().Min, draw.Src)
+	return mask, nil
 }
 
 func exitIfNotNil(err error) {
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -136,6 +338,54 @@ func parseRectStr(rectstr string) (image.Rectangle, error) {
 	return image.Rect(min.Width, min.Height, max.Width, max.Height), nil
 }
 
+// formatRectStr returns the rect in the form taken by parseRectStr.
+func formatRectStr(r image.Rectangle) string {
+	return fmt.Sprintf("%dx%d+%dx%d", r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+}
+
+func parsePointsStr(pointsstr string) ([4]transform.PointF, error) {
+	var points [4]transform.PointF
+
+	parts := strings.Split(pointsstr, ",")
+	if len(parts) != len(points) {
+		return points, errWrongPoints
+	}
+
+	for i, part := range parts {
+		coords := strings.Split(strings.TrimSpace(part), "x")
+		if len(coords) != 2 {
+			return points, errWrongPoints
+		}
+		x, err := strconv.ParseFloat(coords[0], 64)
+		if err != nil {
+			return points, errWrongPoints
+		}
+		y, err := strconv.ParseFloat(coords[1], 64)
+		if err != nil {
+			return points, errWrongPoints
+		}
+		points[i] = transform.PointF{X: x, Y: y}
+	}
+
+	return points, nil
+}
+
+func parseColorStr(colorstr string) (color.Color, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(colorstr, "#"))
+	if err != nil {
+		return nil, errWrongColor
+	}
+
+	switch len(b) {
+	case 3:
+		return color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xFF}, nil
+	case 4:
+		return color.NRGBA{R: b[0], G: b[1], B: b[2], A: b[3]}, nil
+	default:
+		return nil, errWrongColor
+	}
+}
+
 func parseResampleFilter(name string) (transform.ResampleFilter, error) {
 	switch strings.ToLower(name) {
 	case "nearestneighbor":
@@ -156,3 +406,28 @@ func parseResampleFilter(name string) (transform.ResampleFilter, error) {
 		return transform.ResampleFilter{}, errUnknownFilter
 	}
 }
+
+func parseAnchor(name string) (transform.Anchor, error) {
+	switch strings.ToLower(name) {
+	case "center":
+		return transform.Center, nil
+	case "top-left":
+		return transform.TopLeft, nil
+	case "top":
+		return transform.Top, nil
+	case "top-right":
+		return transform.TopRight, nil
+	case "left":
+		return transform.Left, nil
+	case "right":
+		return transform.Right, nil
+	case "bottom-left":
+		return transform.BottomLeft, nil
+	case "bottom":
+		return transform.Bottom, nil
+	case "bottom-right":
+		return transform.BottomRight, nil
+	default:
+		return transform.Center, errUnknownAnchor
+	}
+}
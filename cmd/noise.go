@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"github.com/anthonynsimon/bild/imgio"
 	"github.com/anthonynsimon/bild/noise"
 	"github.com/spf13/cobra"
 )
@@ -25,9 +24,7 @@ func generateNoise() *cobra.Command {
 				Monochrome: mono,
 			})
 
-			encoder := resolveEncoder(fout, imgio.PNGEncoder())
-			err = imgio.Save(fout, result, encoder)
-			exitIfNotNil(err)
+			save(fout, result, nil)
 		}}
 
 	cmd.Flags().StringVarP(&size, "size", "s", "512x512", "the width and height of the output image")
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/anthonynsimon/bild/parallel"
 	"github.com/spf13/cobra"
 )
 
@@ -11,9 +13,75 @@ var rootCmd = &cobra.Command{
 	Use:     "bild",
 	Short:   "A collection of parallel image processing algorithms in pure Go",
 	Version: Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Validate the global flags before running the command
+		if outputFormat != "" {
+			if _, err := imgio.ParseFormat(outputFormat); err != nil {
+				return errUnknownFormat
+			}
+		}
+		if quality < 1 || quality > 100 {
+			return errWrongQuality
+		}
+		if _, err := parsePNGCompression(pngCompression); err != nil {
+			return err
+		}
+		if _, err := parseTIFFCompression(tiffCompression); err != nil {
+			return err
+		}
+		if workers < 0 {
+			return errWrongWorkers
+		}
+		if workers > 0 {
+			parallel.SetDefault(parallel.NewExecutor(&parallel.Options{MaxWorkers: workers}))
+		}
+		_, err := parseStrip(strip)
+		return err
+	},
 }
 
+// noAutoOrient disables rotating the input images as described by their EXIF metadata
+var noAutoOrient bool
+
+// outputFormat is the format of the output image, overriding the one matching its extension
+var outputFormat string
+
+// strip lists the metadata blocks of the input image that aren't copied to the output image
+var strip []string
+
+// quality of the JPEG and lossy WebP output images
+var quality int
+
+// pngCompression is the compression level of PNG output images
+var pngCompression string
+
+// webpLossless encodes WebP output images losslessly, ignoring the quality
+var webpLossless bool
+
+// progressive encodes JPEG output images progressively
+var progressive bool
+
+// tiffCompression is the compression scheme of TIFF output images
+var tiffCompression string
+
+// workers is the maximum number of goroutines processing the images, 0 for as many as GOMAXPROCS
+var workers int
+
+// netpbmASCII writes PBM, PGM and PPM output images in their plain variant
+var netpbmASCII bool
+
 func init() {
+	rootCmd.PersistentFlags().BoolVar(&noAutoOrient, "no-auto-orient", false, "don't rotate input images as described by their EXIF orientation")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "", "format of the output image, overriding its extension, options: png, jpg, bmp, webp, gif, tiff, pbm, pgm, ppm, pam, ff, hdr")
+	rootCmd.PersistentFlags().StringSliceVar(&strip, "strip", nil, "metadata not copied to the output image, options: all, exif, gps, icc, xmp")
+	rootCmd.PersistentFlags().IntVar(&quality, "quality", 100, "quality of jpg and lossy webp output images, from 1 to 100")
+	rootCmd.PersistentFlags().StringVar(&pngCompression, "png-compression", "default", "compression level of png output images, options: default, none, fast, best")
+	rootCmd.PersistentFlags().BoolVar(&webpLossless, "webp-lossless", true, "encode webp output images losslessly, set to false to use the quality")
+	rootCmd.PersistentFlags().BoolVar(&progressive, "progressive", false, "encode jpg output images progressively")
+	rootCmd.PersistentFlags().StringVar(&tiffCompression, "tiff-compression", "lzw", "compression of tiff output images, options: none, lzw, deflate")
+	rootCmd.PersistentFlags().IntVar(&workers, "workers", 0, "maximum number of goroutines processing the image, 0 for one per cpu")
+	rootCmd.PersistentFlags().BoolVar(&netpbmASCII, "netpbm-ascii", false, "write pbm, pgm and ppm output images in their plain ascii variant")
+
 	rootCmd.AddCommand(createAdjust())
 	rootCmd.AddCommand(createBlend())
 	rootCmd.AddCommand(createBlur())
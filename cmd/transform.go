@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"fmt"
 	"image"
+	"os"
 
 	"github.com/anthonynsimon/bild/transform"
 	"github.com/spf13/cobra"
@@ -104,13 +106,14 @@ func flipv() *cobra.Command {
 
 func resize() *cobra.Command {
 	var width, height int
-	var filter string
+	var filter, mode, anchor string
+	var linear bool
 
 	var cmd = &cobra.Command{
 		Use:     "resize",
 		Short:   "resize an image to the given dimensions",
 		Args:    cobra.ExactArgs(2),
-		Example: "resize --width 800 --height 600 --filter lanczos input.jpg output.jpg",
+		Example: "resize --width 800 --height 600 --filter lanczos --mode fill --anchor top input.jpg output.jpg",
 		Run: func(cmd *cobra.Command, args []string) {
 			fin := args[0]
 			fout := args[1]
@@ -118,14 +121,50 @@ func resize() *cobra.Command {
 			f, err := parseResampleFilter(filter)
 			exitIfNotNil(err)
 
-			apply(fin, fout, func(img image.Image) (image.Image, error) {
-				return transform.Resize(img, width, height, f), nil
-			})
+			a, err := parseAnchor(anchor)
+			exitIfNotNil(err)
+
+			opts := &transform.ResizeOptions{}
+			if linear {
+				if mode != "stretch" {
+					exitIfNotNil(errLinearResizeMode)
+				}
+				opts.ColorSpace = transform.LinearRGB
+			}
+
+			var process func(img image.Image) (image.Image, error)
+			switch mode {
+			case "stretch":
+				process = func(img image.Image) (image.Image, error) {
+					return transform.ResizeWithOptions(img, width, height, f, opts), nil
+				}
+			case "fit":
+				process = func(img image.Image) (image.Image, error) {
+					return transform.Fit(img, width, height, f), nil
+				}
+			case "fill":
+				process = func(img image.Image) (image.Image, error) {
+					return transform.Fill(img, width, height, a, f), nil
+				}
+			case "thumbnail":
+				process = func(img image.Image) (image.Image, error) {
+					return transform.Thumbnail(img, width, height, f), nil
+				}
+			default:
+				exitIfNotNil(errUnknownResizeMode)
+			}
+
+			apply(fin, fout, process)
 		}}
 
 	cmd.Flags().IntVarP(&width, "width", "w", 0, "target width in pixels")
 	cmd.Flags().IntVarP(&height, "height", "h", 0, "target height in pixels")
 	cmd.Flags().StringVarP(&filter, "filter", "f", "linear", "resampling filter (nearestneighbor, box, linear, gaussian, mitchellnetravali, catmullrom, lanczos)")
+	cmd.Flags().StringVarP(&mode, "mode", "m", "stretch", "resize mode (stretch: exact size ignoring aspect ratio, fit: inside the box, fill: cover the box and crop, thumbnail: fit without enlarging)")
+	cmd.Flags().StringVarP(&anchor, "anchor", "a", "center", "area kept by --mode fill (center, top-left, top, top-right, left, right, bottom-left, bottom, bottom-right)")
+	cmd.Flags().BoolVar(&linear, "linear", false, "resample in linear light instead of sRGB, avoids darkening fine detail when downsampling")
+	// Defined without a shorthand so that cobra leaves -h to the height flag
+	cmd.Flags().Bool("help", false, "help for resize")
 
 	return cmd
 }
@@ -213,6 +252,281 @@ func shearv() *cobra.Command {
 	return cmd
 }
 
+func perspective() *cobra.Command {
+	var from, to, size, background, filter string
+
+	var cmd = &cobra.Command{
+		Use:     "perspective",
+		Short:   "warp an image so that four source points map to four destination points",
+		Args:    cobra.ExactArgs(2),
+		Example: "perspective --from 120x80,610x130,580x790,90x740 --to 0x0,600x0,600x800,0x800 --size 600x800 input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			fin := args[0]
+			fout := args[1]
+
+			src, err := parsePointsStr(from)
+			exitIfNotNil(err)
+
+			dst, err := parsePointsStr(to)
+			exitIfNotNil(err)
+
+			f, err := parseResampleFilter(filter)
+			exitIfNotNil(err)
+
+			opts := &transform.PerspectiveOptions{}
+			if size != "" {
+				s, err := parseSizeStr(size)
+				exitIfNotNil(err)
+				opts.Bounds = image.Rect(0, 0, s.Width, s.Height)
+			}
+			if background != "" {
+				c, err := parseColorStr(background)
+				exitIfNotNil(err)
+				opts.Background = c
+			}
+
+			apply(fin, fout, func(img image.Image) (image.Image, error) {
+				return transform.Perspective(img, src, dst, f, opts), nil
+			})
+		}}
+
+	cmd.Flags().StringVar(&from, "from", "", "four source points as XxY separated by commas, fractional values allowed (e.g. 0x0,512x0,512.5x256,0x256)")
+	cmd.Flags().StringVar(&to, "to", "", "four destination points as XxY separated by commas, in the same order as --from")
+	cmd.Flags().StringVarP(&size, "size", "s", "", "output size as WxH, defaults to the input size")
+	cmd.Flags().StringVarP(&background, "background", "b", "", "background color as hex RRGGBB or RRGGBBAA, defaults to transparent")
+	cmd.Flags().StringVarP(&filter, "filter", "f", "linear", "resampling filter (nearestneighbor, box, linear, gaussian, mitchellnetravali, catmullrom, lanczos)")
+
+	return cmd
+}
+
+func smartcrop() *cobra.Command {
+	var size string
+	var printRect bool
+
+	var cmd = &cobra.Command{
+		Use:     "smartcrop",
+		Short:   "crop the most interesting area of an image to the given dimensions",
+		Args:    cobra.ExactArgs(2),
+		Example: "smartcrop --size 1200x630 --print-rect input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			fin := args[0]
+			fout := args[1]
+
+			s, err := parseSizeStr(size)
+			exitIfNotNil(err)
+
+			apply(fin, fout, func(img image.Image) (image.Image, error) {
+				r := transform.SmartCropRect(img, s.Width, s.Height)
+				if printRect {
+					fmt.Fprintln(os.Stderr, formatRectStr(r))
+				}
+				return transform.Resize(transform.Crop(img, r), s.Width, s.Height, transform.Linear), nil
+			})
+		}}
+
+	cmd.Flags().StringVarP(&size, "size", "s", "", "output size as WxH (e.g. 1200x630)")
+	cmd.Flags().BoolVar(&printRect, "print-rect", false, "print the chosen crop rectangle to stderr as X0xY0+X1xY1, in the format taken by crop --rect")
+
+	return cmd
+}
+
+func seamcarve() *cobra.Command {
+	var size, protect, remove string
+
+	var cmd = &cobra.Command{
+		Use:     "seamcarve",
+		Short:   "resize an image to the given dimensions by removing or inserting low energy seams",
+		Args:    cobra.ExactArgs(2),
+		Example: "seamcarve --size 900x900 --protect faces.png input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			fin := args[0]
+			fout := args[1]
+
+			s, err := parseSizeStr(size)
+			exitIfNotNil(err)
+
+			opts := &transform.SeamCarveOptions{}
+			if protect != "" {
+				opts.Protect, err = openMask(protect)
+				exitIfNotNil(err)
+			}
+			if remove != "" {
+				opts.Remove, err = openMask(remove)
+				exitIfNotNil(err)
+			}
+
+			apply(fin, fout, func(img image.Image) (image.Image, error) {
+				return transform.SeamCarve(img, s.Width, s.Height, opts), nil
+			})
+		}}
+
+	cmd.Flags().StringVarP(&size, "size", "s", "", "output size as WxH (e.g. 900x900)")
+	cmd.Flags().StringVar(&protect, "protect", "", "grayscale mask image of the areas to keep, non-black pixels are protected")
+	cmd.Flags().StringVar(&remove, "remove", "", "grayscale mask image of the areas to remove first, non-black pixels are removed")
+
+	return cmd
+}
+
+func trim() *cobra.Command {
+	var border string
+	var tolerance uint8
+	var printRect bool
+
+	var cmd = &cobra.Command{
+		Use:     "trim",
+		Short:   "crop the uniform margins around the content of an image",
+		Args:    cobra.ExactArgs(2),
+		Example: "trim --border ffffff --tolerance 16 --print-rect input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			fin := args[0]
+			fout := args[1]
+
+			opts := &transform.TrimOptions{Tolerance: tolerance}
+			if border != "" {
+				c, err := parseColorStr(border)
+				exitIfNotNil(err)
+				opts.Border = c
+			}
+
+			apply(fin, fout, func(img image.Image) (image.Image, error) {
+				result, r := transform.Trim(img, opts)
+				if printRect {
+					fmt.Fprintln(os.Stderr, formatRectStr(r))
+				}
+				return result, nil
+			})
+		}}
+
+	cmd.Flags().StringVarP(&border, "border", "b", "", "margin color as hex RRGGBB or RRGGBBAA, defaults to the color of the top-left pixel")
+	cmd.Flags().Uint8VarP(&tolerance, "tolerance", "t", 0, "max difference between a pixel and the margin color for it to be trimmed (0 to 255)")
+	cmd.Flags().BoolVar(&printRect, "print-rect", false, "print the kept rectangle to stderr as X0xY0+X1xY1, in the format taken by crop --rect")
+
+	return cmd
+}
+
+func lens() *cobra.Command {
+	var coeffs transform.LensCoefficients
+	var undistort, scaleToFit bool
+	var background, filter string
+
+	var cmd = &cobra.Command{
+		Use:     "lens",
+		Short:   "apply or correct Brown-Conrady lens distortion",
+		Args:    cobra.ExactArgs(2),
+		Example: "lens --k1 -0.25 --k2 0.05 --undistort --fit input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			fin := args[0]
+			fout := args[1]
+
+			f, err := parseResampleFilter(filter)
+			exitIfNotNil(err)
+
+			opts := &transform.LensDistortOptions{Undistort: undistort, ScaleToFit: scaleToFit}
+			if background != "" {
+				c, err := parseColorStr(background)
+				exitIfNotNil(err)
+				opts.Background = c
+			}
+
+			apply(fin, fout, func(img image.Image) (image.Image, error) {
+				return transform.LensDistort(img, coeffs, f, opts), nil
+			})
+		}}
+
+	cmd.Flags().Float64Var(&coeffs.K1, "k1", 0, "first radial coefficient (negative for barrel, positive for pincushion)")
+	cmd.Flags().Float64Var(&coeffs.K2, "k2", 0, "second radial coefficient")
+	cmd.Flags().Float64Var(&coeffs.K3, "k3", 0, "third radial coefficient")
+	cmd.Flags().Float64Var(&coeffs.P1, "p1", 0, "first tangential coefficient")
+	cmd.Flags().Float64Var(&coeffs.P2, "p2", 0, "second tangential coefficient")
+	cmd.Flags().BoolVar(&undistort, "undistort", false, "remove the distortion described by the coefficients instead of applying it")
+	cmd.Flags().BoolVar(&scaleToFit, "fit", false, "scale the result so that it has no empty areas at its edges")
+	cmd.Flags().StringVarP(&background, "background", "b", "", "background color as hex RRGGBB or RRGGBBAA, defaults to transparent")
+	cmd.Flags().StringVarP(&filter, "filter", "f", "linear", "resampling filter (nearestneighbor, box, linear, gaussian, mitchellnetravali, catmullrom, lanczos)")
+
+	return cmd
+}
+
+func swirl() *cobra.Command {
+	var angle, radius float64
+
+	var cmd = &cobra.Command{
+		Use:     "swirl",
+		Short:   "twist an image around its center",
+		Args:    cobra.ExactArgs(2),
+		Example: "swirl --angle 180 --radius 120 input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			apply(args[0], args[1], func(img image.Image) (image.Image, error) {
+				return transform.Swirl(img, angle, radius), nil
+			})
+		}}
+
+	cmd.Flags().Float64VarP(&angle, "angle", "a", 90, "twist angle in degrees at the center (clockwise)")
+	cmd.Flags().Float64VarP(&radius, "radius", "r", 100, "radius of the twisted area in pixels")
+
+	return cmd
+}
+
+func ripple() *cobra.Command {
+	var amplitude, wavelength float64
+
+	var cmd = &cobra.Command{
+		Use:     "ripple",
+		Short:   "displace an image with sine waves",
+		Args:    cobra.ExactArgs(2),
+		Example: "ripple --amplitude 4 --wavelength 32 input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			apply(args[0], args[1], func(img image.Image) (image.Image, error) {
+				return transform.Ripple(img, amplitude, wavelength), nil
+			})
+		}}
+
+	cmd.Flags().Float64VarP(&amplitude, "amplitude", "a", 4, "max displacement in pixels")
+	cmd.Flags().Float64VarP(&wavelength, "wavelength", "l", 32, "length of a wave in pixels")
+
+	return cmd
+}
+
+func spherize() *cobra.Command {
+	var strength float64
+
+	var cmd = &cobra.Command{
+		Use:     "spherize",
+		Short:   "wrap an image around a sphere",
+		Args:    cobra.ExactArgs(2),
+		Example: "spherize --strength 0.8 input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			apply(args[0], args[1], func(img image.Image) (image.Image, error) {
+				return transform.Spherize(img, strength), nil
+			})
+		}}
+
+	cmd.Flags().Float64VarP(&strength, "strength", "s", 1, "strength from -1.0 to 1.0, positive bulges and negative pinches")
+
+	return cmd
+}
+
+func polar() *cobra.Command {
+	var inverse bool
+
+	var cmd = &cobra.Command{
+		Use:     "polar",
+		Short:   "convert an image to polar coordinates, or back with --inverse",
+		Args:    cobra.ExactArgs(2),
+		Example: "polar --inverse input.jpg output.jpg",
+		Run: func(cmd *cobra.Command, args []string) {
+			apply(args[0], args[1], func(img image.Image) (image.Image, error) {
+				if inverse {
+					return transform.PolarToCartesian(img), nil
+				}
+				return transform.CartesianToPolar(img), nil
+			})
+		}}
+
+	cmd.Flags().BoolVar(&inverse, "inverse", false, "convert from polar to cartesian coordinates")
+
+	return cmd
+}
+
 func createTransform() *cobra.Command {
 	var transformCmd = &cobra.Command{
 		Use:   "transform",
@@ -228,6 +542,15 @@ func createTransform() *cobra.Command {
 	transformCmd.AddCommand(translate())
 	transformCmd.AddCommand(shearh())
 	transformCmd.AddCommand(shearv())
+	transformCmd.AddCommand(perspective())
+	transformCmd.AddCommand(smartcrop())
+	transformCmd.AddCommand(seamcarve())
+	transformCmd.AddCommand(trim())
+	transformCmd.AddCommand(lens())
+	transformCmd.AddCommand(swirl())
+	transformCmd.AddCommand(ripple())
+	transformCmd.AddCommand(spherize())
+	transformCmd.AddCommand(polar())
 
 	return transformCmd
 }
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthonynsimon/bild/imgio"
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// run executes the command line args and returns what it wrote to the standard output and error.
+func run(t *testing.T, args ...string) ([]byte, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	origStdout, origStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	os.Stdout, os.Stderr = origStdout, origStderr
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errOut, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out, errOut
+}
+
+func TestPrintRectStdout(t *testing.T) {
+	// White image with a black square in the middle
+	img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+			if x >= 3 && x < 5 && y >= 2 && y < 4 {
+				c = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	input := filepath.Join(t.TempDir(), "input.png")
+	if err := imgio.Save(input, img, imgio.PNGEncoder()); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		description string
+		args        []string
+		rect        image.Rectangle
+		size        image.Point
+	}{
+		{
+			description: "trim",
+			args:        []string{"transform", "trim", "--print-rect", "--format", "png", input, "-"},
+			rect:        image.Rect(3, 2, 5, 4),
+			size:        image.Pt(2, 2),
+		},
+		{
+			description: "smartcrop",
+			args:        []string{"transform", "smartcrop", "--size", "4x4", "--print-rect", "--format", "png", input, "-"},
+			rect:        transform.SmartCropRect(img, 4, 4),
+			size:        image.Pt(4, 4),
+		},
+	}
+
+	for _, c := range cases {
+		stdout, stderr := run(t, c.args...)
+
+		// The rectangle doesn't end up in the image written to the standard output
+		result, format, err := imgio.Decode(bytes.NewReader(stdout))
+		if err != nil || format != imgio.PNG {
+			t.Errorf("%s: expected: %v actual: %v %v", c.description+" stdout", imgio.PNG, format, err)
+		} else if actual := result.Bounds().Size(); actual != c.size {
+			t.Errorf("%s: expected: %v actual: %v", c.description+" size", c.size, actual)
+		}
+
+		expected := formatRectStr(c.rect) + "\n"
+		if actual := string(stderr); actual != expected {
+			t.Errorf("%s: expected: %q actual: %q", c.description+" stderr", expected, actual)
+		}
+	}
+}
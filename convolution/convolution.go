@@ -2,11 +2,14 @@
 package convolution
 
 import (
+	"context"
 	"image"
 	"math"
 
 	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/fcolor"
 	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/pixel"
 )
 
 // Options are the Convolve function parameters.
@@ -25,6 +28,49 @@ type Options struct {
 //
 //	result := Convolve(img, kernel, &Options{Bias: 0, Wrap: false})
 func Convolve(img image.Image, k Matrix, o *Options) *image.RGBA {
+	result, _ := ConvolveCtx(context.Background(), img, k, o)
+	return result
+}
+
+// ConvolveCtx applies a convolution matrix (kernel) to an image with the supplied options, like Convolve,
+// but stops early and returns ctx.Err() if ctx is done before it completes. Its progress is reported to
+// the callback set with parallel.WithProgress.
+//
+// Usage example:
+//
+//	result, err := ConvolveCtx(ctx, img, kernel, &Options{Bias: 0, Wrap: false})
+func ConvolveCtx(ctx context.Context, img image.Image, k Matrix, o *Options) (*image.RGBA, error) {
+	dst := image.NewRGBA(img.Bounds())
+	if err := ConvolveIntoCtx(ctx, dst, img, k, o); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ConvolveInto applies a convolution matrix (kernel) to an image with the supplied options, like Convolve,
+// but writes the result into dst rather than allocating a new image. It panics if dst and img don't
+// have the same size, and dst must not share its pixels with img.
+//
+// Usage example:
+//
+//	dst := image.NewRGBA(img.Bounds())
+//	ConvolveInto(dst, img, kernel, &Options{Bias: 0, Wrap: false})
+func ConvolveInto(dst *image.RGBA, img image.Image, k Matrix, o *Options) {
+	ConvolveIntoCtx(context.Background(), dst, img, k, o)
+}
+
+// ConvolveIntoCtx applies a convolution matrix (kernel) to an image with the supplied options into dst,
+// like ConvolveInto, but stops early and returns ctx.Err() if ctx is done before it completes, in which
+// case the pixels of dst are left partly written.
+//
+// Usage example:
+//
+//	err := ConvolveIntoCtx(ctx, dst, img, kernel, &Options{Bias: 0, Wrap: false})
+func ConvolveIntoCtx(ctx context.Context, dst *image.RGBA, img image.Image, k Matrix, o *Options) error {
+	if dst.Rect.Size() != img.Bounds().Size() {
+		panic("convolution: destination and source images have different sizes")
+	}
+
 	// Config the convolution
 	bias := 0.0
 	wrap := false
@@ -35,100 +81,256 @@ func Convolve(img image.Image, k Matrix, o *Options) *image.RGBA {
 		keepAlpha = o.KeepAlpha
 	}
 
-	return execute(img, k, bias, wrap, keepAlpha)
+	return execute(ctx, dst, img, k, bias, wrap, keepAlpha)
 }
 
-func execute(img image.Image, k Matrix, bias float64, wrap, keepAlpha bool) *image.RGBA {
-	// Kernel attributes
+// ConvolveF64 applies a convolution matrix (kernel) to a float image with the supplied options,
+// like Convolve but without rounding nor clamping the resulting values. The Bias option keeps the
+// same range of -255 to 255, being scaled to that of the channels.
+//
+// Usage example:
+//
+//	result := ConvolveF64(fimg, kernel, &Options{Wrap: true})
+func ConvolveF64(img *fcolor.Image, k Matrix, o *Options) *fcolor.Image {
+	bias := 0.0
+	wrap := false
+	keepAlpha := false
+	if o != nil {
+		wrap = o.Wrap
+		bias = o.Bias / 255
+		keepAlpha = o.KeepAlpha
+	}
+
 	lenX := k.MaxX()
 	lenY := k.MaxY()
 	radiusX := lenX / 2
 	radiusY := lenY / 2
 
-	// Pad the source image, basically pre-computing the pixels outside of image bounds
-	var src *image.RGBA
-	if wrap {
-		src = clone.Pad(img, radiusX, radiusY, clone.EdgeWrap)
-	} else {
-		src = clone.Pad(img, radiusX, radiusY, clone.EdgeExtend)
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dst := fcolor.NewImage(bounds)
+	if w == 0 || h == 0 {
+		return dst
 	}
 
-	// src bounds now includes padded pixels
-	srcBounds := src.Bounds()
-	srcW, srcH := srcBounds.Dx(), srcBounds.Dy()
-	dst := image.NewRGBA(img.Bounds())
+	cols := edgeOffsets(w, radiusX, 4, wrap)
+	rows := edgeOffsets(h, radiusY, img.Stride, wrap)
 
-	// To keep alpha we simply don't convolve it
-	if keepAlpha {
-		// Notice we can't use lenY since it will be larger than the actual padding pixels
-		// as it includes the identity element
-		parallel.Line(srcH-(radiusY*2), func(start, end int) {
-			// Correct range so we don't iterate over the padded pixels on the main loop
-			for y := start + radiusY; y < end+radiusY; y++ {
-				for x := radiusX; x < srcW-radiusX; x++ {
-
-					var r, g, b float64
-					// Kernel has access to the padded pixels
-					for ky := 0; ky < lenY; ky++ {
-						iy := y - radiusY + ky
-
-						for kx := 0; kx < lenX; kx++ {
-							ix := x - radiusX + kx
-
-							kvalue := k.At(kx, ky)
-							ipos := iy*src.Stride + ix*4
-							r += float64(src.Pix[ipos+0]) * kvalue
-							g += float64(src.Pix[ipos+1]) * kvalue
-							b += float64(src.Pix[ipos+2]) * kvalue
-						}
-					}
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				var r, g, b, a float64
+				for ky := 0; ky < lenY; ky++ {
+					iy := rows[y+ky]
 
-					// Map x and y indices to non-padded range
-					pos := (y-radiusY)*dst.Stride + (x-radiusX)*4
+					for kx := 0; kx < lenX; kx++ {
+						kvalue := k.At(kx, ky)
+						ipos := iy + cols[x+kx]
+						r += img.Pix[ipos+0] * kvalue
+						g += img.Pix[ipos+1] * kvalue
+						b += img.Pix[ipos+2] * kvalue
+						a += img.Pix[ipos+3] * kvalue
+					}
+				}
 
-					dst.Pix[pos+0] = uint8(math.Max(math.Min(r+bias, 255), 0))
-					dst.Pix[pos+1] = uint8(math.Max(math.Min(g+bias, 255), 0))
-					dst.Pix[pos+2] = uint8(math.Max(math.Min(b+bias, 255), 0))
-					dst.Pix[pos+3] = src.Pix[y*src.Stride+x*4+3]
+				pos := y*dst.Stride + x*4
+				dst.Pix[pos+0] = r + bias
+				dst.Pix[pos+1] = g + bias
+				dst.Pix[pos+2] = b + bias
+				if keepAlpha {
+					dst.Pix[pos+3] = img.Pix[y*img.Stride+x*4+3]
+				} else {
+					dst.Pix[pos+3] = a
 				}
 			}
-		})
-	} else {
-		// Notice we can't use lenY since it will be larger than the actual padding pixels
-		// as it includes the identity element
-		parallel.Line(srcH-(radiusY*2), func(start, end int) {
-			// Correct range so we don't iterate over the padded pixels on the main loop
-			for y := start + radiusY; y < end+radiusY; y++ {
-				for x := radiusX; x < srcW-radiusX; x++ {
-
-					var r, g, b, a float64
-					// Kernel has access to the padded pixels
-					for ky := 0; ky < lenY; ky++ {
-						iy := y - radiusY + ky
-
-						for kx := 0; kx < lenX; kx++ {
-							ix := x - radiusX + kx
-
-							kvalue := k.At(kx, ky)
-							ipos := iy*src.Stride + ix*4
-							r += float64(src.Pix[ipos+0]) * kvalue
-							g += float64(src.Pix[ipos+1]) * kvalue
-							b += float64(src.Pix[ipos+2]) * kvalue
-							a += float64(src.Pix[ipos+3]) * kvalue
+		}
+	})
+
+	return dst
+}
+
+// Convolve64 applies a convolution matrix (kernel) to an image with the supplied options, like Convolve
+// but keeping 16 bits per channel. The Bias option keeps the same range of -255 to 255, being scaled
+// to that of the channels.
+//
+// Usage example:
+//
+//	result := Convolve64(img, kernel, &Options{Bias: 0, Wrap: false})
+func Convolve64(img image.Image, k Matrix, o *Options) *image.RGBA64 {
+	return ConvolveNative(clone.AsShallowRGBA64(img), k, o)
+}
+
+// ConvolveNative applies a convolution matrix (kernel) to an image with the supplied options, like Convolve
+// but running directly on the pixels of img and returning an image of the same type. The Bias option keeps
+// the same range of -255 to 255, being scaled to that of the channels.
+//
+// Usage example:
+//
+//	// Sharpens a grayscale image without converting it to RGBA
+//	result := ConvolveNative(gray, kernel, &Options{Bias: 0, Wrap: false})
+func ConvolveNative[I pixel.Image](img I, k Matrix, o *Options) I {
+	return pixel.Process(img,
+		func(b *pixel.Buffer[uint8]) *pixel.Buffer[uint8] { return ConvolveBuffer(b, k, o) },
+		func(b *pixel.Buffer[uint16]) *pixel.Buffer[uint16] { return ConvolveBuffer(b, k, o) })
+}
+
+// ConvolveBuffer applies a convolution matrix (kernel) to the pixel buffer with the supplied options and
+// returns the result as a new buffer of the same layout. The colors of non-premultiplied pixels are weighted
+// by their alpha value, so that the result matches that of their premultiplied counterpart.
+//
+// Usage example:
+//
+//	result := ConvolveBuffer(buf, kernel, &Options{Bias: 0, Wrap: false})
+func ConvolveBuffer[T pixel.Sample](src *pixel.Buffer[T], k Matrix, o *Options) *pixel.Buffer[T] {
+	maxValue := float64(src.Max())
+
+	bias := 0.0
+	wrap := false
+	keepAlpha := false
+	if o != nil {
+		wrap = o.Wrap
+		bias = o.Bias * maxValue / 0xFF
+		keepAlpha = o.KeepAlpha
+	}
+
+	lenX := k.MaxX()
+	lenY := k.MaxY()
+	radiusX := lenX / 2
+	radiusY := lenY / 2
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	n := src.Channels()
+	straight := src.Layout == pixel.NRGBA
+	dst := pixel.NewBuffer[T](src.Rect, src.Layout)
+	if w == 0 || h == 0 {
+		return dst
+	}
+
+	cols := edgeOffsets(w, radiusX, n, wrap)
+	rows := edgeOffsets(h, radiusY, src.Stride, wrap)
+	// Values are truncated like those of Convolve, so that both give the same result for an *image.RGBA
+	clamp := func(v float64) T {
+		return T(math.Max(math.Min(v, maxValue), 0))
+	}
+
+	parallel.Line(h, func(start, end int) {
+		sums := make([]float64, n)
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				clear(sums)
+				for ky := 0; ky < lenY; ky++ {
+					iy := rows[y+ky]
+
+					for kx := 0; kx < lenX; kx++ {
+						kvalue := k.At(kx, ky)
+						ipos := iy + cols[x+kx]
+						if straight {
+							a := float64(src.Pix[ipos+3])
+							sums[0] += float64(src.Pix[ipos+0]) * a / maxValue * kvalue
+							sums[1] += float64(src.Pix[ipos+1]) * a / maxValue * kvalue
+							sums[2] += float64(src.Pix[ipos+2]) * a / maxValue * kvalue
+							sums[3] += a * kvalue
+							continue
+						}
+						for c := 0; c < n; c++ {
+							sums[c] += float64(src.Pix[ipos+c]) * kvalue
 						}
 					}
+				}
 
-					// Map x and y indices to non-padded range
-					pos := (y-radiusY)*dst.Stride + (x-radiusX)*4
+				pos := y*dst.Stride + x*n
+				if n == 1 {
+					dst.Pix[pos] = clamp(sums[0] + bias)
+					continue
+				}
 
-					dst.Pix[pos+0] = uint8(math.Max(math.Min(r+bias, 255), 0))
-					dst.Pix[pos+1] = uint8(math.Max(math.Min(g+bias, 255), 0))
-					dst.Pix[pos+2] = uint8(math.Max(math.Min(b+bias, 255), 0))
-					dst.Pix[pos+3] = uint8(math.Max(math.Min(a, 255), 0))
+				alpha := clamp(sums[3])
+				if keepAlpha {
+					alpha = src.Pix[y*src.Stride+x*n+3]
+				}
+				dst.Pix[pos+3] = alpha
+				for c := 0; c < 3; c++ {
+					if !straight {
+						dst.Pix[pos+c] = clamp(sums[c] + bias)
+					} else if alpha > 0 {
+						dst.Pix[pos+c] = clamp((sums[c] + bias) * maxValue / float64(alpha))
+					}
 				}
 			}
-		})
-	}
+		}
+	})
 
 	return dst
 }
+
+func execute(ctx context.Context, dst *image.RGBA, img image.Image, k Matrix, bias float64, wrap, keepAlpha bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Kernel attributes
+	lenX := k.MaxX()
+	lenY := k.MaxY()
+	radiusX := lenX / 2
+	radiusY := lenY / 2
+
+	src := clone.AsShallowRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w == 0 || h == 0 {
+		return nil
+	}
+
+	// Offsets in src.Pix of the columns and rows read by the kernel, those outside of the image being taken
+	// from its closest edge, or its opposite side when wrapping, instead of allocating a padded copy of it
+	cols := edgeOffsets(w, radiusX, 4, wrap)
+	rows := edgeOffsets(h, radiusY, src.Stride, wrap)
+
+	return parallel.LineCtx(ctx, h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				var r, g, b, a float64
+				for ky := 0; ky < lenY; ky++ {
+					iy := rows[y+ky]
+
+					for kx := 0; kx < lenX; kx++ {
+						kvalue := k.At(kx, ky)
+						ipos := iy + cols[x+kx]
+						r += float64(src.Pix[ipos+0]) * kvalue
+						g += float64(src.Pix[ipos+1]) * kvalue
+						b += float64(src.Pix[ipos+2]) * kvalue
+						a += float64(src.Pix[ipos+3]) * kvalue
+					}
+				}
+
+				pos := y*dst.Stride + x*4
+				dst.Pix[pos+0] = uint8(math.Max(math.Min(r+bias, 255), 0))
+				dst.Pix[pos+1] = uint8(math.Max(math.Min(g+bias, 255), 0))
+				dst.Pix[pos+2] = uint8(math.Max(math.Min(b+bias, 255), 0))
+				// To keep alpha we simply don't convolve it
+				if keepAlpha {
+					dst.Pix[pos+3] = src.Pix[y*src.Stride+x*4+3]
+				} else {
+					dst.Pix[pos+3] = uint8(math.Max(math.Min(a, 255), 0))
+				}
+			}
+		}
+	})
+}
+
+// edgeOffsets returns the offsets of the indices from -radius to n+radius-1 along a dimension of n pixels,
+// each step being size long. Indices outside of the range 0 to n-1 are clamped to it, or wrapped around it.
+func edgeOffsets(n, radius, size int, wrap bool) []int {
+	offsets := make([]int, n+2*radius)
+	for i := range offsets {
+		j := i - radius
+		if wrap {
+			if j %= n; j < 0 {
+				j += n
+			}
+		} else {
+			j = min(max(j, 0), n-1)
+		}
+		offsets[i] = j * size
+	}
+	return offsets
+}
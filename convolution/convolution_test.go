@@ -1,9 +1,14 @@
 package convolution
 
 import (
+	"context"
 	"image"
+	"image/color"
+	"reflect"
 	"testing"
 
+	"github.com/anthonynsimon/bild/fcolor"
+	"github.com/anthonynsimon/bild/parallel"
 	"github.com/anthonynsimon/bild/util"
 )
 
@@ -205,6 +210,200 @@ func TestConvolve(t *testing.T) {
 	}
 }
 
+func TestConvolve64(t *testing.T) {
+	value := &image.RGBA64{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 8,
+		Pix: []uint8{
+			0x00, 0x01, 0x12, 0x34, 0x00, 0x00, 0x40, 0x00,
+			0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00,
+			0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x60, 0x00,
+		},
+	}
+	kernel := &Kernel{[]float64{1, 1, 1}, 3, 1}
+
+	cases := []struct {
+		description string
+		options     *Options
+		expected    *image.RGBA64
+	}{
+		{
+			description: "clamped edges",
+			options:     nil,
+			expected: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 8,
+				Pix: []uint8{
+					0x00, 0x04, 0x24, 0x68, 0x00, 0x00, 0xC0, 0x00,
+					0x00, 0x06, 0x12, 0x34, 0x00, 0x00, 0xE0, 0x00,
+					0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0xFF, 0xFF,
+				},
+			},
+		},
+		{
+			description: "wrapped edges",
+			options:     &Options{Wrap: true},
+			expected: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 8,
+				Pix: []uint8{
+					0x00, 0x06, 0x12, 0x34, 0x00, 0x00, 0xE0, 0x00,
+					0x00, 0x06, 0x12, 0x34, 0x00, 0x00, 0xE0, 0x00,
+					0x00, 0x06, 0x12, 0x34, 0x00, 0x00, 0xE0, 0x00,
+				},
+			},
+		},
+		{
+			description: "bias and alpha kept",
+			options:     &Options{Bias: 1, KeepAlpha: true},
+			expected: &image.RGBA64{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 8,
+				Pix: []uint8{
+					0x01, 0x05, 0x25, 0x69, 0x01, 0x01, 0x40, 0x00,
+					0x01, 0x07, 0x13, 0x35, 0x01, 0x01, 0x40, 0x00,
+					0x01, 0x09, 0x01, 0x01, 0x01, 0x01, 0x60, 0x00,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := Convolve64(value, kernel, c.options)
+		if !util.RGBA64ImageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %v actual: %v", "Convolve64 "+c.description, c.expected.Pix, actual.Pix)
+		}
+	}
+}
+
+func TestConvolveNative(t *testing.T) {
+	kernel := &Kernel{[]float64{1, 1, 1}, 3, 1}
+
+	gray := &image.Gray{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3,
+		Pix:    []uint8{0x10, 0x20, 0x80},
+	}
+	expected := &image.Gray{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3,
+		Pix:    []uint8{0x40, 0xB0, 0xFF},
+	}
+	if actual := ConvolveNative(gray, kernel, nil); !util.GrayImageEqual(actual, expected) {
+		t.Errorf("%s: expected: %v actual: %v", "ConvolveNative Gray", expected.Pix, actual.Pix)
+	}
+
+	gray16 := &image.Gray16{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 2,
+		Pix:    []uint8{0x00, 0x01, 0x00, 0x02, 0x00, 0x03},
+	}
+	expected16 := &image.Gray16{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 2,
+		Pix:    []uint8{0x01, 0x05, 0x01, 0x07, 0x01, 0x09},
+	}
+	if actual := ConvolveNative(gray16, kernel, &Options{Bias: 1}); !reflect.DeepEqual(actual, expected16) {
+		t.Errorf("%s: expected: %v actual: %v", "ConvolveNative Gray16", expected16.Pix, actual.Pix)
+	}
+
+	// RGBA images give the same result as Convolve
+	rgba := image.NewRGBA(image.Rect(2, 1, 10, 7))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i * 37)
+	}
+	sharpen := &Kernel{[]float64{0, -0.5, 0, -0.5, 3.1, -0.5, 0, -0.5, 0}, 3, 3}
+	for _, o := range []*Options{nil, {KeepAlpha: true}, {Wrap: true}, {Bias: 12.5}} {
+		for _, k := range []*Kernel{kernel, sharpen} {
+			expected := Convolve(rgba, k, o)
+			if actual := ConvolveNative(rgba, k, o); !util.RGBAImageEqual(actual, expected) {
+				t.Errorf("%s: expected: %v actual: %v", "ConvolveNative RGBA", expected.Pix, actual.Pix)
+			}
+		}
+	}
+
+	// Non-premultiplied colors match those of the premultiplied convolution
+	nrgba := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0xFF, 0x00, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0x00, 0x00, 0x00, 0xFF, 0x80,
+		},
+	}
+	blur := &Kernel{[]float64{0.25, 0.5, 0.25}, 3, 1}
+	for _, o := range []*Options{nil, {KeepAlpha: true}, {Wrap: true}} {
+		actual := ConvolveNative(nrgba, blur, o)
+		premultiplied := Convolve(nrgba, blur, o)
+		for x := 0; x < 3; x++ {
+			e, a := color.NRGBAModel.Convert(premultiplied.At(x, 0)).(color.NRGBA), actual.NRGBAAt(x, 0)
+			for _, d := range []int{int(e.R) - int(a.R), int(e.G) - int(a.G), int(e.B) - int(a.B), int(e.A) - int(a.A)} {
+				if d < -2 || d > 2 {
+					t.Errorf("%s: expected: %v actual: %v", "ConvolveNative NRGBA", e, a)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestConvolveF64(t *testing.T) {
+	value := &fcolor.Image{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 4,
+		Pix: []float64{
+			2, 0, -1, 1, 0, 0, 0, 0.5, 4, 0, 0, 1,
+		},
+	}
+	kernel := &Kernel{[]float64{1, 1, 1}, 3, 1}
+
+	cases := []struct {
+		description string
+		options     *Options
+		expected    *fcolor.Image
+	}{
+		{
+			description: "clamped edges",
+			options:     nil,
+			expected: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 4,
+				Pix: []float64{
+					4, 0, -2, 2.5, 6, 0, -1, 2.5, 8, 0, 0, 2.5,
+				},
+			},
+		},
+		{
+			description: "wrapped edges",
+			options:     &Options{Wrap: true},
+			expected: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 4,
+				Pix: []float64{
+					6, 0, -1, 2.5, 6, 0, -1, 2.5, 6, 0, -1, 2.5,
+				},
+			},
+		},
+		{
+			description: "bias and alpha kept",
+			options:     &Options{Bias: 255, KeepAlpha: true},
+			expected: &fcolor.Image{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 4,
+				Pix: []float64{
+					5, 1, -1, 1, 7, 1, 0, 0.5, 9, 1, 1, 1,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := ConvolveF64(value, kernel, c.options)
+		if !util.F64ImageApproxEqual(actual, c.expected, 1e-9) {
+			t.Errorf("%s: expected: %v actual: %v", "ConvolveF64 "+c.description, c.expected.Pix, actual.Pix)
+		}
+	}
+}
+
 func BenchmarkConvolve3(b *testing.B) {
 	benchConvolve(b, 1024, 1024, NewKernel(3, 3))
 }
@@ -228,3 +427,85 @@ func benchConvolve(b *testing.B, w, h int, k *Kernel) {
 		benchResult = Convolve(img, k, &Options{Wrap: false})
 	}
 }
+
+func TestConvolveCtx(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	k := NewKernel(3, 3)
+	for i := range k.Matrix {
+		k.Matrix[i] = 1.0 / 9
+	}
+
+	done := 0.0
+	ctx := parallel.WithProgress(context.Background(), func(d float64) { done = d })
+	actual, err := ConvolveCtx(ctx, img, k, nil)
+	if err != nil || !util.RGBAImageEqual(actual, Convolve(img, k, nil)) {
+		t.Errorf("%s: expected: %v actual: %v", "ConvolveCtx", "same result as Convolve", err)
+	}
+	if done != 1 {
+		t.Errorf("%s: expected: %v actual: %v", "ConvolveCtx progress", 1.0, done)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if actual, err := ConvolveCtx(canceled, img, k, nil); err != context.Canceled || actual != nil {
+		t.Errorf("%s: expected: %v actual: %v %v", "ConvolveCtx canceled", context.Canceled, err, actual)
+	}
+}
+
+func TestEdgeOffsets(t *testing.T) {
+	cases := []struct {
+		description     string
+		n, radius, size int
+		wrap            bool
+		expected        []int
+	}{
+		{"extend", 3, 2, 4, false, []int{0, 0, 0, 4, 8, 8, 8}},
+		{"wrap", 3, 2, 4, true, []int{4, 8, 0, 4, 8, 0, 4}},
+		{"wrap past the size", 2, 3, 1, true, []int{1, 0, 1, 0, 1, 0, 1, 0}},
+		{"no radius", 2, 0, 10, false, []int{0, 10}},
+	}
+
+	for _, c := range cases {
+		actual := edgeOffsets(c.n, c.radius, c.size, c.wrap)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %v actual: %v", "edgeOffsets "+c.description, c.expected, actual)
+		}
+	}
+}
+
+func TestConvolveInto(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 9, 7))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 5)
+	}
+	k := NewKernel(3, 3)
+	for i := range k.Matrix {
+		k.Matrix[i] = float64(i%3) / 4
+	}
+
+	options := []*Options{nil, {Wrap: true}, {Bias: 10, KeepAlpha: true}}
+	for _, o := range options {
+		expected := Convolve(img, k, o)
+
+		// The destination doesn't need to share the bounds of the source, only its size, nor to be cleared
+		dst := image.NewRGBA(image.Rect(-3, 5, 6, 12))
+		for i := range dst.Pix {
+			dst.Pix[i] = 0xAB
+		}
+		ConvolveInto(dst, img, k, o)
+		actual := &image.RGBA{Pix: dst.Pix, Stride: dst.Stride, Rect: img.Rect}
+		if !util.RGBAImageEqual(actual, expected) {
+			t.Errorf("%s: expected: %v actual: %v", "ConvolveInto", expected, actual)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected: %v actual: %v", "ConvolveInto different sizes", "panic", nil)
+		}
+	}()
+	ConvolveInto(image.NewRGBA(image.Rect(0, 0, 9, 6)), img, k, nil)
+}
@@ -0,0 +1,139 @@
+package fcolor
+
+import (
+	"image"
+	"image/color"
+)
+
+// Image is an in-memory image whose At method returns RGBAF64 values.
+// Unlike *image.RGBA, its channels aren't rounded to 8 bits nor limited to the range 0.0 to 1.0,
+// so that it can hold high dynamic range data and the intermediate results of a chain of operations.
+type Image struct {
+	// Pix holds the image's pixels, in R, G, B, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float64
+	// Stride is the Pix stride (in number of float64 values) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewImage returns a new Image with the given bounds.
+//
+// Usage example:
+//
+//	img := fcolor.NewImage(image.Rect(0, 0, 640, 480))
+func NewImage(r image.Rectangle) *Image {
+	return &Image{
+		Pix:    make([]float64, 4*r.Dx()*r.Dy()),
+		Stride: 4 * r.Dx(),
+		Rect:   r,
+	}
+}
+
+// NewImageFrom returns an Image holding a copy of the provided image, with the same bounds.
+//
+// Usage example:
+//
+//	fimg := fcolor.NewImageFrom(img)
+func NewImageFrom(src image.Image) *Image {
+	bounds := src.Bounds()
+	dst := NewImage(bounds)
+
+	switch src := src.(type) {
+	case *Image:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			copy(dst.Pix[dst.PixOffset(bounds.Min.X, y):], src.Pix[src.PixOffset(bounds.Min.X, y):src.PixOffset(bounds.Max.X, y)])
+		}
+	case *image.RGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			srcPos, dstPos := src.PixOffset(bounds.Min.X, y), dst.PixOffset(bounds.Min.X, y)
+			for i := 0; i < bounds.Dx()*4; i++ {
+				dst.Pix[dstPos+i] = float64(src.Pix[srcPos+i]) / 255
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				dst.SetRGBAF64(x, y, RGBAF64Model.Convert(src.At(x, y)).(RGBAF64))
+			}
+		}
+	}
+
+	return dst
+}
+
+// ColorModel returns the RGBAF64 color model.
+func (p *Image) ColorModel() color.Model { return RGBAF64Model }
+
+// Bounds returns the domain for which At returns the pixels of the image.
+func (p *Image) Bounds() image.Rectangle { return p.Rect }
+
+// At returns the color of the pixel at (x, y), or a transparent one outside of the bounds.
+func (p *Image) At(x, y int) color.Color {
+	return p.RGBAF64At(x, y)
+}
+
+// RGBAF64At returns the color of the pixel at (x, y), or a transparent one outside of the bounds.
+func (p *Image) RGBAF64At(x, y int) RGBAF64 {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return RGBAF64{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	return RGBAF64{s[0], s[1], s[2], s[3]}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to the pixel at (x, y).
+func (p *Image) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set sets the pixel at (x, y) to the color, converted to RGBAF64.
+func (p *Image) Set(x, y int, c color.Color) {
+	p.SetRGBAF64(x, y, RGBAF64Model.Convert(c).(RGBAF64))
+}
+
+// SetRGBAF64 sets the pixel at (x, y) to the color, which isn't clamped.
+func (p *Image) SetRGBAF64(x, y int, c RGBAF64) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+}
+
+// SubImage returns an image representing the portion of the image p visible through r.
+// The returned value shares pixels with the original image.
+func (p *Image) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &Image{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &Image{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *Image) Opaque() bool {
+	if p.Rect.Empty() {
+		return true
+	}
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := p.PixOffset(p.Rect.Min.X, y)
+		for end := i + p.Rect.Dx()*4; i < end; i += 4 {
+			if p.Pix[i+3] < 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package fcolor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+	img := NewImage(image.Rect(-1, 2, 3, 5))
+	img.SetRGBAF64(0, 3, RGBAF64{R: 4.5, G: -0.5, B: 0.25, A: 1})
+	img.Set(2, 4, color.RGBA{0xFF, 0x80, 0x00, 0xFF})
+	img.SetRGBAF64(10, 10, RGBAF64{R: 1, A: 1})
+
+	cases := []struct {
+		description string
+		x, y        int
+		expected    RGBAF64
+	}{
+		{description: "unclamped", x: 0, y: 3, expected: RGBAF64{R: 4.5, G: -0.5, B: 0.25, A: 1}},
+		{description: "converted", x: 2, y: 4, expected: RGBAF64{R: 1, G: float64(0x8080) / 0xFFFF, B: 0, A: 1}},
+		{description: "empty", x: -1, y: 2, expected: RGBAF64{}},
+		{description: "outside", x: 10, y: 10, expected: RGBAF64{}},
+	}
+
+	for _, c := range cases {
+		if actual := img.At(c.x, c.y); actual != c.expected {
+			t.Errorf("%s: expected: %#v actual: %#v", "Image.At "+c.description, c.expected, actual)
+		}
+	}
+
+	sub := img.SubImage(image.Rect(0, 3, 10, 10)).(*Image)
+	if sub.Bounds() != image.Rect(0, 3, 3, 5) {
+		t.Errorf("%s: expected: %v actual: %v", "Image.SubImage", image.Rect(0, 3, 3, 5), sub.Bounds())
+	}
+	sub.SetRGBAF64(1, 4, RGBAF64{R: 2, A: 1})
+	if actual := img.RGBAF64At(1, 4); actual != (RGBAF64{R: 2, A: 1}) {
+		t.Errorf("%s: expected the pixels to be shared, actual: %#v", "Image.SubImage", actual)
+	}
+	if empty := img.SubImage(image.Rect(20, 20, 30, 30)); !empty.Bounds().Empty() {
+		t.Errorf("%s: expected an empty image, actual: %v", "Image.SubImage", empty.Bounds())
+	}
+
+	if img.Opaque() || !sub.SubImage(image.Rect(0, 3, 1, 4)).(*Image).Opaque() {
+		t.Errorf("%s: expected the image to be translucent and the pixel opaque", "Image.Opaque")
+	}
+}
+
+func TestNewImageFrom(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(1, 1, 3, 2))
+	copy(rgba.Pix, []uint8{0xFF, 0x00, 0x33, 0xFF, 0x40, 0x40, 0x40, 0x80})
+	gray := image.NewGray(image.Rect(0, 0, 2, 1))
+	copy(gray.Pix, []uint8{0x00, 0xFF})
+	float := NewImage(image.Rect(0, 0, 1, 1))
+	float.SetRGBAF64(0, 0, RGBAF64{R: 7, G: 8, B: 9, A: 1})
+
+	cases := []struct {
+		description string
+		value       image.Image
+		expected    []RGBAF64
+	}{
+		{description: "rgba", value: rgba, expected: []RGBAF64{{R: 1, G: 0, B: 0.2, A: 1}, {R: 0x40 / 255.0, G: 0x40 / 255.0, B: 0x40 / 255.0, A: 0x80 / 255.0}}},
+		{description: "gray", value: gray, expected: []RGBAF64{{A: 1}, {R: 1, G: 1, B: 1, A: 1}}},
+		{description: "float", value: float, expected: []RGBAF64{{R: 7, G: 8, B: 9, A: 1}}},
+	}
+
+	for _, c := range cases {
+		actual := NewImageFrom(c.value)
+		b := c.value.Bounds()
+		if actual.Bounds() != b {
+			t.Errorf("%s: expected: %v actual: %v", "NewImageFrom "+c.description, b, actual.Bounds())
+			continue
+		}
+		for i, expected := range c.expected {
+			if got := actual.RGBAF64At(b.Min.X+i, b.Min.Y); !rgbaf64Equal(got, expected, 1e-9) {
+				t.Errorf("%s: expected: %#v actual: %#v", "NewImageFrom "+c.description, expected, got)
+			}
+		}
+	}
+}
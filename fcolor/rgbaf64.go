@@ -1,13 +1,40 @@
-/*Package fcolor provides a basic RGBAF64 color type.*/
+/*Package fcolor provides a basic RGBAF64 color type and an image type holding it.*/
 package fcolor
 
-import "github.com/anthonynsimon/bild/math/f64"
+import (
+	"image/color"
+
+	"github.com/anthonynsimon/bild/math/f64"
+)
 
 // RGBAF64 represents an RGBA color using the range 0.0 to 1.0 with a float64 for each channel.
+// Like color.RGBA, the color channels are premultiplied by alpha. High dynamic range colors may
+// have values above 1.0, which are clamped when the color is converted to other models.
 type RGBAF64 struct {
 	R, G, B, A float64
 }
 
+// RGBAF64Model converts any color.Color to an RGBAF64.
+var RGBAF64Model = color.ModelFunc(rgbaf64Model)
+
+func rgbaf64Model(c color.Color) color.Color {
+	if c, ok := c.(RGBAF64); ok {
+		return c
+	}
+	r, g, b, a := c.RGBA()
+	return RGBAF64{float64(r) / 0xFFFF, float64(g) / 0xFFFF, float64(b) / 0xFFFF, float64(a) / 0xFFFF}
+}
+
+// RGBA implements the color.Color interface, clamping the channels to the range 0.0 to 1.0
+// and the color channels to at most the alpha.
+func (c RGBAF64) RGBA() (r, g, b, a uint32) {
+	alpha := f64.Clamp(c.A, 0, 1)
+	scale := func(v float64) uint32 {
+		return uint32(f64.Clamp(v, 0, alpha)*0xFFFF + 0.5)
+	}
+	return scale(c.R), scale(c.G), scale(c.B), scale(alpha)
+}
+
 // NewRGBAF64 returns a new RGBAF64 color based on the provided uint8 values.
 // uint8 value 0 maps to 0, 128 to 0.5 and 255 to 1.0.
 func NewRGBAF64(r, g, b, a uint8) RGBAF64 {
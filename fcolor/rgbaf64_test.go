@@ -1,6 +1,7 @@
 package fcolor
 
 import (
+	"image/color"
 	"math"
 	"testing"
 )
@@ -67,6 +68,28 @@ func TestClamp(t *testing.T) {
 	}
 }
 
+func TestRGBA(t *testing.T) {
+	cases := []struct {
+		value    RGBAF64
+		expected color.RGBA64
+	}{
+		{value: RGBAF64{0, 0, 0, 0}, expected: color.RGBA64{0, 0, 0, 0}},
+		{value: RGBAF64{1.0, 0.5, 0.25, 1.0}, expected: color.RGBA64{0xFFFF, 0x8000, 0x4000, 0xFFFF}},
+		{value: RGBAF64{12.0, -3.0, 0.25, 1.5}, expected: color.RGBA64{0xFFFF, 0, 0x4000, 0xFFFF}},
+		{value: RGBAF64{0.75, 0.25, 0.5, 0.5}, expected: color.RGBA64{0x8000, 0x4000, 0x8000, 0x8000}},
+	}
+
+	for _, c := range cases {
+		r, g, b, a := c.value.RGBA()
+		if actual := (color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}); actual != c.expected {
+			t.Errorf("%s: expected: %#v, actual: %#v", "RGBA", c.expected, actual)
+		}
+		if actual := RGBAF64Model.Convert(c.value); actual != c.value {
+			t.Errorf("%s: expected: %#v, actual: %#v", "RGBAF64Model", c.value, actual)
+		}
+	}
+}
+
 func rgbaf64Equal(a, b RGBAF64, maxDiff float64) bool {
 	if math.Abs(a.R-b.R) > maxDiff {
 		return false
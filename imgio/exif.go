@@ -0,0 +1,181 @@
+package imgio
+
+import (
+	"encoding/binary"
+	"image"
+
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// EXIF tags handled by imgio.
+const (
+	exifOrientationTag = 0x0112
+	exifGPSInfoTag     = 0x8825
+)
+
+// exifTypeSizes are the sizes in bytes of the values of each EXIF type, indexed by type id.
+var exifTypeSizes = [...]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// exifData is an EXIF payload, starting at its TIFF header.
+type exifData struct {
+	payload []byte
+	order   binary.ByteOrder
+}
+
+// parseExif returns the EXIF payload with its byte order. Returns false if its header isn't valid.
+func parseExif(payload []byte) (exifData, bool) {
+	if len(payload) < 8 {
+		return exifData{}, false
+	}
+
+	var order binary.ByteOrder
+	switch string(payload[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return exifData{}, false
+	}
+
+	return exifData{payload: payload, order: order}, true
+}
+
+// ifdCount returns the number of entries of the IFD at the offset. Returns -1 if it's out of range.
+func (e exifData) ifdCount(offset int) int {
+	if offset < 8 || offset+2 > len(e.payload) {
+		return -1
+	}
+	count := int(e.order.Uint16(e.payload[offset:]))
+	if offset+2+count*12+4 > len(e.payload) {
+		return -1
+	}
+	return count
+}
+
+// firstIFD returns the offset of the first IFD.
+func (e exifData) firstIFD() int {
+	return int(e.order.Uint32(e.payload[4:]))
+}
+
+// findEntry returns the offset of the entry with the tag in the IFD at the offset, or -1 if it's missing.
+func (e exifData) findEntry(ifd int, tag uint16) int {
+	count := e.ifdCount(ifd)
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if e.order.Uint16(e.payload[entry:]) == tag {
+			return entry
+		}
+	}
+	return -1
+}
+
+// valueRange returns the position and size of the values of the entry at the offset,
+// which are stored inline in the entry when they fit in 4 bytes.
+// Returns false if they are out of range.
+func (e exifData) valueRange(entry int) (int, int, bool) {
+	typ := int(e.order.Uint16(e.payload[entry+2:]))
+	if typ >= len(exifTypeSizes) {
+		return 0, 0, false
+	}
+	size := exifTypeSizes[typ] * int(e.order.Uint32(e.payload[entry+4:]))
+	if size <= 4 {
+		return entry + 8, size, true
+	}
+
+	offset := int(e.order.Uint32(e.payload[entry+8:]))
+	if size < 0 || offset < 8 || offset+size > len(e.payload) {
+		return 0, 0, false
+	}
+	return offset, size, true
+}
+
+// exifOrientation returns the value of the Orientation tag in the first IFD of the EXIF payload,
+// in the range 1 to 8. Returns 1, meaning no transformation, if the tag is missing or invalid.
+func exifOrientation(payload []byte) int {
+	e, ok := parseExif(payload)
+	if !ok {
+		return 1
+	}
+
+	entry := e.findEntry(e.firstIFD(), exifOrientationTag)
+	if entry < 0 {
+		return 1
+	}
+
+	// The tag is a single SHORT value stored inline in the entry
+	v := int(e.order.Uint16(e.payload[entry+8:]))
+	if v < 1 || v > 8 {
+		return 1
+	}
+	return v
+}
+
+// setExifOrientation sets the Orientation tag in the first IFD of the EXIF payload, if present.
+func setExifOrientation(payload []byte, orientation int) {
+	e, ok := parseExif(payload)
+	if !ok {
+		return
+	}
+
+	if entry := e.findEntry(e.firstIFD(), exifOrientationTag); entry >= 0 {
+		e.order.PutUint16(e.payload[entry+8:], uint16(orientation))
+	}
+}
+
+// stripExifGPS removes the GPS IFD from the EXIF payload. The GPSInfo entry is removed from the
+// first IFD and the GPS IFD and its values are zeroed, so that no location is left in the payload.
+func stripExifGPS(payload []byte) {
+	e, ok := parseExif(payload)
+	if !ok {
+		return
+	}
+
+	ifd := e.firstIFD()
+	entry := e.findEntry(ifd, exifGPSInfoTag)
+	if entry < 0 {
+		return
+	}
+	gps := int(e.order.Uint32(e.payload[entry+8:]))
+
+	if count := e.ifdCount(gps); count >= 0 {
+		for i := 0; i < count; i++ {
+			if offset, size, ok := e.valueRange(gps + 2 + i*12); ok {
+				clear(e.payload[offset : offset+size])
+			}
+		}
+		clear(e.payload[gps : gps+2+count*12+4])
+	}
+
+	// Shift the following entries and the offset of the next IFD over the removed entry
+	count := e.ifdCount(ifd)
+	end := ifd + 2 + count*12 + 4
+	copy(e.payload[entry:], e.payload[entry+12:end])
+	clear(e.payload[end-12 : end])
+	e.order.PutUint16(e.payload[ifd:], uint16(count-1))
+}
+
+// orient returns the image rotated and flipped as described by the EXIF orientation value,
+// so that it's displayed upright.
+func orient(img image.Image, orientation int) image.Image {
+	rotation := &transform.RotationOptions{ResizeBounds: true}
+
+	switch orientation {
+	case 2:
+		return transform.FlipH(img)
+	case 3:
+		return transform.Rotate(img, 180, rotation)
+	case 4:
+		return transform.FlipV(img)
+	case 5:
+		return transform.FlipH(transform.Rotate(img, 90, rotation))
+	case 6:
+		return transform.Rotate(img, 90, rotation)
+	case 7:
+		return transform.FlipV(transform.Rotate(img, 90, rotation))
+	case 8:
+		return transform.Rotate(img, 270, rotation)
+	}
+
+	return img
+}
@@ -0,0 +1,220 @@
+package imgio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+// exifPayload returns an EXIF payload with the byte order and a single Orientation tag in its first IFD.
+func exifPayload(order binary.ByteOrder, orientation uint16) []byte {
+	buf := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+	order.PutUint16(buf[8:], 1)
+	order.PutUint16(buf[10:], exifOrientationTag)
+	order.PutUint16(buf[12:], 3)
+	order.PutUint32(buf[14:], 1)
+	order.PutUint16(buf[18:], orientation)
+	return buf
+}
+
+// withJPEGExif returns the JPEG encoded data with an APP1 segment holding the EXIF payload.
+func withJPEGExif(data, payload []byte) []byte {
+	segment := append([]byte("Exif\x00\x00"), payload...)
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(out[4:], uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// withPNGExif returns the PNG encoded data with an eXIf chunk holding the EXIF payload after its header.
+func withPNGExif(data, payload []byte) []byte {
+	// Signature and IHDR chunk
+	const headerSize = 8 + 25
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], "eXIf")
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := append([]byte{}, data[:headerSize]...)
+	out = append(out, chunk...)
+	return append(out, data[headerSize:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	cases := []struct {
+		description string
+		payload     []byte
+		expected    int
+	}{
+		{description: "little endian", payload: exifPayload(binary.LittleEndian, 6), expected: 6},
+		{description: "big endian", payload: exifPayload(binary.BigEndian, 8), expected: 8},
+		{description: "out of range", payload: exifPayload(binary.BigEndian, 9), expected: 1},
+		{description: "truncated", payload: exifPayload(binary.BigEndian, 3)[:16], expected: 1},
+		{description: "missing", payload: nil, expected: 1},
+	}
+
+	for _, c := range cases {
+		actual := exifOrientation(c.payload)
+		if actual != c.expected {
+			t.Errorf("%s: expected: %d actual: %d", "exifOrientation "+c.description, c.expected, actual)
+		}
+	}
+}
+
+// exifPayloadWithGPS returns a big endian EXIF payload with an Orientation tag and a GPS IFD holding
+// a latitude, which is a RATIONAL triplet stored out of line at the end of the payload.
+func exifPayloadWithGPS() []byte {
+	order := binary.BigEndian
+	buf := make([]byte, 8+2+2*12+4+2+12+4+24)
+	copy(buf, "MM")
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+
+	// First IFD
+	order.PutUint16(buf[8:], 2)
+	order.PutUint16(buf[10:], exifOrientationTag)
+	order.PutUint16(buf[12:], 3)
+	order.PutUint32(buf[14:], 1)
+	order.PutUint16(buf[18:], 6)
+	order.PutUint16(buf[22:], exifGPSInfoTag)
+	order.PutUint16(buf[24:], 4)
+	order.PutUint32(buf[26:], 1)
+	order.PutUint32(buf[30:], 38)
+
+	// GPS IFD with the GPSLatitude tag
+	order.PutUint16(buf[38:], 1)
+	order.PutUint16(buf[40:], 0x0002)
+	order.PutUint16(buf[42:], 5)
+	order.PutUint32(buf[44:], 3)
+	order.PutUint32(buf[48:], 56)
+	for i := 56; i < len(buf); i++ {
+		buf[i] = 0xA5
+	}
+	return buf
+}
+
+func TestStripExifGPS(t *testing.T) {
+	value := exifPayloadWithGPS()
+	stripExifGPS(value)
+
+	e, _ := parseExif(value)
+	if e.findEntry(e.firstIFD(), exifGPSInfoTag) >= 0 {
+		t.Errorf("stripExifGPS: expected the GPSInfo entry to be removed")
+	}
+	if count := e.ifdCount(e.firstIFD()); count != 1 {
+		t.Errorf("stripExifGPS: expected 1 entry left, actual: %d", count)
+	}
+	if exifOrientation(value) != 6 {
+		t.Errorf("stripExifGPS: expected the orientation to be kept, actual: %d", exifOrientation(value))
+	}
+	if bytes.IndexByte(value, 0xA5) >= 0 {
+		t.Errorf("stripExifGPS: expected the GPS values to be zeroed, actual: %v", value)
+	}
+	if len(value) != len(exifPayloadWithGPS()) {
+		t.Errorf("stripExifGPS: expected the payload size to be kept")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 1 2 3
+	// 4 5 6
+	value := &image.RGBA{
+		Rect:   image.Rect(0, 0, 3, 2),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0x01, 0x01, 0x01, 0xFF, 0x02, 0x02, 0x02, 0xFF, 0x03, 0x03, 0x03, 0xFF,
+			0x04, 0x04, 0x04, 0xFF, 0x05, 0x05, 0x05, 0xFF, 0x06, 0x06, 0x06, 0xFF,
+		},
+	}
+
+	// Upright layouts of the value for each orientation, as the values of its pixels in row-major order
+	cases := []struct {
+		orientation int
+		w, h        int
+		expected    []uint8
+	}{
+		{orientation: 1, w: 3, h: 2, expected: []uint8{1, 2, 3, 4, 5, 6}},
+		{orientation: 2, w: 3, h: 2, expected: []uint8{3, 2, 1, 6, 5, 4}},
+		{orientation: 3, w: 3, h: 2, expected: []uint8{6, 5, 4, 3, 2, 1}},
+		{orientation: 4, w: 3, h: 2, expected: []uint8{4, 5, 6, 1, 2, 3}},
+		{orientation: 5, w: 2, h: 3, expected: []uint8{1, 4, 2, 5, 3, 6}},
+		{orientation: 6, w: 2, h: 3, expected: []uint8{4, 1, 5, 2, 6, 3}},
+		{orientation: 7, w: 2, h: 3, expected: []uint8{6, 3, 5, 2, 4, 1}},
+		{orientation: 8, w: 2, h: 3, expected: []uint8{3, 6, 2, 5, 1, 4}},
+	}
+
+	for _, c := range cases {
+		expected := image.NewRGBA(image.Rect(0, 0, c.w, c.h))
+		for i, v := range c.expected {
+			copy(expected.Pix[i*4:], []uint8{v, v, v, 0xFF})
+		}
+
+		actual := toRGBA(orient(value, c.orientation))
+		if !util.RGBAImageEqual(actual, expected) {
+			t.Errorf("orient %d:\nexpected:%v\nactual:%v", c.orientation, util.RGBAToString(expected), util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Pix[0], img.Pix[3] = 0xFF, 0xFF
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	filename := filepath.Join(t.TempDir(), "rotated.png")
+	err := os.WriteFile(filename, withPNGExif(buf.Bytes(), exifPayload(binary.BigEndian, 6)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		description string
+		options     *OpenOptions
+		expected    image.Rectangle
+		red         image.Point
+	}{
+		{description: "default", options: nil, expected: image.Rect(0, 0, 3, 2), red: image.Pt(0, 0)},
+		{description: "auto orient", options: &OpenOptions{AutoOrient: true}, expected: image.Rect(0, 0, 2, 3), red: image.Pt(1, 0)},
+	}
+
+	for _, c := range cases {
+		actual, err := OpenWithOptions(filename, c.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual.Bounds() != c.expected {
+			t.Errorf("%s: expected bounds: %v actual: %v", "OpenWithOptions "+c.description, c.expected, actual.Bounds())
+		}
+		if r, _, _, _ := actual.At(c.red.X, c.red.Y).RGBA(); r != 0xFFFF {
+			t.Errorf("%s: expected the red pixel at %v", "OpenWithOptions "+c.description, c.red)
+		}
+	}
+}
+
+// toRGBA returns the image as an *image.RGBA with its origin at 0, 0.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			out.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+func TestFarbfeldEncoder(t *testing.T) {
+	deep := image.NewNRGBA64(image.Rect(0, 0, 7, 3))
+	for i := range deep.Pix {
+		deep.Pix[i] = uint8(i * 31)
+	}
+	translucent := gradient(image.Rect(-2, 1, 5, 4))
+	for i := 3; i < len(translucent.Pix); i += 4 {
+		translucent.Pix[i] = uint8(i * 3)
+	}
+
+	cases := []struct {
+		description string
+		value       image.Image
+	}{
+		{description: "16-bit", value: deep},
+		{description: "gray", value: &image.Gray{Rect: image.Rect(0, 0, 2, 1), Stride: 2, Pix: []uint8{0x12, 0xEF}}},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := FarbfeldEncoder()(&buf, c.value); err != nil {
+			t.Fatalf("%s: %v", "FarbfeldEncoder "+c.description, err)
+		}
+
+		actual, format, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", "FarbfeldEncoder "+c.description, err)
+		}
+		if format != "farbfeld" || actual.ColorModel() != color.NRGBA64Model || !imageEqual(actual, c.value) {
+			t.Errorf("%s: expected: %#v actual: %v %#v", "FarbfeldEncoder "+c.description, c.value, format, actual)
+		}
+	}
+
+	// Translucent 8-bit channels are kept exactly rather than rounded through premultiplied values
+	var buf bytes.Buffer
+	if err := FarbfeldEncoder()(&buf, translucent); err != nil {
+		t.Fatalf("%s: %v", "FarbfeldEncoder", err)
+	}
+	actual, err := decodeFarbfeld(&buf)
+	if err != nil {
+		t.Fatalf("%s: %v", "FarbfeldEncoder", err)
+	}
+	for i, v := range actual.(*image.NRGBA64).Pix {
+		if expected := translucent.Pix[i/2]; v != expected {
+			t.Fatalf("%s: expected: %#v actual: %#v at %d", "FarbfeldEncoder translucent", expected, v, i)
+		}
+	}
+}
+
+func TestDecodeFarbfeld(t *testing.T) {
+	cases := []struct {
+		description string
+		value       string
+		expected    image.Image
+		err         error
+	}{
+		{
+			description: "pixel",
+			value:       "farbfeld\x00\x00\x00\x01\x00\x00\x00\x01\x12\x34\x56\x78\x9A\xBC\xDE\xF0",
+			expected:    &image.NRGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}},
+		},
+		{description: "truncated header", value: "farbfeld\x00\x00", err: io.ErrUnexpectedEOF},
+		{description: "truncated pixels", value: "farbfeld\x00\x00\x00\x02\x00\x00\x00\x01\x00\x00", err: io.ErrUnexpectedEOF},
+		{description: "too large", value: "farbfeld\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF", err: errInvalidFarbfeld},
+	}
+
+	for _, c := range cases {
+		actual, err := decodeFarbfeld(bytes.NewReader([]byte(c.value)))
+		if err != c.err {
+			t.Errorf("%s: expected: %v actual: %v", "decodeFarbfeld "+c.description, c.err, err)
+			continue
+		}
+		if err == nil && !imageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %#v actual: %#v", "decodeFarbfeld "+c.description, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package imgio
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the name of an image encoding, matching the name its decoder is registered with in the image package.
+type Format string
+
+// Supported formats
+const (
+	PNG      Format = "png"
+	JPEG     Format = "jpeg"
+	BMP      Format = "bmp"
+	WEBP     Format = "webp"
+	GIF      Format = "gif"
+	TIFF     Format = "tiff"
+	PBM      Format = "pbm"
+	PGM      Format = "pgm"
+	PPM      Format = "ppm"
+	PAM      Format = "pam"
+	Farbfeld Format = "farbfeld"
+	HDR      Format = "hdr"
+)
+
+// ErrUnknownFormat is returned when a format name or file extension isn't supported.
+var ErrUnknownFormat = errors.New("imgio: unknown format")
+
+// formatNames are the names and file extensions of each format, without the dot.
+var formatNames = map[string]Format{
+	"png":      PNG,
+	"jpg":      JPEG,
+	"jpeg":     JPEG,
+	"bmp":      BMP,
+	"webp":     WEBP,
+	"gif":      GIF,
+	"tif":      TIFF,
+	"tiff":     TIFF,
+	"pbm":      PBM,
+	"pgm":      PGM,
+	"ppm":      PPM,
+	"pam":      PAM,
+	"ff":       Farbfeld,
+	"farbfeld": Farbfeld,
+	"hdr":      HDR,
+}
+
+// ParseFormat returns the format for the provided name or file extension, case insensitive
+// and with or without the leading dot.
+//
+// Usage example:
+//
+//	format, err := imgio.ParseFormat("jpg")
+func ParseFormat(name string) (Format, error) {
+	format, ok := formatNames[strings.ToLower(strings.TrimPrefix(name, "."))]
+	if !ok {
+		return "", ErrUnknownFormat
+	}
+	return format, nil
+}
+
+// FormatFromFilename returns the format matching the extension of the filename.
+//
+// Usage example:
+//
+//	format, err := imgio.FormatFromFilename("output.webp")
+func FormatFromFilename(filename string) (Format, error) {
+	return ParseFormat(filepath.Ext(filename))
+}
+
+// encoder returns the encoder for the format with its default options.
+func (f Format) encoder() (Encoder, error) {
+	switch f {
+	case PNG:
+		return PNGEncoder(), nil
+	case JPEG:
+		return JPEGEncoder(jpegDefaultQuality), nil
+	case BMP:
+		return BMPEncoder(), nil
+	case WEBP:
+		return WEBPEncoder(nil), nil
+	case GIF:
+		return GIFEncoder(nil), nil
+	case TIFF:
+		return TIFFEncoder(nil), nil
+	case PBM:
+		return PBMEncoder(nil), nil
+	case PGM:
+		return PGMEncoder(nil), nil
+	case PPM:
+		return PPMEncoder(nil), nil
+	case PAM:
+		return PAMEncoder(), nil
+	case Farbfeld:
+		return FarbfeldEncoder(), nil
+	case HDR:
+		return HDREncoder(), nil
+	}
+	return nil, ErrUnknownFormat
+}
@@ -0,0 +1,58 @@
+package imgio
+
+import "testing"
+
+func TestParseFormat(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected Format
+		err      error
+	}{
+		{value: "png", expected: PNG},
+		{value: "JPG", expected: JPEG},
+		{value: ".jpeg", expected: JPEG},
+		{value: "bmp", expected: BMP},
+		{value: "webp", expected: WEBP},
+		{value: "GIF", expected: GIF},
+		{value: "tif", expected: TIFF},
+		{value: ".tiff", expected: TIFF},
+		{value: "pbm", expected: PBM},
+		{value: "PGM", expected: PGM},
+		{value: "ppm", expected: PPM},
+		{value: "pam", expected: PAM},
+		{value: ".ff", expected: Farbfeld},
+		{value: "farbfeld", expected: Farbfeld},
+		{value: "HDR", expected: HDR},
+		{value: "xcf", expected: "", err: ErrUnknownFormat},
+		{value: "", expected: "", err: ErrUnknownFormat},
+	}
+
+	for _, c := range cases {
+		actual, err := ParseFormat(c.value)
+		if actual != c.expected || err != c.err {
+			t.Errorf("%s: expected: %q, %v actual: %q, %v", "ParseFormat "+c.value, c.expected, c.err, actual, err)
+		}
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected Format
+		err      error
+	}{
+		{value: "output.png", expected: PNG},
+		{value: "dir.v2/photo.JPG", expected: JPEG},
+		{value: "scan.tif", expected: TIFF},
+		{value: "frame.ppm", expected: PPM},
+		{value: "output", expected: "", err: ErrUnknownFormat},
+		{value: "-", expected: "", err: ErrUnknownFormat},
+	}
+
+	for _, c := range cases {
+		actual, err := FormatFromFilename(c.value)
+		if actual != c.expected || err != c.err {
+			t.Errorf("%s: expected: %q, %v actual: %q, %v", "FormatFromFilename "+c.value, c.expected, c.err, actual, err)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package imgio
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/anthonynsimon/bild/clone"
+)
+
+// GIFOptions are the GIF encoding parameters.
+// NumColors is the maximum number of colors of the palette computed from each image, ranging from 1 to 256.
+// Default of 256 is used if zero. One of them is reserved for transparency when the image has any.
+// Palette is a fixed palette used instead of computing one, its entries with an alpha of zero being transparent.
+// Dither set to true diffuses the quantization error with Floyd-Steinberg dithering.
+type GIFOptions struct {
+	NumColors int
+	Palette   color.Palette
+	Dither    bool
+}
+
+// ErrEmptyAnimation is returned when encoding an animation without frames.
+var ErrEmptyAnimation = errors.New("imgio: animation has no frames")
+
+// GIFEncoder returns an encoder to GIF with the provided options.
+// Default parameters are used if a nil *GIFOptions is passed.
+// Pixels with an alpha below half are transparent, the rest are opaque.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.gif", img, imgio.GIFEncoder(&imgio.GIFOptions{NumColors: 64, Dither: true}))
+func GIFEncoder(o *GIFOptions) Encoder {
+	return func(w io.Writer, img image.Image) error {
+		return gif.Encode(w, gifPaletted(img, o), nil)
+	}
+}
+
+// Animation is a sequence of frames, each one covering the whole canvas.
+// Delays are the times to show each frame in 100ths of a second.
+// Disposal are the disposal methods of each frame as defined in image/gif, gif.DisposalBackground
+// clearing the frame before drawing the next one, while gif.DisposalNone leaves it visible through
+// the transparent pixels of the next one.
+// LoopCount is the number of times the animation is shown, 0 looping forever and -1 showing it once.
+type Animation struct {
+	Frames    []image.Image
+	Delays    []int
+	Disposal  []byte
+	LoopCount int
+}
+
+// OpenAnimation loads and decodes all the frames of a GIF file and returns them.
+//
+// Usage example:
+//
+//	anim, err := imgio.OpenAnimation("input.gif")
+func OpenAnimation(filename string) (*Animation, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeAnimation(f)
+}
+
+// DecodeAnimation decodes all the frames of a GIF from the reader and returns them.
+// The frames are composited as they would be displayed, so each one is a complete *image.RGBA
+// of the size of the canvas, and their disposal is gif.DisposalBackground.
+//
+// Usage example:
+//
+//	anim, err := imgio.DecodeAnimation(resp.Body)
+func DecodeAnimation(r io.Reader) (*Animation, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	canvasBounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	for _, frame := range g.Image {
+		canvasBounds = canvasBounds.Union(frame.Bounds())
+	}
+
+	anim := &Animation{LoopCount: g.LoopCount}
+	canvas := image.NewRGBA(canvasBounds)
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		if g.Disposal[i] == gif.DisposalPrevious {
+			previous = clone.AsRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		anim.Frames = append(anim.Frames, clone.AsRGBA(canvas))
+		anim.Delays = append(anim.Delays, g.Delay[i])
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+
+		switch g.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return anim, nil
+}
+
+// SaveAnimation creates a file and writes to it the animation as a GIF with the provided options.
+// Default parameters are used if a nil *GIFOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.SaveAnimation("output.gif", anim, nil)
+func SaveAnimation(filename string, a *Animation, o *GIFOptions) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return EncodeAnimation(f, a, o)
+}
+
+// EncodeAnimation writes the animation to w as a GIF with the provided options, computing a palette
+// for each frame unless a fixed one is set. Missing delays and disposal methods default to zero.
+// Default parameters are used if a nil *GIFOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.EncodeAnimation(os.Stdout, anim, &imgio.GIFOptions{Dither: true})
+func EncodeAnimation(w io.Writer, a *Animation, o *GIFOptions) error {
+	if len(a.Frames) == 0 {
+		return ErrEmptyAnimation
+	}
+
+	g := &gif.GIF{LoopCount: a.LoopCount}
+	var canvasBounds image.Rectangle
+	for i, frame := range a.Frames {
+		g.Image = append(g.Image, gifPaletted(frame, o))
+		var delay int
+		if i < len(a.Delays) {
+			delay = a.Delays[i]
+		}
+		var disposal byte
+		if i < len(a.Disposal) {
+			disposal = a.Disposal[i]
+		}
+		g.Delay = append(g.Delay, delay)
+		g.Disposal = append(g.Disposal, disposal)
+		canvasBounds = canvasBounds.Union(frame.Bounds())
+	}
+	g.Config = image.Config{Width: canvasBounds.Max.X, Height: canvasBounds.Max.Y}
+
+	return gif.EncodeAll(w, g)
+}
+
+// Map returns a new animation with fn applied to every frame, keeping the timing of the frames.
+//
+// Usage example:
+//
+//	blurred := anim.Map(func(img image.Image) image.Image {
+//		return blur.Gaussian(img, 3.0)
+//	})
+func (a *Animation) Map(fn func(image.Image) image.Image) *Animation {
+	result := &Animation{
+		Frames:    make([]image.Image, len(a.Frames)),
+		Delays:    append([]int(nil), a.Delays...),
+		Disposal:  append([]byte(nil), a.Disposal...),
+		LoopCount: a.LoopCount,
+	}
+	for i, frame := range a.Frames {
+		result.Frames[i] = fn(frame)
+	}
+	return result
+}
+
+// gifPaletted returns the image converted to a palette of at most 256 colors.
+func gifPaletted(img image.Image, o *GIFOptions) *image.Paletted {
+	numColors, palette, drawer := 256, color.Palette(nil), draw.Drawer(draw.Src)
+	if o != nil {
+		if o.NumColors > 0 {
+			numColors = min(o.NumColors, 256)
+		}
+		palette = o.Palette
+		if o.Dither {
+			drawer = draw.FloydSteinberg
+		}
+	}
+
+	// GIF transparency is all or nothing
+	b := img.Bounds()
+	src := image.NewNRGBA(b)
+	draw.Draw(src, b, img, b.Min, draw.Src)
+	transparent := false
+	for i := 3; i < len(src.Pix); i += 4 {
+		if src.Pix[i] < 0x80 {
+			src.Pix[i-3], src.Pix[i-2], src.Pix[i-1], src.Pix[i] = 0, 0, 0, 0
+			transparent = true
+		} else {
+			src.Pix[i] = 0xFF
+		}
+	}
+
+	if palette == nil {
+		if transparent {
+			palette = append(medianCut(src, numColors-1), color.NRGBA{})
+		} else {
+			palette = medianCut(src, numColors)
+		}
+	}
+
+	dst := image.NewPaletted(b, palette)
+	drawer.Draw(dst, b, src, b.Min)
+	return dst
+}
+
+// medianCut returns a palette of at most n colors representing the opaque pixels of the image,
+// by repeatedly splitting the box of colors with the widest range at its median.
+func medianCut(img *image.NRGBA, n int) color.Palette {
+	// Large images are sampled, which is enough to find their dominant colors
+	const maxSamples = 1 << 16
+	step := max(1, len(img.Pix)/4/maxSamples) * 4
+
+	var pixels [][3]uint8
+	for i := 0; i < len(img.Pix); i += step {
+		if img.Pix[i+3] != 0 {
+			pixels = append(pixels, [3]uint8{img.Pix[i], img.Pix[i+1], img.Pix[i+2]})
+		}
+	}
+	if len(pixels) == 0 || n < 1 {
+		return color.Palette{}
+	}
+
+	// widest returns the channel with the widest range of the box, and that range
+	widest := func(box [][3]uint8) (int, int) {
+		lo, hi := box[0], box[0]
+		for _, p := range box {
+			for c := range p {
+				lo[c], hi[c] = min(lo[c], p[c]), max(hi[c], p[c])
+			}
+		}
+		channel := 0
+		for c := 1; c < 3; c++ {
+			if hi[c]-lo[c] > hi[channel]-lo[channel] {
+				channel = c
+			}
+		}
+		return channel, int(hi[channel] - lo[channel])
+	}
+
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < n {
+		split, splitChannel, splitRange := -1, 0, 0
+		for i, box := range boxes {
+			if channel, r := widest(box); r > splitRange {
+				split, splitChannel, splitRange = i, channel, r
+			}
+		}
+		// Every box holds a single color
+		if split < 0 {
+			break
+		}
+
+		box := boxes[split]
+		sort.Slice(box, func(i, j int) bool { return box[i][splitChannel] < box[j][splitChannel] })
+		mid := len(box) / 2
+		boxes[split] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var sum [3]int
+		for _, p := range box {
+			for c := range p {
+				sum[c] += int(p[c])
+			}
+		}
+		half := len(box) / 2
+		palette[i] = color.NRGBA{
+			R: uint8((sum[0] + half) / len(box)),
+			G: uint8((sum[1] + half) / len(box)),
+			B: uint8((sum[2] + half) / len(box)),
+			A: 0xFF,
+		}
+	}
+	return palette
+}
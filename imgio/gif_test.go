@@ -0,0 +1,228 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestGIFEncoder(t *testing.T) {
+	red, blue := color.RGBA{0xFF, 0x00, 0x00, 0xFF}, color.RGBA{0x00, 0x00, 0xFF, 0xFF}
+	twoColors := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	translucent := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		twoColors.SetRGBA(x, 0, red)
+		twoColors.SetRGBA(x, 1, blue)
+		translucent.SetRGBA(x, 0, red)
+		translucent.SetRGBA(x, 1, color.RGBA{0x00, 0x00, 0x20, 0x20})
+	}
+	transparentResult := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		transparentResult.SetRGBA(x, 0, red)
+	}
+
+	many := gradient(image.Rect(0, 0, 32, 32))
+
+	cases := []struct {
+		description string
+		options     *GIFOptions
+		value       image.Image
+		expected    *image.RGBA
+		maxColors   int
+	}{
+		{description: "two colors", options: nil, value: twoColors, expected: twoColors, maxColors: 2},
+		{description: "transparency", options: nil, value: translucent, expected: transparentResult, maxColors: 2},
+		{description: "num colors", options: &GIFOptions{NumColors: 16}, value: many, maxColors: 16},
+		{description: "num colors dithered", options: &GIFOptions{NumColors: 16, Dither: true}, value: many, maxColors: 16},
+		{description: "fixed palette", options: &GIFOptions{Palette: palette.Plan9}, value: many, maxColors: 256},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := GIFEncoder(c.options)(&buf, c.value); err != nil {
+			t.Fatalf("%s: %v", "GIFEncoder "+c.description, err)
+		}
+
+		decoded, err := gif.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", "GIFEncoder "+c.description, err)
+		}
+		paletted := decoded.(*image.Paletted)
+		if len(paletted.Palette) > c.maxColors {
+			t.Errorf("%s: expected at most %d colors, actual: %d", "GIFEncoder "+c.description, c.maxColors, len(paletted.Palette))
+		}
+
+		if c.expected != nil {
+			actual := image.NewRGBA(paletted.Bounds())
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 4; x++ {
+					actual.Set(x, y, paletted.At(x, y))
+				}
+			}
+			if !util.RGBAImageEqual(actual, c.expected) {
+				t.Errorf("%s: expected: %#v actual: %#v", "GIFEncoder "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
+			}
+		} else if diff := meanAbsDiff(c.value, decoded); diff > 16 {
+			t.Errorf("%s: mean difference too large: %.2f", "GIFEncoder "+c.description, diff)
+		}
+	}
+}
+
+func TestMedianCut(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	copy(img.Pix, []uint8{
+		0x00, 0x00, 0x00, 0xFF, 0x10, 0x00, 0x00, 0xFF, 0xF0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00,
+	})
+
+	cases := []struct {
+		n        int
+		expected color.Palette
+	}{
+		{n: 1, expected: color.Palette{color.NRGBA{0x55, 0x55, 0x55, 0xFF}}},
+		{n: 2, expected: color.Palette{color.NRGBA{0x00, 0x00, 0x00, 0xFF}, color.NRGBA{0x80, 0x80, 0x80, 0xFF}}},
+		{n: 3, expected: color.Palette{color.NRGBA{0x00, 0x00, 0x00, 0xFF}, color.NRGBA{0x10, 0x00, 0x00, 0xFF}, color.NRGBA{0xF0, 0xFF, 0xFF, 0xFF}}},
+		{n: 8, expected: color.Palette{color.NRGBA{0x00, 0x00, 0x00, 0xFF}, color.NRGBA{0x10, 0x00, 0x00, 0xFF}, color.NRGBA{0xF0, 0xFF, 0xFF, 0xFF}}},
+	}
+
+	for _, c := range cases {
+		actual := medianCut(img, c.n)
+		if len(actual) != len(c.expected) {
+			t.Errorf("%s: expected: %v actual: %v", "medianCut", c.expected, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != c.expected[i] {
+				t.Errorf("%s: expected: %v actual: %v", "medianCut", c.expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestDecodeAnimation(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.RGBA{0xFF, 0x00, 0x00, 0xFF}, color.RGBA{0x00, 0x00, 0xFF, 0xFF}}
+	frame := func(r image.Rectangle, index uint8) *image.Paletted {
+		img := image.NewPaletted(r, pal)
+		for i := range img.Pix {
+			img.Pix[i] = index
+		}
+		return img
+	}
+
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			frame(image.Rect(0, 0, 2, 2), 1),
+			frame(image.Rect(1, 0, 2, 1), 2),
+			frame(image.Rect(0, 1, 1, 2), 2),
+			frame(image.Rect(0, 0, 1, 1), 0),
+		},
+		Delay:     []int{10, 20, 30, 40},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalBackground, gif.DisposalNone},
+		LoopCount: 2,
+		Config:    image.Config{Width: 2, Height: 2},
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := DecodeAnimation(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, b, o := []uint8{0xFF, 0x00, 0x00, 0xFF}, []uint8{0x00, 0x00, 0xFF, 0xFF}, []uint8{0x00, 0x00, 0x00, 0x00}
+	pixels := func(p ...[]uint8) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		for i := range p {
+			copy(img.Pix[i*4:], p[i])
+		}
+		return img
+	}
+	expected := []*image.RGBA{
+		pixels(r, r, r, r),
+		// Drawn over the first frame
+		pixels(r, b, r, r),
+		// The second frame was disposed to the previous canvas
+		pixels(r, r, b, r),
+		// The third frame was disposed to the background, the fourth one is fully transparent
+		pixels(r, r, o, r),
+	}
+
+	if len(actual.Frames) != len(expected) || actual.LoopCount != 2 {
+		t.Fatalf("DecodeAnimation: expected %d frames, actual: %d", len(expected), len(actual.Frames))
+	}
+	for i := range expected {
+		if !util.RGBAImageEqual(actual.Frames[i].(*image.RGBA), expected[i]) {
+			t.Errorf("%s %d: expected: %#v actual: %#v", "DecodeAnimation frame", i, util.RGBAToString(expected[i]), util.RGBAToString(actual.Frames[i].(*image.RGBA)))
+		}
+		if actual.Delays[i] != g.Delay[i] || actual.Disposal[i] != gif.DisposalBackground {
+			t.Errorf("%s %d: unexpected delay %d or disposal %d", "DecodeAnimation frame", i, actual.Delays[i], actual.Disposal[i])
+		}
+	}
+}
+
+func TestEncodeAnimation(t *testing.T) {
+	red, blue := image.NewRGBA(image.Rect(0, 0, 3, 2)), image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []uint8{0xFF, 0x00, 0x00, 0xFF})
+		copy(blue.Pix[i:], []uint8{0x00, 0x00, 0xFF, 0xFF})
+	}
+	anim := &Animation{Frames: []image.Image{red, blue, red}, Delays: []int{5, 10}, LoopCount: -1}
+
+	filename := filepath.Join(t.TempDir(), "anim.gif")
+	if err := SaveAnimation(filename, anim, nil); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := OpenAnimation(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual.Frames) != 3 || actual.LoopCount != -1 {
+		t.Fatalf("EncodeAnimation: expected 3 frames played once, actual: %d frames, loop count %d", len(actual.Frames), actual.LoopCount)
+	}
+	for i, expected := range []*image.RGBA{red, blue, red} {
+		if !util.RGBAImageEqual(actual.Frames[i].(*image.RGBA), expected) {
+			t.Errorf("%s %d: expected: %#v actual: %#v", "EncodeAnimation frame", i, util.RGBAToString(expected), util.RGBAToString(actual.Frames[i].(*image.RGBA)))
+		}
+	}
+	if expected := []int{5, 10, 0}; actual.Delays[0] != expected[0] || actual.Delays[1] != expected[1] || actual.Delays[2] != expected[2] {
+		t.Errorf("EncodeAnimation: expected delays: %v actual: %v", expected, actual.Delays)
+	}
+
+	if err := EncodeAnimation(&bytes.Buffer{}, &Animation{}, nil); err != ErrEmptyAnimation {
+		t.Errorf("EncodeAnimation: expected: %v actual: %v", ErrEmptyAnimation, err)
+	}
+}
+
+func TestAnimationMap(t *testing.T) {
+	anim := &Animation{
+		Frames:    []image.Image{image.NewRGBA(image.Rect(0, 0, 2, 2)), image.NewRGBA(image.Rect(0, 0, 2, 2))},
+		Delays:    []int{1, 2},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalBackground},
+		LoopCount: 3,
+	}
+
+	calls := 0
+	actual := anim.Map(func(img image.Image) image.Image {
+		calls++
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	})
+
+	if calls != 2 || len(actual.Frames) != 2 || actual.Frames[1].Bounds() != image.Rect(0, 0, 1, 1) {
+		t.Errorf("Animation.Map: expected every frame to be mapped, actual calls: %d", calls)
+	}
+	if actual.Delays[1] != 2 || actual.Disposal[1] != gif.DisposalBackground || actual.LoopCount != 3 {
+		t.Errorf("Animation.Map: expected the timing to be kept, actual: %v %v %d", actual.Delays, actual.Disposal, actual.LoopCount)
+	}
+	if anim.Frames[0].Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Errorf("Animation.Map: expected the source animation to be unchanged")
+	}
+}
@@ -0,0 +1,335 @@
+package imgio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/anthonynsimon/bild/fcolor"
+)
+
+var (
+	errInvalidHDR = errors.New("imgio: invalid radiance hdr image")
+	errHDRFormat  = errors.New("imgio: unsupported radiance hdr pixel format")
+)
+
+func init() {
+	image.RegisterFormat("hdr", "#?RADIANCE", decodeHDR, decodeHDRConfig)
+	image.RegisterFormat("hdr", "#?RGBE", decodeHDR, decodeHDRConfig)
+}
+
+// HDREncoder returns an encoder to Radiance HDR, which stores each pixel as RGBE, three 8-bit
+// mantissas sharing an 8-bit exponent. The values of an *fcolor.Image are written as they are,
+// including those above 1.0. The alpha channel isn't stored, so translucent pixels are written as
+// composited over black.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.hdr", fimg, imgio.HDREncoder())
+func HDREncoder() Encoder {
+	return func(w io.Writer, img image.Image) error {
+		src, ok := img.(*fcolor.Image)
+		if !ok {
+			src = fcolor.NewImageFrom(img)
+		}
+
+		b := src.Bounds()
+		bw := bufio.NewWriter(w)
+		fmt.Fprintf(bw, "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", b.Dy(), b.Dx())
+
+		line := make([]byte, b.Dx()*4)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := src.RGBAF64At(x, y)
+				floatToRGBE(line[(x-b.Min.X)*4:], c.R, c.G, c.B)
+			}
+			writeHDRScanline(bw, line)
+		}
+		return bw.Flush()
+	}
+}
+
+// hdrHeader describes the raster of a Radiance HDR image, flipX and flipY being set when its
+// scanlines are stored right to left and bottom to top. Exposure is the product of the EXPOSURE
+// variables, by which the stored values were multiplied.
+type hdrHeader struct {
+	width, height int
+	flipX, flipY  bool
+	exposure      float64
+}
+
+func decodeHDRConfig(r io.Reader) (image.Config, error) {
+	h, err := readHDRHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: fcolor.RGBAF64Model, Width: h.width, Height: h.height}, nil
+}
+
+// decodeHDR decodes a Radiance HDR image into an opaque *fcolor.Image. The stored values are divided
+// by the EXPOSURE of the header, so that the pixels hold the radiance of the scene whatever the
+// exposure adjustments made to the file.
+func decodeHDR(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHDRHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	// The scanlines are read before allocating the image, so that a header claiming a huge size
+	// doesn't allocate more than the data holds
+	rgbe := make([]byte, 0, min(h.width*h.height*4, 1<<20))
+	line := make([]byte, h.width*4)
+	for y := 0; y < h.height; y++ {
+		if err := readHDRScanline(br, line); err != nil {
+			return nil, err
+		}
+		rgbe = append(rgbe, line...)
+	}
+
+	img := fcolor.NewImage(image.Rect(0, 0, h.width, h.height))
+	for y := 0; y < h.height; y++ {
+		dy := y
+		if h.flipY {
+			dy = h.height - 1 - y
+		}
+		for x := 0; x < h.width; x++ {
+			dx := x
+			if h.flipX {
+				dx = h.width - 1 - x
+			}
+			r, g, b := rgbeToFloat(rgbe[(y*h.width+x)*4:])
+			img.SetRGBAF64(dx, dy, fcolor.RGBAF64{R: r / h.exposure, G: g / h.exposure, B: b / h.exposure, A: 1})
+		}
+	}
+	return img, nil
+}
+
+// readHDRHeader reads the header of the image up to its resolution line, leaving r at the start of
+// its scanlines. Only the standard orientations storing the image row by row are supported.
+func readHDRHeader(r *bufio.Reader) (*hdrHeader, error) {
+	magic, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(magic, "#?") {
+		return nil, errInvalidHDR
+	}
+
+	// The variables end with an empty line
+	exposure := 1.0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if format, ok := strings.CutPrefix(line, "FORMAT="); ok && format != "32-bit_rle_rgbe" {
+			return nil, errHDRFormat
+		}
+		// Each EXPOSURE variable records a further adjustment, so they add up
+		if value, ok := strings.CutPrefix(line, "EXPOSURE="); ok {
+			e, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || !(e > 0) || math.IsInf(e, 0) {
+				return nil, errInvalidHDR
+			}
+			exposure *= e
+		}
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 4 || (fields[0] != "-Y" && fields[0] != "+Y") || (fields[2] != "+X" && fields[2] != "-X") {
+		return nil, errInvalidHDR
+	}
+	height, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, errInvalidHDR
+	}
+	width, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, errInvalidHDR
+	}
+	if width < 1 || height < 1 || uint64(width)*uint64(height)*4 > maxDecodedSamples {
+		return nil, errInvalidHDR
+	}
+
+	return &hdrHeader{width: width, height: height, flipX: fields[2] == "-X", flipY: fields[0] == "+Y", exposure: exposure}, nil
+}
+
+// readHDRScanline reads a scanline of RGBE pixels into line, which holds 4 bytes per pixel.
+// Scanlines are either run length encoded component by component, or stored flat.
+func readHDRScanline(r *bufio.Reader, line []byte) error {
+	width := len(line) / 4
+	if width < 8 || width > 0x7FFF {
+		return readHDRFlatScanline(r, line)
+	}
+
+	header, err := r.Peek(4)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if header[0] != 2 || header[1] != 2 || header[2]&0x80 != 0 {
+		return readHDRFlatScanline(r, line)
+	}
+	if int(header[2])<<8|int(header[3]) != width {
+		return errInvalidHDR
+	}
+	r.Discard(4)
+
+	// Each run starts with a count above 128 followed by the repeated value, while a count
+	// up to 128 is followed by as many literal values
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			run := count > 128
+			n := int(count)
+			if run {
+				n -= 128
+			}
+			if n == 0 || x+n > width {
+				return errInvalidHDR
+			}
+
+			if run {
+				value, err := r.ReadByte()
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				for ; n > 0; n-- {
+					line[x*4+c] = value
+					x++
+				}
+				continue
+			}
+			for ; n > 0; n-- {
+				value, err := r.ReadByte()
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				line[x*4+c] = value
+				x++
+			}
+		}
+	}
+	return nil
+}
+
+// readHDRFlatScanline reads a scanline of RGBE pixels stored one after the other, where a pixel of
+// 1, 1, 1, n repeats the previous pixel n times, with n shifted by 8 bits more for each such pixel
+// in a row.
+func readHDRFlatScanline(r *bufio.Reader, line []byte) error {
+	width, shift := len(line)/4, 0
+	for x := 0; x < width; {
+		p := line[x*4 : x*4+4]
+		if _, err := io.ReadFull(r, p); err != nil {
+			return unexpectedEOF(err)
+		}
+		if p[0] != 1 || p[1] != 1 || p[2] != 1 {
+			x, shift = x+1, 0
+			continue
+		}
+
+		n := int(p[3]) << shift
+		if x == 0 || n < 0 || x+n > width {
+			return errInvalidHDR
+		}
+		for ; n > 0; n-- {
+			copy(line[x*4:x*4+4], line[(x-1)*4:x*4])
+			x++
+		}
+		shift += 8
+	}
+	return nil
+}
+
+// writeHDRScanline writes the scanline of RGBE pixels, run length encoded component by component
+// when its width allows it, or flat otherwise.
+func writeHDRScanline(w *bufio.Writer, line []byte) {
+	width := len(line) / 4
+	if width < 8 || width > 0x7FFF {
+		w.Write(line)
+		return
+	}
+
+	w.Write([]byte{2, 2, byte(width >> 8), byte(width)})
+	component := make([]byte, width)
+	for c := 0; c < 4; c++ {
+		for x := range component {
+			component[x] = line[x*4+c]
+		}
+		writeHDRRuns(w, component)
+	}
+}
+
+// writeHDRRuns writes the values as runs of at least 4 repeated values, of up to 127 of them, with
+// the values in between written as literals, up to 128 at a time.
+func writeHDRRuns(w *bufio.Writer, values []byte) {
+	const minRun, maxRun, maxLiteral = 4, 127, 128
+
+	for cur := 0; cur < len(values); {
+		// Find the start of the next run long enough to be worth encoding
+		runStart, runLength := cur, 0
+		for runStart < len(values) {
+			runLength = 1
+			for runStart+runLength < len(values) && runLength < maxRun && values[runStart+runLength] == values[runStart] {
+				runLength++
+			}
+			if runLength >= minRun {
+				break
+			}
+			runStart += runLength
+		}
+
+		for cur < runStart {
+			n := min(maxLiteral, runStart-cur)
+			w.WriteByte(byte(n))
+			w.Write(values[cur : cur+n])
+			cur += n
+		}
+		if runStart < len(values) {
+			w.WriteByte(byte(128 + runLength))
+			w.WriteByte(values[runStart])
+			cur += runLength
+		}
+	}
+}
+
+// rgbeToFloat returns the color channels of the RGBE pixel, whose mantissas are taken at the middle
+// of the range they represent.
+func rgbeToFloat(p []byte) (r, g, b float64) {
+	if p[3] == 0 {
+		return 0, 0, 0
+	}
+	f := math.Ldexp(1, int(p[3])-(128+8))
+	return (float64(p[0]) + 0.5) * f, (float64(p[1]) + 0.5) * f, (float64(p[2]) + 0.5) * f
+}
+
+// floatToRGBE stores the color channels as an RGBE pixel into p. Negative values are stored as
+// zero, and values too large for the format as its maximum.
+func floatToRGBE(p []byte, r, g, b float64) {
+	v := max(r, g, b)
+	if !(v >= 1e-32) {
+		p[0], p[1], p[2], p[3] = 0, 0, 0, 0
+		return
+	}
+
+	_, e := math.Frexp(v)
+	e = min(e, 127)
+	scale := math.Ldexp(1, 8-e)
+	mantissa := func(c float64) byte {
+		return byte(min(max(c*scale, 0), 255))
+	}
+	p[0], p[1], p[2], p[3] = mantissa(r), mantissa(g), mantissa(b), byte(e+128)
+}
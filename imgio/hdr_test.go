@@ -0,0 +1,207 @@
+package imgio
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/anthonynsimon/bild/adjust"
+	"github.com/anthonynsimon/bild/fcolor"
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestHDREncoder(t *testing.T) {
+	// Narrow images are stored flat, wider ones run length encoded
+	narrow := fcolor.NewImage(image.Rect(0, 0, 5, 3))
+	wide := fcolor.NewImage(image.Rect(3, -2, 300, 4))
+	for _, img := range []*fcolor.Image{narrow, wide} {
+		for i := 0; i < len(img.Pix); i += 4 {
+			v := float64(i/4%37) * 0.37
+			if i/4%100 < 50 {
+				v = 12.5
+			}
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v/2, v*100, 1
+		}
+	}
+
+	cases := []struct {
+		description string
+		value       *fcolor.Image
+	}{
+		{description: "narrow", value: narrow},
+		{description: "wide", value: wide},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := HDREncoder()(&buf, c.value); err != nil {
+			t.Fatalf("%s: %v", "HDREncoder "+c.description, err)
+		}
+
+		decoded, format, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", "HDREncoder "+c.description, err)
+		}
+		actual, ok := decoded.(*fcolor.Image)
+		if !ok || format != "hdr" || actual.Bounds().Size() != c.value.Bounds().Size() {
+			t.Fatalf("%s: expected: %v actual: %v %T %v", "HDREncoder "+c.description, c.value.Bounds(), format, decoded, decoded.Bounds())
+		}
+
+		// RGBE stores 8 bits of precision relative to the largest channel
+		b := c.value.Bounds()
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				expected, got := c.value.RGBAF64At(b.Min.X+x, b.Min.Y+y), actual.RGBAF64At(x, y)
+				tolerance := max(expected.R, expected.G, expected.B) / 128
+				if math.Abs(expected.R-got.R) > tolerance || math.Abs(expected.G-got.G) > tolerance || math.Abs(expected.B-got.B) > tolerance || got.A != 1 {
+					t.Fatalf("%s: expected: %v actual: %v at %d, %d", "HDREncoder "+c.description, expected, got, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeHDR(t *testing.T) {
+	header := "#?RADIANCE\n# comment\nFORMAT=32-bit_rle_rgbe\nEXPOSURE=1.0\n\n"
+
+	cases := []struct {
+		description string
+		value       string
+		expected    []fcolor.RGBAF64
+		width       int
+		err         error
+	}{
+		{
+			description: "flat",
+			value:       header + "-Y 1 +X 2\n\x80\x40\x00\x81\x80\x80\x80\x7F",
+			expected:    []fcolor.RGBAF64{{R: 1.00390625, G: 0.50390625, B: 0.00390625, A: 1}, {R: 0.2509765625, G: 0.2509765625, B: 0.2509765625, A: 1}},
+			width:       2,
+		},
+		{
+			description: "bottom to top right to left",
+			value:       "#?RGBE\n\n+Y 2 -X 1\n\x80\x80\x80\x81\x00\x00\x00\x00",
+			expected:    []fcolor.RGBAF64{{A: 1}, {R: 1.00390625, G: 1.00390625, B: 1.00390625, A: 1}},
+			width:       1,
+		},
+		{
+			description: "old run length encoding",
+			value:       header + "-Y 1 +X 4\n\x80\x80\x80\x81\x01\x01\x01\x02\x00\x00\x00\x00",
+			expected: []fcolor.RGBAF64{
+				{R: 1.00390625, G: 1.00390625, B: 1.00390625, A: 1}, {R: 1.00390625, G: 1.00390625, B: 1.00390625, A: 1},
+				{R: 1.00390625, G: 1.00390625, B: 1.00390625, A: 1}, {A: 1},
+			},
+			width: 4,
+		},
+		{
+			description: "run length encoding",
+			value:       header + "-Y 1 +X 8\n\x02\x02\x00\x08\x86\x80\x02\x01\x00\x88\x00\x88\x00\x88\x81",
+			expected: []fcolor.RGBAF64{
+				{R: 1.00390625, G: 0.00390625, B: 0.00390625, A: 1}, {R: 1.00390625, G: 0.00390625, B: 0.00390625, A: 1},
+				{R: 1.00390625, G: 0.00390625, B: 0.00390625, A: 1}, {R: 1.00390625, G: 0.00390625, B: 0.00390625, A: 1},
+				{R: 1.00390625, G: 0.00390625, B: 0.00390625, A: 1}, {R: 1.00390625, G: 0.00390625, B: 0.00390625, A: 1},
+				{R: 0.01171875, G: 0.00390625, B: 0.00390625, A: 1}, {R: 0.00390625, G: 0.00390625, B: 0.00390625, A: 1},
+			},
+			width: 8,
+		},
+		{
+			description: "exposure",
+			value:       "#?RADIANCE\nEXPOSURE=2\nEXPOSURE= 0.25\n\n-Y 1 +X 1\n\x80\x40\x00\x81",
+			expected:    []fcolor.RGBAF64{{R: 2.0078125, G: 1.0078125, B: 0.0078125, A: 1}},
+			width:       1,
+		},
+		{description: "zero exposure", value: "#?RADIANCE\nEXPOSURE=0\n\n-Y 1 +X 1\n\x00\x00\x00\x00", err: errInvalidHDR},
+		{description: "xyze", value: "#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x00\x00\x00\x00", err: errHDRFormat},
+		{description: "column major", value: header + "+X 1 -Y 1\n\x00\x00\x00\x00", err: errInvalidHDR},
+		{description: "truncated", value: header + "-Y 2 +X 1\n\x00\x00\x00\x00", err: io.ErrUnexpectedEOF},
+		{description: "run too long", value: header + "-Y 1 +X 8\n\x02\x02\x00\x08\x89\x80", err: errInvalidHDR},
+	}
+
+	for _, c := range cases {
+		img, err := decodeHDR(strings.NewReader(c.value))
+		if err != c.err {
+			t.Errorf("%s: expected: %v actual: %v", "decodeHDR "+c.description, c.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		actual := img.(*fcolor.Image)
+		if actual.Bounds() != image.Rect(0, 0, c.width, len(c.expected)/c.width) {
+			t.Errorf("%s: expected: %v actual: %v", "decodeHDR "+c.description, c.width, actual.Bounds())
+			continue
+		}
+		for i, expected := range c.expected {
+			if got := actual.RGBAF64At(i%c.width, i/c.width); got != expected {
+				t.Errorf("%s: expected: %v actual: %v at %d", "decodeHDR "+c.description, expected, got, i)
+			}
+		}
+	}
+}
+
+func TestHDRToneMap(t *testing.T) {
+	// The fixture stores radiances of 4 and 1 with an EXPOSURE of 0.5
+	img, err := Open("testdata/scene.hdr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	radiance, ok := img.(*fcolor.Image)
+	if !ok {
+		t.Fatalf("%s: expected: %T actual: %T", "Open scene.hdr", radiance, img)
+	}
+	expected := &fcolor.Image{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 4,
+		Pix:    []float64{4.015625, 4.015625, 4.015625, 1, 1.00390625, 1.00390625, 1.00390625, 1},
+	}
+	if !util.F64ImageApproxEqual(radiance, expected, 1e-12) {
+		t.Errorf("%s: expected: %v actual: %v", "Open scene.hdr", expected.Pix, radiance.Pix)
+	}
+
+	// One stop down brings the highlight to the white point, which maps it to the full 8-bit value
+	actual := adjust.ToneMapReinhard(adjust.ExposureF64(radiance, -1), 2)
+	mapped := &image.RGBA{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 4,
+		Pix:    []uint8{0xFF, 0xFF, 0xFF, 0xFF, 0xA5, 0xA5, 0xA5, 0xFF},
+	}
+	if !util.RGBAImageEqual(actual, mapped) {
+		t.Errorf("%s: expected: %v actual: %v", "ToneMapReinhard scene.hdr", mapped.Pix, actual.Pix)
+	}
+}
+
+func TestWriteHDRRuns(t *testing.T) {
+	cases := []struct {
+		description string
+		value       []byte
+	}{
+		{description: "literals", value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{description: "short runs", value: []byte{1, 1, 1, 2, 2, 2, 3, 3, 3, 4}},
+		{description: "long run", value: bytes.Repeat([]byte{7}, 300)},
+		{description: "mixed", value: append(append(bytes.Repeat([]byte{0, 1}, 150), bytes.Repeat([]byte{9}, 10)...), 1, 2)},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		header := []byte{2, 2, byte(len(c.value) >> 8), byte(len(c.value))}
+		line := make([]byte, len(c.value)*4)
+		for i, v := range c.value {
+			line[i*4], line[i*4+1], line[i*4+2], line[i*4+3] = v, v, v, v
+		}
+		w := bufio.NewWriter(&buf)
+		writeHDRScanline(w, line)
+		w.Flush()
+
+		if !bytes.HasPrefix(buf.Bytes(), header) {
+			t.Fatalf("%s: expected run length encoding header %v actual: %v", "writeHDRRuns "+c.description, header, buf.Bytes()[:4])
+		}
+		actual := make([]byte, len(line))
+		if err := readHDRScanline(bufio.NewReader(&buf), actual); err != nil || !bytes.Equal(actual, line) {
+			t.Errorf("%s: expected: %v actual: %v %v", "writeHDRRuns "+c.description, line, actual, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package imgio
 
 import (
+	"bytes"
 	"image"
 	"image/jpeg"
 	"image/png"
@@ -15,6 +16,9 @@ import (
 // Encoder encodes the provided image and writes it
 type Encoder func(io.Writer, image.Image) error
 
+// jpegDefaultQuality is the JPEG quality used by Encode.
+const jpegDefaultQuality = jpeg.DefaultQuality
+
 // Open loads and decodes an image from a file and returns it.
 //
 // Usage example:
@@ -37,6 +41,89 @@ func Open(filename string) (image.Image, error) {
 	return img, nil
 }
 
+// OpenOptions are the parameters used when loading an image.
+// AutoOrient set to true reads the EXIF Orientation tag of JPEG, PNG and WebP files and rotates
+// and flips the image accordingly, so that it's returned upright as a camera would display it.
+type OpenOptions struct {
+	AutoOrient bool
+}
+
+// OpenWithOptions loads and decodes an image from a file and returns it.
+// Default parameters are used if a nil *OpenOptions is passed, which is the same as calling Open.
+//
+// Usage example:
+//
+//	// Decodes a photo from a file, rotating it as described by its EXIF metadata
+//	img, err := imgio.OpenWithOptions("photo.jpg", &imgio.OpenOptions{AutoOrient: true})
+func OpenWithOptions(filename string, options *OpenOptions) (image.Image, error) {
+	if options == nil || !options.AutoOrient {
+		return Open(filename)
+	}
+
+	img, _, err := OpenWithMetadata(filename, options)
+	return img, err
+}
+
+// Decode decodes an image from the reader and returns it along with its format, which is detected
+// from the magic bytes at the start of the data.
+//
+// Usage example:
+//
+//	// Decodes an image from the body of an HTTP response
+//	img, format, err := imgio.Decode(resp.Body)
+func Decode(r io.Reader) (image.Image, Format, error) {
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, Format(format), nil
+}
+
+// DecodeWithMetadata decodes an image from the reader and returns it along with its metadata,
+// in the same way as OpenWithMetadata.
+// Default parameters are used if a nil *OpenOptions is passed.
+//
+// Usage example:
+//
+//	// Decodes a photo from the standard input, rotating it as described by its EXIF metadata
+//	img, md, err := imgio.DecodeWithMetadata(os.Stdin, &imgio.OpenOptions{AutoOrient: true})
+func DecodeWithMetadata(r io.Reader, options *OpenOptions) (image.Image, *Metadata, error) {
+	// The metadata blocks are read from the raw data, so the whole of it is kept
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	md := readMetadata(data)
+	if options != nil && options.AutoOrient {
+		if orientation := exifOrientation(md.EXIF); orientation != 1 {
+			img = orient(img, orientation)
+			setExifOrientation(md.EXIF, 1)
+		}
+	}
+
+	return img, md, nil
+}
+
+// Encode writes the image to w in the provided format, using the default options of its encoder.
+//
+// Usage example:
+//
+//	// Writes an image as PNG to the standard output
+//	err := imgio.Encode(os.Stdout, img, imgio.PNG)
+func Encode(w io.Writer, img image.Image, format Format) error {
+	encoder, err := format.encoder()
+	if err != nil {
+		return err
+	}
+	return encoder(w, img)
+}
+
 // JPEGEncoder returns an encoder to JPEG given the argument 'quality'
 func JPEGEncoder(quality int) Encoder {
 	return func(w io.Writer, img image.Image) error {
@@ -44,7 +131,8 @@ func JPEGEncoder(quality int) Encoder {
 	}
 }
 
-// PNGEncoder returns an encoder to PNG
+// PNGEncoder returns an encoder to PNG. Images with 16 bits per channel, such as the *image.RGBA64
+// returned by the 64-bit variants of the processing functions, are written with 16-bit samples.
 func PNGEncoder() Encoder {
 	return func(w io.Writer, img image.Image) error {
 		return png.Encode(w, img)
@@ -65,6 +65,32 @@ func TestEncode(t *testing.T) {
 				},
 			},
 		},
+		{
+			format:  "gif",
+			encoder: GIFEncoder(nil),
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 3),
+				Stride: 3 * 4,
+				Pix: []uint8{
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF,
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0x80, 0x00, 0x00, 0xFF,
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF,
+				},
+			},
+		},
+		{
+			format:  "tiff",
+			encoder: TIFFEncoder(nil),
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 3),
+				Stride: 3 * 4,
+				Pix: []uint8{
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF,
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0x80, 0x00, 0x00, 0xFF,
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF,
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -79,3 +105,37 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeDecode(t *testing.T) {
+	value := &image.RGBA{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0xFF, 0x00, 0x00, 0xFF, 0x00, 0x00, 0xFF, 0xFF,
+		},
+	}
+
+	for _, format := range []Format{PNG, JPEG, BMP, WEBP, GIF, TIFF, PBM, PGM, PPM, PAM, Farbfeld, HDR} {
+		buf := bytes.Buffer{}
+		if err := Encode(&buf, value, format); err != nil {
+			t.Errorf("%s: %v", "Encode "+format, err)
+			continue
+		}
+
+		img, actual, err := Decode(&buf)
+		if err != nil {
+			t.Errorf("%s: %v", "Decode "+format, err)
+			continue
+		}
+		if actual != format || img.Bounds() != value.Bounds() {
+			t.Errorf("%s: expected: %v %v actual: %v %v", "Decode "+format, format, value.Bounds(), actual, img.Bounds())
+		}
+	}
+
+	if err := Encode(&bytes.Buffer{}, value, Format("xcf")); err != ErrUnknownFormat {
+		t.Errorf("Encode: expected: %v actual: %v", ErrUnknownFormat, err)
+	}
+	if _, _, err := Decode(strings.NewReader("not an image")); err != image.ErrFormat {
+		t.Errorf("Decode: expected: %v actual: %v", image.ErrFormat, err)
+	}
+}
@@ -0,0 +1,490 @@
+package imgio
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+)
+
+// JPEGOptions are the JPEG encoding parameters.
+// Quality ranges from 1 to 100, higher is better. Default of 75 is used if zero.
+// Progressive set to true encodes the image in several scans of increasing detail, so that it's
+// displayed progressively while loading. Otherwise a baseline JPEG is encoded.
+type JPEGOptions struct {
+	Quality     int
+	Progressive bool
+}
+
+// JPEGEncoderWithOptions returns an encoder to JPEG with the provided options.
+// Default parameters are used if a nil *JPEGOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.jpg", img, imgio.JPEGEncoderWithOptions(&imgio.JPEGOptions{Quality: 85, Progressive: true}))
+func JPEGEncoderWithOptions(o *JPEGOptions) Encoder {
+	quality, progressive := jpeg.DefaultQuality, false
+	if o != nil {
+		if o.Quality != 0 {
+			quality = o.Quality
+		}
+		progressive = o.Progressive
+	}
+
+	return func(w io.Writer, img image.Image) error {
+		if progressive {
+			return encodeProgressiveJPEG(w, img, quality)
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+}
+
+// jpegZigzag maps the zig-zag order of the coefficients of a block to their natural order.
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegUnscaledQuant are the luminance and chrominance quantization tables from section K.1 of the
+// JPEG spec in zig-zag order, which are scaled according to the quality.
+var jpegUnscaledQuant = [2][64]uint8{
+	{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	},
+	{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+// jpegHuffmanSpec is a Huffman table, count[i] being the number of codes of length i+1 bits
+// and value the symbols in the order of their codes.
+type jpegHuffmanSpec struct {
+	count [16]uint8
+	value []uint8
+}
+
+// jpegHuffmanSpecs are the luminance DC, luminance AC, chrominance DC and chrominance AC tables
+// from section K.3 of the JPEG spec. The AC tables include the end of band symbol 0x00,
+// which is an end of band run of length 1 in progressive scans.
+var jpegHuffmanSpecs = [4]jpegHuffmanSpec{
+	{
+		[16]uint8{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]uint8{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]uint8{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]uint8{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]uint8{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]uint8{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// jpegHuffmanCode is the code of a symbol, stored in its lowest size bits.
+type jpegHuffmanCode struct {
+	code uint32
+	size uint
+}
+
+// codes returns the canonical Huffman codes of the table, indexed by symbol.
+func (s jpegHuffmanSpec) codes() [256]jpegHuffmanCode {
+	var codes [256]jpegHuffmanCode
+	code, k := uint32(0), 0
+	for i, n := range s.count {
+		for j := 0; j < int(n); j++ {
+			codes[s.value[k]] = jpegHuffmanCode{code, uint(i + 1)}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+// jpegComponent is a color component of the image, along with its quantized coefficients.
+type jpegComponent struct {
+	id   uint8
+	h, v int
+	// table is the index of the quantization and Huffman tables, 0 for luminance and 1 for chrominance
+	table int
+	// width and height are the size of the component in pixels
+	width, height int
+	// blocksW and blocksH are the number of blocks, padded to whole MCUs
+	blocksW, blocksH int
+	// blocks are the quantized coefficients in zig-zag order
+	blocks [][64]int32
+}
+
+// jpegScan is a scan of a progressive JPEG, holding the coefficients Ss to Se of the components.
+type jpegScan struct {
+	components []int
+	ss, se     int
+}
+
+// jpegBitWriter writes the Huffman coded data of a scan, stuffing a zero byte after each 0xFF byte.
+type jpegBitWriter struct {
+	w     *bufio.Writer
+	bits  uint64
+	nbits uint
+}
+
+func (b *jpegBitWriter) emit(code uint32, size uint) {
+	b.bits = b.bits<<size | uint64(code)&(1<<size-1)
+	b.nbits += size
+	for b.nbits >= 8 {
+		c := byte(b.bits >> (b.nbits - 8))
+		b.w.WriteByte(c)
+		if c == 0xFF {
+			b.w.WriteByte(0)
+		}
+		b.nbits -= 8
+	}
+}
+
+// flush pads the last byte of the scan with 1 bits.
+func (b *jpegBitWriter) flush() {
+	if b.nbits > 0 {
+		b.emit(1<<(8-b.nbits)-1, 8-b.nbits)
+	}
+}
+
+// emitValue writes the Huffman code of the symbol followed by the size bits of the value, of which
+// negative values are stored as their one's complement.
+func (b *jpegBitWriter) emitValue(codes *[256]jpegHuffmanCode, run int, value int32) {
+	a, size := value, uint(0)
+	if a < 0 {
+		a = -a
+		value--
+	}
+	for ; a > 0; a >>= 1 {
+		size++
+	}
+	c := codes[run<<4|int(size)]
+	b.emit(c.code, c.size)
+	if size > 0 {
+		b.emit(uint32(value), size)
+	}
+}
+
+// encodeProgressiveJPEG writes the image as a progressive JPEG using spectral selection. The DC coefficients
+// of all components are sent first, followed by the low and then the high frequencies of the luminance.
+func encodeProgressiveJPEG(w io.Writer, img image.Image, quality int) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width >= 1<<16 || height >= 1<<16 {
+		return errors.New("imgio: image is too large to encode as JPEG")
+	}
+	if width == 0 || height == 0 {
+		return errors.New("imgio: image is empty")
+	}
+
+	quality = max(1, min(100, quality))
+	scale := 200 - quality*2
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+	var quant [2][64]uint8
+	for i := range quant {
+		for j := range quant[i] {
+			quant[i][j] = uint8(max(1, min(255, (int(jpegUnscaledQuant[i][j])*scale+50)/100)))
+		}
+	}
+
+	components := jpegComponents(img, &quant)
+	scans := []jpegScan{{components: []int{0}, ss: 0, se: 0}, {components: []int{0}, ss: 1, se: 5}}
+	if len(components) == 3 {
+		scans[0].components = []int{0, 1, 2}
+		scans = append(scans, jpegScan{components: []int{1}, ss: 1, se: 63}, jpegScan{components: []int{2}, ss: 1, se: 63})
+	}
+	scans = append(scans, jpegScan{components: []int{0}, ss: 6, se: 63})
+
+	bw := bufio.NewWriter(w)
+	writeMarker := func(marker byte, payload ...byte) {
+		bw.Write([]byte{0xFF, marker, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)})
+		bw.Write(payload)
+	}
+
+	bw.Write([]byte{0xFF, 0xD8})
+
+	tables := len(components)/3 + 1
+	var dqt []byte
+	for i := 0; i < tables; i++ {
+		dqt = append(dqt, byte(i))
+		dqt = append(dqt, quant[i][:]...)
+	}
+	writeMarker(0xDB, dqt...)
+
+	sof := []byte{8, byte(height >> 8), byte(height), byte(width >> 8), byte(width), byte(len(components))}
+	for _, c := range components {
+		sof = append(sof, c.id, byte(c.h<<4|c.v), byte(c.table))
+	}
+	writeMarker(0xC2, sof...)
+
+	var dht []byte
+	for i := 0; i < tables; i++ {
+		for class, spec := range jpegHuffmanSpecs[i*2 : i*2+2] {
+			dht = append(dht, byte(class<<4|i))
+			dht = append(dht, spec.count[:]...)
+			dht = append(dht, spec.value...)
+		}
+	}
+	writeMarker(0xC4, dht...)
+
+	var codes [4][256]jpegHuffmanCode
+	for i, spec := range jpegHuffmanSpecs {
+		codes[i] = spec.codes()
+	}
+
+	for _, scan := range scans {
+		sos := []byte{byte(len(scan.components))}
+		for _, ci := range scan.components {
+			sos = append(sos, components[ci].id, byte(components[ci].table<<4|components[ci].table))
+		}
+		sos = append(sos, byte(scan.ss), byte(scan.se), 0)
+		writeMarker(0xDA, sos...)
+
+		bits := &jpegBitWriter{w: bw}
+		predictors := make([]int32, len(components))
+		encodeBlock := func(ci int, block *[64]int32) {
+			table := components[ci].table
+			if scan.ss == 0 {
+				bits.emitValue(&codes[table*2], 0, block[0]-predictors[ci])
+				predictors[ci] = block[0]
+				return
+			}
+
+			run := 0
+			for k := scan.ss; k <= scan.se; k++ {
+				if block[k] == 0 {
+					run++
+					continue
+				}
+				for ; run > 15; run -= 16 {
+					bits.emitValue(&codes[table*2+1], 15, 0)
+				}
+				bits.emitValue(&codes[table*2+1], run, block[k])
+				run = 0
+			}
+			if run > 0 {
+				bits.emitValue(&codes[table*2+1], 0, 0)
+			}
+		}
+
+		if len(scan.components) == 1 {
+			// Single component scans only hold the blocks within the component, not the MCU padding
+			ci := scan.components[0]
+			c := &components[ci]
+			for by := 0; by < (c.height+7)/8; by++ {
+				for bx := 0; bx < (c.width+7)/8; bx++ {
+					encodeBlock(ci, &c.blocks[by*c.blocksW+bx])
+				}
+			}
+		} else {
+			mcusX, mcusY := components[0].blocksW/components[0].h, components[0].blocksH/components[0].v
+			for my := 0; my < mcusY; my++ {
+				for mx := 0; mx < mcusX; mx++ {
+					for _, ci := range scan.components {
+						c := &components[ci]
+						for v := 0; v < c.v; v++ {
+							for h := 0; h < c.h; h++ {
+								encodeBlock(ci, &c.blocks[(my*c.v+v)*c.blocksW+mx*c.h+h])
+							}
+						}
+					}
+				}
+			}
+		}
+		bits.flush()
+	}
+
+	bw.Write([]byte{0xFF, 0xD9})
+	return bw.Flush()
+}
+
+// jpegComponents returns the components of the image with the quantized coefficients of each block.
+// Color images are converted to YCbCr with the chroma subsampled by 2 in both axes, and grayscale
+// images have a single component.
+func jpegComponents(img image.Image, quant *[2][64]uint8) []jpegComponent {
+	src := clone.AsShallowRGBA(img)
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	_, gray := img.(*image.Gray)
+	hmax := 2
+	if gray {
+		hmax = 1
+	}
+	mcuSize := 8 * hmax
+	mcusX, mcusY := (width+mcuSize-1)/mcuSize, (height+mcuSize-1)/mcuSize
+	pw, ph := mcusX*mcuSize, mcusY*mcuSize
+
+	// The planes are padded to whole MCUs by repeating the edge pixels
+	planes := [3][]float64{make([]float64, pw*ph), make([]float64, pw*ph), make([]float64, pw*ph)}
+	for y := 0; y < ph; y++ {
+		sy := min(y, height-1)
+		for x := 0; x < pw; x++ {
+			pos := src.PixOffset(bounds.Min.X+min(x, width-1), bounds.Min.Y+sy)
+			yy, cb, cr := color.RGBToYCbCr(src.Pix[pos], src.Pix[pos+1], src.Pix[pos+2])
+			planes[0][y*pw+x], planes[1][y*pw+x], planes[2][y*pw+x] = float64(yy), float64(cb), float64(cr)
+		}
+	}
+
+	if gray {
+		c := jpegComponent{id: 1, h: 1, v: 1, width: width, height: height, blocksW: mcusX, blocksH: mcusY}
+		c.blocks = jpegBlocks(planes[0], pw, ph, &quant[0])
+		return []jpegComponent{c}
+	}
+
+	components := []jpegComponent{{id: 1, h: 2, v: 2, width: width, height: height, blocksW: mcusX * 2, blocksH: mcusY * 2}}
+	components[0].blocks = jpegBlocks(planes[0], pw, ph, &quant[0])
+
+	cw, ch := pw/2, ph/2
+	for i := 1; i < 3; i++ {
+		sub := make([]float64, cw*ch)
+		for y := 0; y < ch; y++ {
+			for x := 0; x < cw; x++ {
+				p := planes[i][y*2*pw+x*2:]
+				sub[y*cw+x] = (p[0] + p[1] + p[pw] + p[pw+1]) / 4
+			}
+		}
+		c := jpegComponent{id: uint8(i + 1), h: 1, v: 1, table: 1, width: (width + 1) / 2, height: (height + 1) / 2, blocksW: mcusX, blocksH: mcusY}
+		c.blocks = jpegBlocks(sub, cw, ch, &quant[1])
+		components = append(components, c)
+	}
+
+	return components
+}
+
+// jpegBlocks returns the DCT coefficients of the 8x8 blocks of the plane of size w and h,
+// quantized with the table and in zig-zag order.
+func jpegBlocks(plane []float64, w, h int, quant *[64]uint8) [][64]int32 {
+	blocks := make([][64]int32, (w/8)*(h/8))
+	var samples, coefs [64]float64
+	for by := 0; by < h/8; by++ {
+		for bx := 0; bx < w/8; bx++ {
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 8; x++ {
+					samples[y*8+x] = plane[(by*8+y)*w+bx*8+x] - 128
+				}
+			}
+			fdct(&samples, &coefs)
+			block := &blocks[by*(w/8)+bx]
+			for k := range block {
+				block[k] = int32(math.Round(coefs[jpegZigzag[k]] / float64(quant[k])))
+			}
+		}
+	}
+	return blocks
+}
+
+// dctCos are the cosines used by the DCT, scaled by the normalization factors.
+var dctCos = func() [8][8]float64 {
+	var table [8][8]float64
+	for u := 0; u < 8; u++ {
+		f := 0.5
+		if u == 0 {
+			f = 0.5 / math.Sqrt2
+		}
+		for x := 0; x < 8; x++ {
+			table[u][x] = f * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+	return table
+}()
+
+// fdct computes the 2D forward DCT of the 8x8 block, applied to its rows and then to its columns.
+func fdct(src, dst *[64]float64) {
+	var tmp [64]float64
+	for y := 0; y < 8; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < 8; x++ {
+				sum += dctCos[u][x] * src[y*8+x]
+			}
+			tmp[y*8+u] = sum
+		}
+	}
+	for u := 0; u < 8; u++ {
+		for v := 0; v < 8; v++ {
+			var sum float64
+			for y := 0; y < 8; y++ {
+				sum += dctCos[v][y] * tmp[y*8+u]
+			}
+			dst[v*8+u] = sum
+		}
+	}
+}
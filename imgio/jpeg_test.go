@@ -0,0 +1,102 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// gradient returns an image of the provided bounds with smooth color transitions.
+func gradient(r image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), uint8(y * 4), uint8(255 - (x+y)*2), 0xFF})
+		}
+	}
+	return img
+}
+
+// meanAbsDiff returns the mean absolute difference of the color channels of the images.
+func meanAbsDiff(a, b image.Image) float64 {
+	var sum float64
+	ba, bb := a.Bounds(), b.Bounds()
+	for y := 0; y < ba.Dy(); y++ {
+		for x := 0; x < ba.Dx(); x++ {
+			ca := color.NRGBAModel.Convert(a.At(ba.Min.X+x, ba.Min.Y+y)).(color.NRGBA)
+			cb := color.NRGBAModel.Convert(b.At(bb.Min.X+x, bb.Min.Y+y)).(color.NRGBA)
+			for _, d := range []int{int(ca.R) - int(cb.R), int(ca.G) - int(cb.G), int(ca.B) - int(cb.B)} {
+				sum += float64(max(d, -d))
+			}
+		}
+	}
+	return sum / float64(ba.Dx()*ba.Dy()*3)
+}
+
+func TestJPEGEncoderWithOptions(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 19, 11))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 3)
+	}
+
+	cases := []struct {
+		description string
+		img         image.Image
+		options     *JPEGOptions
+	}{
+		{description: "default", img: gradient(image.Rect(0, 0, 37, 21)), options: nil},
+		{description: "baseline", img: gradient(image.Rect(0, 0, 37, 21)), options: &JPEGOptions{Quality: 90}},
+		{description: "progressive", img: gradient(image.Rect(0, 0, 37, 21)), options: &JPEGOptions{Quality: 90, Progressive: true}},
+		{description: "progressive single pixel", img: gradient(image.Rect(0, 0, 1, 1)), options: &JPEGOptions{Progressive: true}},
+		{description: "progressive offset bounds", img: gradient(image.Rect(5, 3, 30, 40)), options: &JPEGOptions{Quality: 90, Progressive: true}},
+		{description: "progressive gray", img: gray, options: &JPEGOptions{Quality: 90, Progressive: true}},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := JPEGEncoderWithOptions(c.options)(&buf, c.img); err != nil {
+			t.Fatalf("%s: %v", "JPEGEncoderWithOptions "+c.description, err)
+		}
+
+		progressive := c.options != nil && c.options.Progressive
+		if sof2 := bytes.Contains(buf.Bytes(), []byte{0xFF, 0xC2}); sof2 != progressive {
+			t.Errorf("%s: expected progressive: %v actual: %v", "JPEGEncoderWithOptions "+c.description, progressive, sof2)
+		}
+
+		decoded, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Errorf("%s: %v", "JPEGEncoderWithOptions "+c.description, err)
+			continue
+		}
+		if decoded.Bounds().Size() != c.img.Bounds().Size() {
+			t.Errorf("%s: expected size: %v actual: %v", "JPEGEncoderWithOptions "+c.description, c.img.Bounds().Size(), decoded.Bounds().Size())
+		}
+		if diff := meanAbsDiff(c.img, decoded); diff > 4 {
+			t.Errorf("%s: mean difference too large: %.2f", "JPEGEncoderWithOptions "+c.description, diff)
+		}
+	}
+}
+
+func TestProgressiveJPEGMatchesBaseline(t *testing.T) {
+	img := gradient(image.Rect(0, 0, 64, 48))
+
+	var baseline, progressive bytes.Buffer
+	JPEGEncoderWithOptions(&JPEGOptions{Quality: 80})(&baseline, img)
+	JPEGEncoderWithOptions(&JPEGOptions{Quality: 80, Progressive: true})(&progressive, img)
+
+	a, err := jpeg.Decode(&baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := jpeg.Decode(&progressive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both use the same quantization tables, so the error is of the same magnitude
+	if da, db := meanAbsDiff(img, a), meanAbsDiff(img, b); db > da+1 {
+		t.Errorf("ProgressiveJPEG: expected a difference close to the baseline %.2f, actual: %.2f", da, db)
+	}
+}
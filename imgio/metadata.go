@@ -0,0 +1,489 @@
+package imgio
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+	"os"
+)
+
+// Metadata holds the metadata blocks stored alongside the pixels of an image file.
+// EXIF is the EXIF payload starting at its TIFF header, ICC is the ICC color profile
+// and XMP is the XMP packet. Blocks missing from the file are nil.
+type Metadata struct {
+	EXIF []byte
+	ICC  []byte
+	XMP  []byte
+}
+
+// StripOptions select the metadata removed by Strip.
+// GPS removes the location from the EXIF payload while keeping the rest of it.
+type StripOptions struct {
+	EXIF bool
+	GPS  bool
+	ICC  bool
+	XMP  bool
+}
+
+// ErrMetadataTooLarge is returned when a metadata block doesn't fit in the encoded format.
+var ErrMetadataTooLarge = errors.New("imgio: metadata block too large for the format")
+
+const (
+	jpegExifHeader = "Exif\x00\x00"
+	jpegXMPHeader  = "http://ns.adobe.com/xap/1.0/\x00"
+	jpegICCHeader  = "ICC_PROFILE\x00"
+	pngSignature   = "\x89PNG\r\n\x1a\n"
+	pngXMPKeyword  = "XML:com.adobe.xmp"
+
+	// Max size of the payload of a JPEG segment, the length field includes its own 2 bytes
+	jpegMaxSegment = 0xFFFF - 2
+)
+
+// Strip returns a copy of the metadata without the selected blocks.
+// Default of removing all metadata is used if a nil *StripOptions is passed.
+//
+// Usage example:
+//
+//	// Remove the location of a photo, keeping the rest of its metadata
+//	md = md.Strip(&imgio.StripOptions{GPS: true})
+func (m *Metadata) Strip(options *StripOptions) *Metadata {
+	if options == nil {
+		options = &StripOptions{EXIF: true, ICC: true, XMP: true}
+	}
+
+	result := m.clone()
+	if options.EXIF {
+		result.EXIF = nil
+	} else if options.GPS && result.EXIF != nil {
+		stripExifGPS(result.EXIF)
+	}
+	if options.ICC {
+		result.ICC = nil
+	}
+	if options.XMP {
+		result.XMP = nil
+	}
+
+	return result
+}
+
+// clone returns a deep copy of the metadata.
+func (m *Metadata) clone() *Metadata {
+	if m == nil {
+		return &Metadata{}
+	}
+	return &Metadata{EXIF: bytes.Clone(m.EXIF), ICC: bytes.Clone(m.ICC), XMP: bytes.Clone(m.XMP)}
+}
+
+// empty returns true if the metadata has no blocks.
+func (m *Metadata) empty() bool {
+	return m == nil || (m.EXIF == nil && m.ICC == nil && m.XMP == nil)
+}
+
+// OpenWithMetadata loads and decodes an image from a file and returns it along with its metadata,
+// read from JPEG, PNG and WebP files. The metadata is empty for other formats.
+// If the AutoOrient option rotates the image, the EXIF Orientation tag of the returned metadata
+// is reset so that the image isn't rotated again when saved with it.
+// Default parameters are used if a nil *OpenOptions is passed.
+//
+// Usage example:
+//
+//	// Decodes a photo and saves it as a smaller JPEG with the same metadata
+//	img, md, err := imgio.OpenWithMetadata("photo.jpg", nil)
+//	if err != nil {
+//		return err
+//	}
+//	result := transform.Resize(img, 800, 600, transform.Linear)
+//	err = imgio.Save("small.jpg", result, imgio.WithMetadata(imgio.JPEGEncoder(90), md))
+func OpenWithMetadata(filename string, options *OpenOptions) (image.Image, *Metadata, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return DecodeWithMetadata(f, options)
+}
+
+// WithMetadata returns an encoder that writes the metadata into the data encoded by the provided encoder.
+// JPEG, PNG and WebP are supported, the data of other formats is written unchanged.
+//
+// Usage example:
+//
+//	// Save an image as PNG with the ICC profile of another image
+//	err := imgio.Save("output.png", img, imgio.WithMetadata(imgio.PNGEncoder(), &imgio.Metadata{ICC: md.ICC}))
+func WithMetadata(encoder Encoder, md *Metadata) Encoder {
+	return func(w io.Writer, img image.Image) error {
+		if md.empty() {
+			return encoder(w, img)
+		}
+
+		var buf bytes.Buffer
+		if err := encoder(&buf, img); err != nil {
+			return err
+		}
+
+		data, err := writeMetadata(buf.Bytes(), img.Bounds(), md)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		return err
+	}
+}
+
+// readMetadata returns the metadata stored in the JPEG, PNG or WebP encoded data.
+func readMetadata(data []byte) *Metadata {
+	switch {
+	case isJPEG(data):
+		return readJPEGMetadata(data)
+	case isPNG(data):
+		return readPNGMetadata(data)
+	case isWebP(data):
+		return readWebPMetadata(data)
+	}
+	return &Metadata{}
+}
+
+// writeMetadata returns the JPEG, PNG or WebP encoded data of an image with bounds b with the metadata
+// added to it. The data of other formats is returned unchanged.
+func writeMetadata(data []byte, b image.Rectangle, md *Metadata) ([]byte, error) {
+	switch {
+	case isJPEG(data):
+		return writeJPEGMetadata(data, md)
+	case isPNG(data):
+		return writePNGMetadata(data, md)
+	case isWebP(data):
+		return writeWebPMetadata(data, b, md)
+	}
+	return data, nil
+}
+
+func isJPEG(data []byte) bool {
+	return len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8
+}
+
+func isPNG(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(pngSignature))
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// jpegSegments calls fn with the marker, position and payload of each segment of the JPEG encoded data
+// up to the start of the compressed data, stopping early if fn returns false.
+func jpegSegments(data []byte, fn func(marker byte, pos int, payload []byte) bool) {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return
+		}
+		marker := data[i+1]
+		// Start of scan, the compressed data follows and there are no more metadata segments
+		if marker == 0xDA || marker == 0xD9 {
+			return
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return
+		}
+		if !fn(marker, i, data[i+4:i+2+size]) {
+			return
+		}
+		i += 2 + size
+	}
+}
+
+func readJPEGMetadata(data []byte) *Metadata {
+	md := &Metadata{}
+	var icc [][]byte
+
+	jpegSegments(data, func(marker byte, pos int, payload []byte) bool {
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte(jpegExifHeader)) && md.EXIF == nil:
+			md.EXIF = bytes.Clone(payload[len(jpegExifHeader):])
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte(jpegXMPHeader)) && md.XMP == nil:
+			md.XMP = bytes.Clone(payload[len(jpegXMPHeader):])
+		case marker == 0xE2 && bytes.HasPrefix(payload, []byte(jpegICCHeader)) && len(payload) >= len(jpegICCHeader)+2:
+			// The profile is split across segments, numbered from 1 up to their count
+			seq, count := int(payload[len(jpegICCHeader)]), int(payload[len(jpegICCHeader)+1])
+			if icc == nil && count > 0 {
+				icc = make([][]byte, count)
+			}
+			if seq >= 1 && seq <= len(icc) {
+				icc[seq-1] = payload[len(jpegICCHeader)+2:]
+			}
+		}
+		return true
+	})
+
+	for _, chunk := range icc {
+		if chunk == nil {
+			return md
+		}
+	}
+	if icc != nil {
+		md.ICC = bytes.Join(icc, nil)
+	}
+
+	return md
+}
+
+func writeJPEGMetadata(data []byte, md *Metadata) ([]byte, error) {
+	// Insert the segments after the JFIF header if present, otherwise right after the start of image marker
+	pos := 2
+	jpegSegments(data, func(marker byte, p int, payload []byte) bool {
+		if marker == 0xE0 {
+			pos = p + 4 + len(payload)
+		}
+		return false
+	})
+
+	var segments bytes.Buffer
+	writeSegment := func(marker byte, parts ...[]byte) error {
+		size := 0
+		for _, p := range parts {
+			size += len(p)
+		}
+		if size > jpegMaxSegment {
+			return ErrMetadataTooLarge
+		}
+		segments.Write([]byte{0xFF, marker})
+		segments.Write(binary.BigEndian.AppendUint16(nil, uint16(size+2)))
+		for _, p := range parts {
+			segments.Write(p)
+		}
+		return nil
+	}
+
+	if md.EXIF != nil {
+		if err := writeSegment(0xE1, []byte(jpegExifHeader), md.EXIF); err != nil {
+			return nil, err
+		}
+	}
+	if md.XMP != nil {
+		if err := writeSegment(0xE1, []byte(jpegXMPHeader), md.XMP); err != nil {
+			return nil, err
+		}
+	}
+	if md.ICC != nil {
+		chunkSize := jpegMaxSegment - len(jpegICCHeader) - 2
+		count := (len(md.ICC) + chunkSize - 1) / chunkSize
+		if count > 255 {
+			return nil, ErrMetadataTooLarge
+		}
+		for i := 0; i < count; i++ {
+			chunk := md.ICC[i*chunkSize : min((i+1)*chunkSize, len(md.ICC))]
+			if err := writeSegment(0xE2, []byte(jpegICCHeader), []byte{byte(i + 1), byte(count)}, chunk); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := make([]byte, 0, len(data)+segments.Len())
+	result = append(result, data[:pos]...)
+	result = append(result, segments.Bytes()...)
+	return append(result, data[pos:]...), nil
+}
+
+// pngChunks calls fn with the name, position and payload of each chunk of the PNG encoded data,
+// stopping early if fn returns false.
+func pngChunks(data []byte, fn func(name string, pos int, payload []byte) bool) {
+	for i := len(pngSignature); i+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		if size < 0 || i+12+size > len(data) {
+			return
+		}
+		if !fn(string(data[i+4:i+8]), i, data[i+8:i+8+size]) {
+			return
+		}
+		i += 12 + size
+	}
+}
+
+func readPNGMetadata(data []byte) *Metadata {
+	md := &Metadata{}
+
+	pngChunks(data, func(name string, pos int, payload []byte) bool {
+		switch name {
+		case "eXIf":
+			md.EXIF = bytes.Clone(payload)
+		case "iCCP":
+			// Profile name, null separator, compression method and the zlib compressed profile
+			sep := bytes.IndexByte(payload, 0)
+			if sep < 0 || sep+2 > len(payload) {
+				break
+			}
+			r, err := zlib.NewReader(bytes.NewReader(payload[sep+2:]))
+			if err != nil {
+				break
+			}
+			if profile, err := io.ReadAll(r); err == nil {
+				md.ICC = profile
+			}
+		case "iTXt":
+			// Keyword, null separator, compression flag and method, language tag and translated keyword,
+			// both null terminated, and the text
+			fields := bytes.SplitN(payload, []byte{0}, 2)
+			if len(fields) < 2 || string(fields[0]) != pngXMPKeyword || len(fields[1]) < 2 || fields[1][0] != 0 {
+				break
+			}
+			rest := bytes.SplitN(fields[1][2:], []byte{0}, 3)
+			if len(rest) == 3 {
+				md.XMP = bytes.Clone(rest[2])
+			}
+		}
+		return name != "IEND"
+	})
+
+	return md
+}
+
+func writePNGMetadata(data []byte, md *Metadata) ([]byte, error) {
+	// The metadata chunks must come before the image data, so they are inserted right after the header
+	pos := -1
+	pngChunks(data, func(name string, p int, payload []byte) bool {
+		if name == "IHDR" {
+			pos = p + 12 + len(payload)
+		}
+		return false
+	})
+	if pos < 0 {
+		return data, nil
+	}
+
+	var chunks bytes.Buffer
+	writeChunk := func(name string, payload []byte) {
+		chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		chunk = append(chunk, name...)
+		chunk = append(chunk, payload...)
+		chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+		chunks.Write(chunk)
+	}
+
+	if md.ICC != nil {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(md.ICC)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		writeChunk("iCCP", append([]byte("ICC Profile\x00\x00"), compressed.Bytes()...))
+	}
+	if md.EXIF != nil {
+		writeChunk("eXIf", md.EXIF)
+	}
+	if md.XMP != nil {
+		writeChunk("iTXt", append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), md.XMP...))
+	}
+
+	result := make([]byte, 0, len(data)+chunks.Len())
+	result = append(result, data[:pos]...)
+	result = append(result, chunks.Bytes()...)
+	return append(result, data[pos:]...), nil
+}
+
+// webpChunks calls fn with the name and payload of each chunk of the WebP encoded data,
+// stopping early if fn returns false.
+func webpChunks(data []byte, fn func(name string, payload []byte) bool) {
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if size < 0 || i+8+size > len(data) {
+			return
+		}
+		if !fn(string(data[i:i+4]), data[i+8:i+8+size]) {
+			return
+		}
+		// Chunks are padded to an even size
+		i += 8 + size + size&1
+	}
+}
+
+func readWebPMetadata(data []byte) *Metadata {
+	md := &Metadata{}
+
+	webpChunks(data, func(name string, payload []byte) bool {
+		switch name {
+		case "EXIF":
+			// Some encoders keep the JPEG segment header in the chunk
+			md.EXIF = bytes.Clone(bytes.TrimPrefix(payload, []byte(jpegExifHeader)))
+		case "ICCP":
+			md.ICC = bytes.Clone(payload)
+		case "XMP ":
+			md.XMP = bytes.Clone(payload)
+		}
+		return true
+	})
+
+	return md
+}
+
+// WebP extended format flags, stored in the VP8X chunk.
+const (
+	webpFlagICC   = 0x20
+	webpFlagAlpha = 0x10
+	webpFlagEXIF  = 0x08
+	webpFlagXMP   = 0x04
+)
+
+func writeWebPMetadata(data []byte, b image.Rectangle, md *Metadata) ([]byte, error) {
+	var vp8x []byte
+	var frames []webpChunk
+	webpChunks(data, func(name string, payload []byte) bool {
+		switch name {
+		case "VP8X":
+			vp8x = bytes.Clone(payload)
+		case "ICCP", "EXIF", "XMP ":
+			// Replaced by the provided metadata
+		default:
+			frames = append(frames, webpChunk{name, payload})
+		}
+		return true
+	})
+
+	// Simple format files have a single image chunk, the extended format header is required for metadata
+	if len(vp8x) < 10 {
+		vp8x = webpVP8X(b, 0)
+		for _, c := range frames {
+			// Lossless images store whether they use the alpha channel in their header
+			if c.name == "VP8L" && len(c.payload) >= 5 && c.payload[4]&0x10 != 0 {
+				vp8x[0] |= webpFlagAlpha
+			}
+		}
+	}
+
+	vp8x[0] &^= webpFlagICC | webpFlagEXIF | webpFlagXMP
+	if md.ICC != nil {
+		vp8x[0] |= webpFlagICC
+	}
+	if md.EXIF != nil {
+		vp8x[0] |= webpFlagEXIF
+	}
+	if md.XMP != nil {
+		vp8x[0] |= webpFlagXMP
+	}
+
+	// The ICC profile precedes the image data and EXIF and XMP follow it
+	chunks := []webpChunk{{"VP8X", vp8x}}
+	if md.ICC != nil {
+		chunks = append(chunks, webpChunk{"ICCP", md.ICC})
+	}
+	chunks = append(chunks, frames...)
+	if md.EXIF != nil {
+		chunks = append(chunks, webpChunk{"EXIF", md.EXIF})
+	}
+	if md.XMP != nil {
+		chunks = append(chunks, webpChunk{"XMP ", md.XMP})
+	}
+
+	result, ok := webpFile(chunks)
+	if !ok {
+		return nil, ErrMetadataTooLarge
+	}
+	return result, nil
+}
@@ -0,0 +1,177 @@
+package imgio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadMetadata(t *testing.T) {
+	payload := exifPayload(binary.LittleEndian, 3)
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+
+	var jpegBuf, pngBuf bytes.Buffer
+	jpeg.Encode(&jpegBuf, img, nil)
+	png.Encode(&pngBuf, img)
+
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0A\x00\x00\x00\x08\x00\x00\x00\x01\x00\x00\x01\x00\x00EXIF")
+	webp = binary.LittleEndian.AppendUint32(webp, uint32(len(payload)+6))
+	webp = append(webp, "Exif\x00\x00"...)
+	webp = append(webp, payload...)
+
+	cases := []struct {
+		description string
+		data        []byte
+		expected    []byte
+	}{
+		{description: "jpeg", data: withJPEGExif(jpegBuf.Bytes(), payload), expected: payload},
+		{description: "png", data: withPNGExif(pngBuf.Bytes(), payload), expected: payload},
+		{description: "webp with segment header", data: webp, expected: payload},
+		{description: "jpeg without exif", data: jpegBuf.Bytes(), expected: nil},
+		{description: "png without exif", data: pngBuf.Bytes(), expected: nil},
+		{description: "unknown format", data: []byte("GIF89a"), expected: nil},
+	}
+
+	for _, c := range cases {
+		actual := readMetadata(c.data)
+		if !bytes.Equal(actual.EXIF, c.expected) {
+			t.Errorf("%s: expected: %v actual: %v", "readMetadata "+c.description, c.expected, actual.EXIF)
+		}
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+
+	md := &Metadata{
+		EXIF: exifPayload(binary.BigEndian, 1),
+		ICC:  bytes.Repeat([]byte("profile"), 20000),
+		XMP:  []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"></x:xmpmeta>`),
+	}
+
+	cases := []struct {
+		format   string
+		encoder  Encoder
+		expected *Metadata
+	}{
+		{format: "jpeg", encoder: JPEGEncoder(90), expected: md},
+		{format: "png", encoder: PNGEncoder(), expected: md},
+		{format: "webp", encoder: WEBPEncoder(nil), expected: md},
+		{format: "bmp", encoder: BMPEncoder(), expected: &Metadata{}},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := WithMetadata(c.encoder, md)(&buf, img); err != nil {
+			t.Fatalf("%s: %v", "WithMetadata "+c.format, err)
+		}
+
+		decoded, format, err := image.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%s: %v", "WithMetadata "+c.format, err)
+			continue
+		}
+		if format != c.format || decoded.Bounds() != img.Bounds() {
+			t.Errorf("%s: unexpected decoded %s image of bounds %v", "WithMetadata "+c.format, format, decoded.Bounds())
+		}
+
+		actual := readMetadata(buf.Bytes())
+		if !bytes.Equal(actual.EXIF, c.expected.EXIF) || !bytes.Equal(actual.ICC, c.expected.ICC) || !bytes.Equal(actual.XMP, c.expected.XMP) {
+			t.Errorf("%s: metadata not preserved, actual sizes: %d %d %d",
+				"WithMetadata "+c.format, len(actual.EXIF), len(actual.ICC), len(actual.XMP))
+		}
+	}
+}
+
+func TestWithMetadataTooLarge(t *testing.T) {
+	md := &Metadata{EXIF: make([]byte, 0x10000)}
+	err := WithMetadata(JPEGEncoder(90), md)(&bytes.Buffer{}, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	if err != ErrMetadataTooLarge {
+		t.Errorf("WithMetadata: expected: %v actual: %v", ErrMetadataTooLarge, err)
+	}
+}
+
+func TestMetadataStrip(t *testing.T) {
+	md := &Metadata{EXIF: exifPayloadWithGPS(), ICC: []byte("icc"), XMP: []byte("xmp")}
+
+	cases := []struct {
+		description string
+		options     *StripOptions
+		check       func(m *Metadata) bool
+	}{
+		{
+			description: "all",
+			options:     nil,
+			check:       func(m *Metadata) bool { return m.empty() },
+		},
+		{
+			description: "gps",
+			options:     &StripOptions{GPS: true},
+			check: func(m *Metadata) bool {
+				e, _ := parseExif(m.EXIF)
+				return e.findEntry(e.firstIFD(), exifGPSInfoTag) < 0 && m.ICC != nil && m.XMP != nil
+			},
+		},
+		{
+			description: "icc and xmp",
+			options:     &StripOptions{ICC: true, XMP: true},
+			check:       func(m *Metadata) bool { return bytes.Equal(m.EXIF, md.EXIF) && m.ICC == nil && m.XMP == nil },
+		},
+	}
+
+	for _, c := range cases {
+		if actual := md.Strip(c.options); !c.check(actual) {
+			t.Errorf("%s: unexpected result: %+v", "Strip "+c.description, actual)
+		}
+	}
+
+	if !bytes.Equal(md.EXIF, exifPayloadWithGPS()) {
+		t.Errorf("Strip: expected the source metadata to be unchanged")
+	}
+}
+
+func TestOpenWithMetadata(t *testing.T) {
+	md := &Metadata{EXIF: exifPayload(binary.LittleEndian, 8), XMP: []byte("xmp")}
+	filename := filepath.Join(t.TempDir(), "rotated.jpg")
+	if err := Save(filename, image.NewRGBA(image.Rect(0, 0, 4, 2)), WithMetadata(JPEGEncoder(90), md)); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		description         string
+		options             *OpenOptions
+		expectedBounds      image.Rectangle
+		expectedOrientation int
+	}{
+		{description: "default", options: nil, expectedBounds: image.Rect(0, 0, 4, 2), expectedOrientation: 8},
+		{description: "auto orient", options: &OpenOptions{AutoOrient: true}, expectedBounds: image.Rect(0, 0, 2, 4), expectedOrientation: 1},
+	}
+
+	for _, c := range cases {
+		img, actual, err := OpenWithMetadata(filename, c.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds() != c.expectedBounds {
+			t.Errorf("%s: expected bounds: %v actual: %v", "OpenWithMetadata "+c.description, c.expectedBounds, img.Bounds())
+		}
+		if o := exifOrientation(actual.EXIF); o != c.expectedOrientation {
+			t.Errorf("%s: expected orientation: %d actual: %d", "OpenWithMetadata "+c.description, c.expectedOrientation, o)
+		}
+		if string(actual.XMP) != "xmp" {
+			t.Errorf("%s: expected the XMP to be read, actual: %q", "OpenWithMetadata "+c.description, actual.XMP)
+		}
+	}
+
+	if _, _, err := OpenWithMetadata(filepath.Join(t.TempDir(), "missing.jpg"), nil); !os.IsNotExist(err) {
+		t.Errorf("OpenWithMetadata: expected a not exist error, actual: %v", err)
+	}
+}
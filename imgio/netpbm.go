@@ -0,0 +1,578 @@
+package imgio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NetpbmOptions are the PBM, PGM and PPM encoding parameters.
+// ASCII set to true writes the plain variant of the format, which stores the samples as decimal
+// numbers, instead of the raw binary one.
+type NetpbmOptions struct {
+	ASCII bool
+}
+
+var (
+	errInvalidNetpbm = errors.New("imgio: invalid netpbm image")
+	errNetpbmDepth   = errors.New("imgio: unsupported netpbm depth")
+)
+
+// maxDecodedSamples limits the size of the images decoded by the Netpbm, farbfeld and Radiance HDR decoders.
+const maxDecodedSamples = 1 << 30
+
+func init() {
+	image.RegisterFormat("pbm", "P1", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pbm", "P4", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pgm", "P2", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pgm", "P5", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P3", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P6", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pam", "P7", decodeNetpbm, decodeNetpbmConfig)
+}
+
+// PBMEncoder returns an encoder to PBM with the provided options, the pixels darker than half gray
+// being black and the others white.
+// Default parameters are used if a nil *NetpbmOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.pbm", img, imgio.PBMEncoder(&imgio.NetpbmOptions{ASCII: true}))
+func PBMEncoder(o *NetpbmOptions) Encoder {
+	return func(w io.Writer, img image.Image) error {
+		return encodeNetpbm(w, img, '1', o)
+	}
+}
+
+// PGMEncoder returns an encoder to PGM with the provided options. Images with 16-bit color models
+// are stored with 16 bits per sample.
+// Default parameters are used if a nil *NetpbmOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.pgm", img, imgio.PGMEncoder(nil))
+func PGMEncoder(o *NetpbmOptions) Encoder {
+	return func(w io.Writer, img image.Image) error {
+		return encodeNetpbm(w, img, '2', o)
+	}
+}
+
+// PPMEncoder returns an encoder to PPM with the provided options. Images with 16-bit color models
+// are stored with 16 bits per sample.
+// Default parameters are used if a nil *NetpbmOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.ppm", img, imgio.PPMEncoder(nil))
+func PPMEncoder(o *NetpbmOptions) Encoder {
+	return func(w io.Writer, img image.Image) error {
+		return encodeNetpbm(w, img, '3', o)
+	}
+}
+
+// PAMEncoder returns an encoder to PAM, which stores *image.Gray and *image.Gray16 images as
+// GRAYSCALE, opaque images as RGB and the others as RGB_ALPHA. Images with 16-bit color models
+// are stored with 16 bits per sample.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.pam", img, imgio.PAMEncoder())
+func PAMEncoder() Encoder {
+	return func(w io.Writer, img image.Image) error {
+		return encodeNetpbm(w, img, '7', nil)
+	}
+}
+
+// netpbmHeader describes the raster of a Netpbm image. The magic number ranges from '1' to '7', the
+// plain formats being '1' to '3' and their raw counterparts '4' to '6'.
+type netpbmHeader struct {
+	magic         byte
+	width, height int
+	depth         int
+	maxval        int
+}
+
+// colorModel returns the color model of the images decoded with the header.
+func (h *netpbmHeader) colorModel() color.Model {
+	deep := h.maxval > 0xFF
+	switch {
+	case h.depth == 1 && deep:
+		return color.Gray16Model
+	case h.depth == 1:
+		return color.GrayModel
+	case h.depth == 3 && deep:
+		return color.RGBA64Model
+	case h.depth == 3:
+		return color.RGBAModel
+	case deep:
+		return color.NRGBA64Model
+	default:
+		return color.NRGBAModel
+	}
+}
+
+func decodeNetpbmConfig(r io.Reader) (image.Config, error) {
+	h, err := readNetpbmHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: h.colorModel(), Width: h.width, Height: h.height}, nil
+}
+
+// decodeNetpbm decodes any of the PBM, PGM, PPM and PAM formats. PAM images are read according to
+// their depth, 1 being grayscale, 2 grayscale with alpha, 3 RGB and 4 RGB with alpha.
+func decodeNetpbm(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readNetpbmHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := readNetpbmSamples(br, h)
+	if err != nil {
+		return nil, err
+	}
+
+	// Samples are scaled to the full range of the color model
+	full := uint32(0xFF)
+	if h.maxval > 0xFF {
+		full = 0xFFFF
+	}
+	for i, s := range samples {
+		v := (uint32(s)*full + uint32(h.maxval)/2) / uint32(h.maxval)
+		// PBM stores black as 1
+		if h.magic == '1' || h.magic == '4' {
+			v = full - v
+		}
+		samples[i] = uint16(v)
+	}
+
+	rect := image.Rect(0, 0, h.width, h.height)
+	switch h.colorModel() {
+	case color.GrayModel:
+		img := image.NewGray(rect)
+		for i, s := range samples {
+			img.Pix[i] = uint8(s)
+		}
+		return img, nil
+	case color.Gray16Model:
+		img := image.NewGray16(rect)
+		for i, s := range samples {
+			img.Pix[i*2], img.Pix[i*2+1] = uint8(s>>8), uint8(s)
+		}
+		return img, nil
+	}
+
+	// Color images are stored as 4 samples per pixel, which get their missing channels filled in
+	deep := h.maxval > 0xFF
+	pixel := make([]uint16, 4)
+	pix := make([]uint8, 0, h.width*h.height*8)
+	for i := 0; i < len(samples); i += h.depth {
+		switch h.depth {
+		case 2:
+			pixel[0], pixel[1], pixel[2], pixel[3] = samples[i], samples[i], samples[i], samples[i+1]
+		case 3:
+			pixel[0], pixel[1], pixel[2], pixel[3] = samples[i], samples[i+1], samples[i+2], uint16(full)
+		default:
+			copy(pixel, samples[i:i+4])
+		}
+		for _, s := range pixel {
+			if deep {
+				pix = append(pix, uint8(s>>8), uint8(s))
+			} else {
+				pix = append(pix, uint8(s))
+			}
+		}
+	}
+
+	switch h.colorModel() {
+	case color.RGBAModel:
+		return &image.RGBA{Pix: pix, Stride: h.width * 4, Rect: rect}, nil
+	case color.RGBA64Model:
+		return &image.RGBA64{Pix: pix, Stride: h.width * 8, Rect: rect}, nil
+	case color.NRGBA64Model:
+		return &image.NRGBA64{Pix: pix, Stride: h.width * 8, Rect: rect}, nil
+	default:
+		return &image.NRGBA{Pix: pix, Stride: h.width * 4, Rect: rect}, nil
+	}
+}
+
+// readNetpbmHeader reads the header of the image, leaving r at the start of its raster.
+func readNetpbmHeader(r *bufio.Reader) (*netpbmHeader, error) {
+	var magic [2]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+	if magic[0] != 'P' || magic[1] < '1' || magic[1] > '7' {
+		return nil, errInvalidNetpbm
+	}
+
+	h := &netpbmHeader{magic: magic[1], depth: 1, maxval: 1}
+	if h.magic == '7' {
+		if err := readPAMHeader(r, h); err != nil {
+			return nil, err
+		}
+	} else {
+		fields := []*int{&h.width, &h.height}
+		if h.magic != '1' && h.magic != '4' {
+			fields = append(fields, &h.maxval)
+		}
+		if h.magic == '3' || h.magic == '6' {
+			h.depth = 3
+		}
+		for _, field := range fields {
+			v, err := readNetpbmInt(r)
+			if err != nil {
+				return nil, err
+			}
+			*field = v
+		}
+	}
+
+	if h.width < 1 || h.height < 1 || h.maxval < 1 || h.maxval > 0xFFFF {
+		return nil, errInvalidNetpbm
+	}
+	if h.depth < 1 || h.depth > 4 {
+		return nil, errNetpbmDepth
+	}
+	if uint64(h.width)*uint64(h.height)*uint64(h.depth) > maxDecodedSamples {
+		return nil, errInvalidNetpbm
+	}
+	return h, nil
+}
+
+// readPAMHeader reads the lines of the header of a PAM image following its magic number, up to the
+// ENDHDR line. The TUPLTYPE is ignored, the depth being enough to interpret the samples.
+func readPAMHeader(r *bufio.Reader, h *netpbmHeader) error {
+	h.depth, h.maxval = 0, 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return errInvalidNetpbm
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var field *int
+		switch fields[0] {
+		case "ENDHDR":
+			return nil
+		case "TUPLTYPE":
+			continue
+		case "WIDTH":
+			field = &h.width
+		case "HEIGHT":
+			field = &h.height
+		case "DEPTH":
+			field = &h.depth
+		case "MAXVAL":
+			field = &h.maxval
+		default:
+			return errInvalidNetpbm
+		}
+		if len(fields) != 2 {
+			return errInvalidNetpbm
+		}
+		v, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return errInvalidNetpbm
+		}
+		*field = v
+	}
+}
+
+// readNetpbmInt reads a decimal number, skipping the whitespace and comments before it along with
+// the single whitespace character after it.
+func readNetpbmInt(r *bufio.Reader) (int, error) {
+	c, err := skipNetpbmSpace(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if c < '0' || c > '9' {
+		return 0, errInvalidNetpbm
+	}
+	v := 0
+	for c >= '0' && c <= '9' {
+		if v = v*10 + int(c-'0'); v > 0xFFFFFFFF {
+			return 0, errInvalidNetpbm
+		}
+		// The number may end with the data
+		if c, err = r.ReadByte(); err == io.EOF {
+			return v, nil
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if !isNetpbmSpace(c) {
+		return 0, errInvalidNetpbm
+	}
+	return v, nil
+}
+
+// skipNetpbmSpace returns the first byte that isn't whitespace or part of a comment.
+func skipNetpbmSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		if c == '#' {
+			if _, err := r.ReadString('\n'); err != nil {
+				return 0, unexpectedEOF(err)
+			}
+		} else if !isNetpbmSpace(c) {
+			return c, nil
+		}
+	}
+}
+
+func isNetpbmSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF in place of io.EOF, as the data ended before the image.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readNetpbmSamples reads the raster of the image, returning its samples without scaling them.
+// The samples are allocated as they are read, so that a header claiming a huge size doesn't
+// allocate more than the data holds.
+func readNetpbmSamples(r *bufio.Reader, h *netpbmHeader) ([]uint16, error) {
+	n := h.width * h.height * h.depth
+	samples := make([]uint16, 0, min(n, 1<<20))
+
+	switch h.magic {
+	case '1':
+		// Bits of plain PBM images don't need to be separated by whitespace
+		for len(samples) < n {
+			c, err := skipNetpbmSpace(r)
+			if err != nil {
+				return nil, err
+			}
+			if c != '0' && c != '1' {
+				return nil, errInvalidNetpbm
+			}
+			samples = append(samples, uint16(c-'0'))
+		}
+	case '2', '3':
+		for len(samples) < n {
+			v, err := readNetpbmInt(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if v > h.maxval {
+				return nil, errInvalidNetpbm
+			}
+			samples = append(samples, uint16(v))
+		}
+	case '4':
+		// Raw PBM rows are packed 8 pixels per byte, the most significant bit first
+		row := make([]byte, (h.width+7)/8)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(r, row); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			for x := 0; x < h.width; x++ {
+				samples = append(samples, uint16(row[x/8]>>(7-x%8))&1)
+			}
+		}
+	default:
+		size := 1
+		if h.maxval > 0xFF {
+			size = 2
+		}
+		raster, err := io.ReadAll(io.LimitReader(r, int64(n*size)))
+		if err != nil {
+			return nil, err
+		}
+		if len(raster) < n*size {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := 0; i < len(raster); i += size {
+			s := uint16(raster[i])
+			if size == 2 {
+				s = s<<8 | uint16(raster[i+1])
+			}
+			if int(s) > h.maxval {
+				return nil, errInvalidNetpbm
+			}
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
+
+// encodeNetpbm writes the image in the format of the magic number of its plain variant, or its raw
+// one unless ASCII is set in the options, or as a PAM image if the magic number is '7'.
+func encodeNetpbm(w io.Writer, img image.Image, magic byte, o *NetpbmOptions) error {
+	b := img.Bounds()
+	if b.Empty() {
+		return errInvalidNetpbm
+	}
+
+	maxval := 0xFF
+	if has16BitSamples(img) {
+		maxval = 0xFFFF
+	}
+
+	var depth int
+	var tupleType string
+	switch magic {
+	case '1':
+		depth, maxval = 1, 1
+	case '2':
+		depth = 1
+	case '3':
+		depth = 3
+	default:
+		switch img.(type) {
+		case *image.Gray, *image.Gray16:
+			depth, tupleType = 1, "GRAYSCALE"
+		default:
+			depth, tupleType = 4, "RGB_ALPHA"
+		}
+	}
+
+	samples := netpbmSamples(img, depth)
+	if tupleType == "RGB_ALPHA" && netpbmOpaque(samples) {
+		depth, tupleType = 3, "RGB"
+		for i := 0; i < len(samples)/4; i++ {
+			copy(samples[i*3:i*3+3], samples[i*4:i*4+3])
+		}
+		samples = samples[:len(samples)/4*3]
+	}
+
+	// Samples are scaled down from 16 bits, black being 1 in PBM images
+	for i, s := range samples {
+		switch maxval {
+		case 1:
+			samples[i] = 1 - s>>15
+		case 0xFF:
+			samples[i] = s >> 8
+		}
+	}
+
+	// The raw variants follow the plain ones
+	ascii := o != nil && o.ASCII && magic != '7'
+	if !ascii && magic != '7' {
+		magic += 3
+	}
+
+	bw := bufio.NewWriter(w)
+	switch magic {
+	case '7':
+		fmt.Fprintf(bw, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH %d\nMAXVAL %d\nTUPLTYPE %s\nENDHDR\n", b.Dx(), b.Dy(), depth, maxval, tupleType)
+	case '1', '4':
+		fmt.Fprintf(bw, "P%c\n%d %d\n", magic, b.Dx(), b.Dy())
+	default:
+		fmt.Fprintf(bw, "P%c\n%d %d\n%d\n", magic, b.Dx(), b.Dy(), maxval)
+	}
+
+	switch {
+	case magic == '4':
+		writePBMRaster(bw, samples, b.Dx())
+	case ascii:
+		writeNetpbmASCII(bw, samples, magic == '1')
+	default:
+		for _, s := range samples {
+			if maxval > 0xFF {
+				bw.WriteByte(uint8(s >> 8))
+			}
+			bw.WriteByte(uint8(s))
+		}
+	}
+	return bw.Flush()
+}
+
+// netpbmSamples returns the samples of the pixels of the image scaled to 16 bits, which are gray if
+// depth is 1, RGB if 3, and RGB with non-premultiplied alpha if 4.
+func netpbmSamples(img image.Image, depth int) []uint16 {
+	b := img.Bounds()
+	samples := make([]uint16, 0, b.Dx()*b.Dy()*depth)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.At(x, y)
+			switch depth {
+			case 1:
+				samples = append(samples, color.Gray16Model.Convert(c).(color.Gray16).Y)
+			case 3:
+				r, g, b, _ := c.RGBA()
+				samples = append(samples, uint16(r), uint16(g), uint16(b))
+			default:
+				n := toNRGBA64(c)
+				samples = append(samples, n.R, n.G, n.B, n.A)
+			}
+		}
+	}
+	return samples
+}
+
+// netpbmOpaque returns whether the alpha of all the RGBA samples is opaque.
+func netpbmOpaque(samples []uint16) bool {
+	for i := 3; i < len(samples); i += 4 {
+		if samples[i] != 0xFFFF {
+			return false
+		}
+	}
+	return true
+}
+
+// writePBMRaster writes the bits of the raw PBM image row by row, each row padded to a whole byte.
+func writePBMRaster(w *bufio.Writer, samples []uint16, width int) {
+	row := make([]byte, (width+7)/8)
+	for y := 0; y < len(samples)/width; y++ {
+		clear(row)
+		for x, s := range samples[y*width : (y+1)*width] {
+			row[x/8] |= uint8(s) << (7 - x%8)
+		}
+		w.Write(row)
+	}
+}
+
+// writeNetpbmASCII writes the samples as decimal numbers separated by spaces, with lines no longer
+// than 70 characters as recommended by the spec. The bits of PBM images aren't separated.
+func writeNetpbmASCII(w *bufio.Writer, samples []uint16, bits bool) {
+	const maxLine = 70
+	line := 0
+	for _, s := range samples {
+		v := strconv.Itoa(int(s))
+		if line > 0 && line+len(v)+1 > maxLine {
+			w.WriteByte('\n')
+			line = 0
+		} else if line > 0 && !bits {
+			w.WriteByte(' ')
+			line++
+		}
+		w.WriteString(v)
+		line += len(v)
+	}
+	w.WriteByte('\n')
+}
+
+// toNRGBA64 returns the color as non-premultiplied 16-bit RGBA. Unlike color.NRGBA64Model, it keeps
+// the exact channels of translucent color.NRGBA colors instead of rounding them through their
+// premultiplied values.
+func toNRGBA64(c color.Color) color.NRGBA64 {
+	if n, ok := c.(color.NRGBA); ok {
+		return color.NRGBA64{uint16(n.R) * 0x101, uint16(n.G) * 0x101, uint16(n.B) * 0x101, uint16(n.A) * 0x101}
+	}
+	return color.NRGBA64Model.Convert(c).(color.NRGBA64)
+}
+
+// has16BitSamples returns whether the color model of the image has 16 bits per channel.
+func has16BitSamples(img image.Image) bool {
+	model := img.ColorModel()
+	return model == color.RGBA64Model || model == color.NRGBA64Model || model == color.Gray16Model
+}
@@ -0,0 +1,235 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeNetpbm(t *testing.T) {
+	cases := []struct {
+		description string
+		value       string
+		format      string
+		expected    image.Image
+		err         error
+	}{
+		{
+			description: "plain pbm",
+			value:       "P1\n# comment\n3 2\n0 1 0\n110\n",
+			format:      "pbm",
+			expected:    &image.Gray{Rect: image.Rect(0, 0, 3, 2), Stride: 3, Pix: []uint8{0xFF, 0x00, 0xFF, 0x00, 0x00, 0xFF}},
+		},
+		{
+			description: "raw pbm",
+			value:       "P4 10 2\n\x80\x40\x00\xC0",
+			format:      "pbm",
+			expected: &image.Gray{Rect: image.Rect(0, 0, 10, 2), Stride: 10, Pix: []uint8{
+				0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00,
+				0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00,
+			}},
+		},
+		{
+			description: "plain pgm",
+			value:       "P2\n2 2\n15\n0 15\n# comment\n7 8",
+			format:      "pgm",
+			expected:    &image.Gray{Rect: image.Rect(0, 0, 2, 2), Stride: 2, Pix: []uint8{0x00, 0xFF, 0x77, 0x88}},
+		},
+		{
+			description: "raw 16-bit pgm",
+			value:       "P5 2 1 65535\n\x12\x34\xFF\xFF",
+			format:      "pgm",
+			expected:    &image.Gray16{Rect: image.Rect(0, 0, 2, 1), Stride: 4, Pix: []uint8{0x12, 0x34, 0xFF, 0xFF}},
+		},
+		{
+			description: "plain ppm",
+			value:       "P3\n2 1\n255\n255 0 0   0 128 255\n",
+			format:      "ppm",
+			expected:    &image.RGBA{Rect: image.Rect(0, 0, 2, 1), Stride: 8, Pix: []uint8{0xFF, 0x00, 0x00, 0xFF, 0x00, 0x80, 0xFF, 0xFF}},
+		},
+		{
+			description: "raw 16-bit ppm",
+			value:       "P6\n1 1\n1023\n\x03\xFF\x00\x00\x02\x00",
+			format:      "ppm",
+			expected:    &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0xFF, 0xFF, 0x00, 0x00, 0x80, 0x20, 0xFF, 0xFF}},
+		},
+		{
+			description: "pam grayscale alpha",
+			value:       "P7\nWIDTH 2\nHEIGHT 1\n# comment\nDEPTH 2\nMAXVAL 255\nTUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x10\x80\x20\xFF",
+			format:      "pam",
+			expected:    &image.NRGBA{Rect: image.Rect(0, 0, 2, 1), Stride: 8, Pix: []uint8{0x10, 0x10, 0x10, 0x80, 0x20, 0x20, 0x20, 0xFF}},
+		},
+		{
+			description: "pam black and white",
+			value:       "P7\nWIDTH 2\nHEIGHT 1\nDEPTH 1\nMAXVAL 1\nTUPLTYPE BLACKANDWHITE\nENDHDR\n\x00\x01",
+			format:      "pam",
+			expected:    &image.Gray{Rect: image.Rect(0, 0, 2, 1), Stride: 2, Pix: []uint8{0x00, 0xFF}},
+		},
+		{description: "sample above maxval", value: "P2 1 1 15 16", err: errInvalidNetpbm},
+		{description: "missing size", value: "P5 1\n", err: io.ErrUnexpectedEOF},
+		{description: "truncated raster", value: "P6 2 2 255\n\x00\x00\x00", err: io.ErrUnexpectedEOF},
+		{description: "zero size", value: "P5 0 1 255\n", err: errInvalidNetpbm},
+		{description: "unsupported depth", value: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 5\nMAXVAL 255\nENDHDR\n\x00\x00\x00\x00\x00", err: errNetpbmDepth},
+	}
+
+	for _, c := range cases {
+		actual, format, err := image.Decode(strings.NewReader(c.value))
+		if err != c.err {
+			t.Errorf("%s: expected: %v actual: %v", "Decode "+c.description, c.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if format != c.format {
+			t.Errorf("%s: expected: %v actual: %v", "Decode "+c.description, c.format, format)
+		}
+		if !imageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %#v actual: %#v", "Decode "+c.description, c.expected, actual)
+		}
+
+		config, _, err := image.DecodeConfig(strings.NewReader(c.value))
+		if err != nil || config.ColorModel != c.expected.ColorModel() || config.Width != c.expected.Bounds().Dx() {
+			t.Errorf("%s: expected: %v actual: %v %v", "DecodeConfig "+c.description, c.expected.Bounds(), config, err)
+		}
+	}
+}
+
+func TestNetpbmEncoders(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 13, 5))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 7)
+	}
+	gray16 := image.NewGray16(image.Rect(0, 0, 13, 5))
+	for i := range gray16.Pix {
+		gray16.Pix[i] = uint8(i * 13)
+	}
+	opaque := gradient(image.Rect(2, 3, 15, 8))
+	translucent := gradient(image.Rect(0, 0, 13, 5))
+	for i := 3; i < len(translucent.Pix); i += 4 {
+		translucent.Pix[i] = uint8(i * 3)
+	}
+	deep := image.NewRGBA64(image.Rect(0, 0, 13, 5))
+	for i := range deep.Pix {
+		deep.Pix[i] = uint8(i * 31)
+		if i%8 >= 6 {
+			deep.Pix[i] = 0xFF
+		}
+	}
+	bilevel := image.NewGray(image.Rect(0, 0, 13, 5))
+	for i := range bilevel.Pix {
+		bilevel.Pix[i] = uint8(i%3) * 0x7F
+	}
+	bilevelResult := image.NewGray(image.Rect(0, 0, 13, 5))
+	for i := range bilevelResult.Pix {
+		if i%3 == 2 {
+			bilevelResult.Pix[i] = 0xFF
+		}
+	}
+
+	cases := []struct {
+		description string
+		encoder     func(*NetpbmOptions) Encoder
+		value       image.Image
+		expected    image.Image
+		binary      bool
+	}{
+		{description: "pbm", encoder: PBMEncoder, value: bilevel, expected: bilevelResult},
+		{description: "pgm", encoder: PGMEncoder, value: gray, expected: gray},
+		{description: "pgm 16-bit", encoder: PGMEncoder, value: gray16, expected: gray16},
+		{description: "ppm", encoder: PPMEncoder, value: opaque, expected: rgba(opaque)},
+		{description: "ppm 16-bit", encoder: PPMEncoder, value: deep, expected: deep},
+		{description: "pam gray", encoder: func(*NetpbmOptions) Encoder { return PAMEncoder() }, value: gray16, expected: gray16, binary: true},
+		{description: "pam opaque", encoder: func(*NetpbmOptions) Encoder { return PAMEncoder() }, value: opaque, expected: rgba(opaque), binary: true},
+		{description: "pam translucent", encoder: func(*NetpbmOptions) Encoder { return PAMEncoder() }, value: translucent, expected: translucent, binary: true},
+	}
+
+	for _, c := range cases {
+		for _, o := range []*NetpbmOptions{nil, {ASCII: true}} {
+			if o != nil && c.binary {
+				continue
+			}
+			description := c.description
+			if o != nil {
+				description += " ascii"
+			}
+
+			var buf bytes.Buffer
+			if err := c.encoder(o)(&buf, c.value); err != nil {
+				t.Fatalf("%s: %v", "Encoder "+description, err)
+			}
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if o != nil && len(line) > 70 {
+					t.Errorf("%s: line longer than 70 characters: %q", "Encoder "+description, line)
+				}
+			}
+
+			actual, _, err := image.Decode(&buf)
+			if err != nil {
+				t.Fatalf("%s: %v", "Encoder "+description, err)
+			}
+			if !imageEqual(actual, c.expected) {
+				t.Errorf("%s: expected: %#v actual: %#v", "Encoder "+description, c.expected, actual)
+			}
+		}
+	}
+}
+
+func TestNetpbmEncoderOutput(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	copy(img.Pix, []uint8{0x00, 0xFF, 0x00, 0x80, 0x7F, 0xFF})
+
+	cases := []struct {
+		description string
+		encoder     Encoder
+		expected    string
+	}{
+		{description: "plain pbm", encoder: PBMEncoder(&NetpbmOptions{ASCII: true}), expected: "P1\n3 2\n101010\n"},
+		{description: "raw pbm", encoder: PBMEncoder(nil), expected: "P4\n3 2\n\xA0\x40"},
+		{description: "plain pgm", encoder: PGMEncoder(&NetpbmOptions{ASCII: true}), expected: "P2\n3 2\n255\n0 255 0 128 127 255\n"},
+		{description: "raw pgm", encoder: PGMEncoder(nil), expected: "P5\n3 2\n255\n\x00\xFF\x00\x80\x7F\xFF"},
+		{description: "pam", encoder: PAMEncoder(), expected: "P7\nWIDTH 3\nHEIGHT 2\nDEPTH 1\nMAXVAL 255\nTUPLTYPE GRAYSCALE\nENDHDR\n\x00\xFF\x00\x80\x7F\xFF"},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := c.encoder(&buf, img); err != nil {
+			t.Fatalf("%s: %v", "Encoder "+c.description, err)
+		}
+		if actual := buf.String(); actual != c.expected {
+			t.Errorf("%s: expected: %q actual: %q", "Encoder "+c.description, c.expected, actual)
+		}
+	}
+}
+
+// imageEqual returns whether the images have the same bounds and colors in the color model of a.
+func imageEqual(a, b image.Image) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+	ba, bb := a.Bounds(), b.Bounds()
+	for y := 0; y < ba.Dy(); y++ {
+		for x := 0; x < ba.Dx(); x++ {
+			expected := a.ColorModel().Convert(b.At(bb.Min.X+x, bb.Min.Y+y))
+			if actual := a.At(ba.Min.X+x, ba.Min.Y+y); actual != expected {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rgba returns the image converted to an *image.RGBA.
+func rgba(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	result := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			result.Set(x, y, color.RGBAModel.Convert(img.At(x, y)))
+		}
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package imgio
+
+import (
+	"image"
+	"image/png"
+	"io"
+)
+
+// PNGOptions are the PNG encoding parameters.
+// CompressionLevel trades encoding speed for file size, png.DefaultCompression being used if zero.
+type PNGOptions struct {
+	CompressionLevel png.CompressionLevel
+}
+
+// PNGEncoderWithOptions returns an encoder to PNG with the provided options.
+// Default parameters are used if a nil *PNGOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.png", img, imgio.PNGEncoderWithOptions(&imgio.PNGOptions{CompressionLevel: png.BestCompression}))
+func PNGEncoderWithOptions(o *PNGOptions) Encoder {
+	encoder := &png.Encoder{}
+	if o != nil {
+		encoder.CompressionLevel = o.CompressionLevel
+	}
+
+	return func(w io.Writer, img image.Image) error {
+		return encoder.Encode(w, img)
+	}
+}
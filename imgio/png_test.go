@@ -0,0 +1,96 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestPNGEncoderWithOptions(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i / 64)
+		if i%4 == 3 {
+			img.Pix[i] = 0xFF
+		}
+	}
+
+	cases := []struct {
+		description string
+		options     *PNGOptions
+	}{
+		{description: "default", options: nil},
+		{description: "none", options: &PNGOptions{CompressionLevel: png.NoCompression}},
+		{description: "best", options: &PNGOptions{CompressionLevel: png.BestCompression}},
+	}
+
+	sizes := map[string]int{}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := PNGEncoderWithOptions(c.options)(&buf, img); err != nil {
+			t.Fatalf("%s: %v", "PNGEncoderWithOptions "+c.description, err)
+		}
+		sizes[c.description] = buf.Len()
+
+		decoded, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", "PNGEncoderWithOptions "+c.description, err)
+		}
+		if actual, ok := decoded.(*image.RGBA); !ok || !util.RGBAImageEqual(actual, img) {
+			t.Errorf("%s: expected the image to be preserved", "PNGEncoderWithOptions "+c.description)
+		}
+	}
+
+	if sizes["none"] <= sizes["best"] {
+		t.Errorf("PNGEncoderWithOptions: expected no compression to be larger, actual sizes: %v", sizes)
+	}
+}
+
+func TestPNG16Bit(t *testing.T) {
+	rgba64 := image.NewRGBA64(image.Rect(0, 0, 16, 16))
+	nrgba64 := image.NewNRGBA64(image.Rect(0, 0, 16, 16))
+	gray16 := image.NewGray16(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			v := uint16(0x8000 + y*16 + x)
+			rgba64.SetRGBA64(x, y, color.RGBA64{v, v + 1, v - 1, 0xFFFF})
+			nrgba64.SetNRGBA64(x, y, color.NRGBA64{v, v + 1, v - 1, v + 3})
+			gray16.SetGray16(x, y, color.Gray16{v})
+		}
+	}
+
+	cases := []struct {
+		description string
+		value       image.Image
+	}{
+		{description: "RGBA64", value: rgba64},
+		{description: "NRGBA64", value: nrgba64},
+		{description: "Gray16", value: gray16},
+	}
+
+	filename := filepath.Join(t.TempDir(), "16bit.png")
+	for _, c := range cases {
+		if err := Save(filename, c.value, PNGEncoder()); err != nil {
+			t.Fatalf("%s: %v", "PNG16Bit "+c.description, err)
+		}
+		decoded, err := Open(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", "PNG16Bit "+c.description, err)
+		}
+
+		b := c.value.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				expected := color.NRGBA64Model.Convert(c.value.At(x, y))
+				if actual := color.NRGBA64Model.Convert(decoded.At(x, y)); actual != expected {
+					t.Fatalf("%s: expected: %v actual: %v", "PNG16Bit "+c.description, expected, actual)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,499 @@
+package imgio
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+
+	"golang.org/x/image/tiff"
+)
+
+// TIFFCompression is the compression scheme of the pixel data of a TIFF image.
+type TIFFCompression int
+
+// Supported TIFF compression schemes
+const (
+	TIFFUncompressed TIFFCompression = iota
+	TIFFLZW
+	TIFFDeflate
+)
+
+// TIFFOptions are the TIFF encoding parameters.
+// Compression defaults to TIFFUncompressed.
+// Predictor set to true stores the difference of each sample to the one on its left, which compresses
+// better with TIFFLZW and TIFFDeflate.
+type TIFFOptions struct {
+	Compression TIFFCompression
+	Predictor   bool
+}
+
+// ErrNoPages is returned when encoding a TIFF document without pages.
+var ErrNoPages = errors.New("imgio: document has no pages")
+
+// TIFF tags, field types and values used by the encoder, specified in the TIFF 6.0 spec.
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffPhotometric     = 262
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffXResolution     = 282
+	tiffYResolution     = 283
+	tiffPlanarConfig    = 284
+	tiffResolutionUnit  = 296
+	tiffPageNumber      = 297
+	tiffPredictor       = 317
+	tiffExtraSamples    = 338
+
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+
+	tiffBlackIsZero = 1
+	tiffRGB         = 2
+)
+
+// TIFFEncoder returns an encoder to TIFF with the provided options.
+// Default parameters are used if a nil *TIFFOptions is passed.
+// *image.Gray and *image.Gray16 images are stored as grayscale, others as RGB with an alpha channel if
+// they aren't opaque. Images with 16-bit color models are stored with 16 bits per sample.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.tif", img, imgio.TIFFEncoder(&imgio.TIFFOptions{Compression: imgio.TIFFLZW, Predictor: true}))
+func TIFFEncoder(o *TIFFOptions) Encoder {
+	return func(w io.Writer, img image.Image) error {
+		return EncodeTIFFPages(w, []image.Image{img}, o)
+	}
+}
+
+// OpenTIFFPages loads and decodes all the pages of a TIFF file and returns them.
+//
+// Usage example:
+//
+//	pages, err := imgio.OpenTIFFPages("scan.tif")
+func OpenTIFFPages(filename string) ([]image.Image, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeTIFFPages(f)
+}
+
+// DecodeTIFFPages decodes all the pages of a TIFF document from the reader and returns them.
+//
+// Usage example:
+//
+//	pages, err := imgio.DecodeTIFFPages(resp.Body)
+func DecodeTIFFPages(r io.Reader) ([]image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	order, offsets, err := tiffPageOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]image.Image, 0, len(offsets))
+	for _, offset := range offsets {
+		// The decoder reads the first page, so the header is changed to point at each of them in turn
+		page := &tiffPage{data: data}
+		copy(page.header[:], data[:4])
+		order.PutUint32(page.header[4:], offset)
+
+		img, err := tiff.Decode(page)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, img)
+	}
+	return pages, nil
+}
+
+// SaveTIFFPages creates a file and writes to it the pages as a TIFF document with the provided options.
+// Default parameters are used if a nil *TIFFOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.SaveTIFFPages("output.tif", pages, &imgio.TIFFOptions{Compression: imgio.TIFFDeflate})
+func SaveTIFFPages(filename string, pages []image.Image, o *TIFFOptions) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return EncodeTIFFPages(f, pages, o)
+}
+
+// EncodeTIFFPages writes the pages to w as a TIFF document with the provided options.
+// Default parameters are used if a nil *TIFFOptions is passed.
+//
+// Usage example:
+//
+//	err := imgio.EncodeTIFFPages(os.Stdout, pages, nil)
+func EncodeTIFFPages(w io.Writer, pages []image.Image, o *TIFFOptions) error {
+	if len(pages) == 0 {
+		return ErrNoPages
+	}
+	options := TIFFOptions{}
+	if o != nil {
+		options = *o
+	}
+
+	// Little endian header, the offset of the first page is patched below
+	data := []byte("II\x2A\x00\x00\x00\x00\x00")
+	nextOffset := 4
+	for i, img := range pages {
+		pix, samples, bits, photometric := tiffPixels(img)
+		b := img.Bounds()
+		if uint64(len(pix)) > 0xFFFFFFFF {
+			return errors.New("imgio: image is too large to encode as TIFF")
+		}
+
+		if options.Predictor {
+			tiffPredict(pix, b.Dx(), samples, bits)
+		}
+		strip, compression, err := tiffCompress(pix, options.Compression)
+		if err != nil {
+			return err
+		}
+
+		stripOffset := len(data)
+		data = append(data, strip...)
+		if len(data)&1 != 0 {
+			data = append(data, 0)
+		}
+
+		bitsPerSample := make([]uint32, samples)
+		for s := range bitsPerSample {
+			bitsPerSample[s] = uint32(bits)
+		}
+		predictor := uint32(1)
+		if options.Predictor {
+			predictor = 2
+		}
+		entries := []tiffEntry{
+			{tiffImageWidth, tiffLong, []uint32{uint32(b.Dx())}},
+			{tiffImageLength, tiffLong, []uint32{uint32(b.Dy())}},
+			{tiffBitsPerSample, tiffShort, bitsPerSample},
+			{tiffCompression, tiffShort, []uint32{compression}},
+			{tiffPhotometric, tiffShort, []uint32{photometric}},
+			{tiffStripOffsets, tiffLong, []uint32{uint32(stripOffset)}},
+			{tiffSamplesPerPixel, tiffShort, []uint32{uint32(samples)}},
+			{tiffRowsPerStrip, tiffLong, []uint32{uint32(b.Dy())}},
+			{tiffStripByteCounts, tiffLong, []uint32{uint32(len(strip))}},
+			{tiffXResolution, tiffRational, []uint32{72, 1}},
+			{tiffYResolution, tiffRational, []uint32{72, 1}},
+			{tiffPlanarConfig, tiffShort, []uint32{1}},
+			// Inches
+			{tiffResolutionUnit, tiffShort, []uint32{2}},
+			{tiffPageNumber, tiffShort, []uint32{uint32(i), uint32(len(pages))}},
+			{tiffPredictor, tiffShort, []uint32{predictor}},
+		}
+		if samples == 4 {
+			// Unassociated alpha
+			entries = append(entries, tiffEntry{tiffExtraSamples, tiffShort, []uint32{2}})
+		}
+
+		binary.LittleEndian.PutUint32(data[nextOffset:], uint32(len(data)))
+		data, nextOffset = appendTIFFIFD(data, entries)
+		if uint64(len(data)) > 0xFFFFFFFF {
+			return errors.New("imgio: document is too large to encode as TIFF")
+		}
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// tiffEntry is a field of an image file directory, its values being pairs of numerator and
+// denominator for the rational type.
+type tiffEntry struct {
+	tag, typ uint16
+	values   []uint32
+}
+
+// appendTIFFIFD appends the image file directory with the entries, which must be sorted by tag,
+// followed by the values that don't fit in them. It returns the data along with the position of the offset of the
+// next directory.
+func appendTIFFIFD(data []byte, entries []tiffEntry) ([]byte, int) {
+	start := len(data)
+	extra := start + 2 + 12*len(entries) + 4
+
+	var values []byte
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(entries)))
+	for _, e := range entries {
+		var payload []byte
+		count := len(e.values)
+		for _, v := range e.values {
+			if e.typ == tiffShort {
+				payload = binary.LittleEndian.AppendUint16(payload, uint16(v))
+			} else {
+				payload = binary.LittleEndian.AppendUint32(payload, v)
+			}
+		}
+		if e.typ == tiffRational {
+			count /= 2
+		}
+
+		data = binary.LittleEndian.AppendUint16(data, e.tag)
+		data = binary.LittleEndian.AppendUint16(data, e.typ)
+		data = binary.LittleEndian.AppendUint32(data, uint32(count))
+		if len(payload) <= 4 {
+			data = append(data, payload...)
+			data = append(data, make([]byte, 4-len(payload))...)
+		} else {
+			data = binary.LittleEndian.AppendUint32(data, uint32(extra+len(values)))
+			values = append(values, payload...)
+		}
+	}
+
+	next := len(data)
+	data = append(data, 0, 0, 0, 0)
+	data = append(data, values...)
+	if len(data)&1 != 0 {
+		data = append(data, 0)
+	}
+	return data, next
+}
+
+// tiffPixels returns the samples of the image in little endian byte order, along with the number of
+// samples per pixel, the bits per sample and the photometric interpretation.
+func tiffPixels(img image.Image) ([]byte, int, int, uint32) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	switch src := img.(type) {
+	case *image.Gray:
+		pix := make([]byte, 0, w*h)
+		for y := 0; y < h; y++ {
+			pix = append(pix, src.Pix[y*src.Stride:y*src.Stride+w]...)
+		}
+		return pix, 1, 8, tiffBlackIsZero
+	case *image.Gray16:
+		pix := make([]byte, 0, w*h*2)
+		for y := 0; y < h; y++ {
+			row := src.Pix[y*src.Stride : y*src.Stride+w*2]
+			for x := 0; x < len(row); x += 2 {
+				pix = append(pix, row[x+1], row[x])
+			}
+		}
+		return pix, 1, 16, tiffBlackIsZero
+	}
+
+	if has16BitSamples(img) {
+		src := image.NewNRGBA64(image.Rect(0, 0, w, h))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+		samples := tiffSamples(src.Pix, 8, 7)
+		pix := make([]byte, 0, w*h*samples*2)
+		for i := 0; i < len(src.Pix); i += 8 {
+			for s := 0; s < samples; s++ {
+				pix = append(pix, src.Pix[i+s*2+1], src.Pix[i+s*2])
+			}
+		}
+		return pix, samples, 16, tiffRGB
+	}
+
+	src := toNRGBA(img)
+	samples := tiffSamples(src.Pix, 4, 3)
+	pix := make([]byte, 0, w*h*samples)
+	for i := 0; i < len(src.Pix); i += 4 {
+		pix = append(pix, src.Pix[i:i+samples]...)
+	}
+	return pix, samples, 8, tiffRGB
+}
+
+// tiffSamples returns 3 if all the pixels of size n are opaque, their alpha being at offset alpha,
+// or 4 otherwise.
+func tiffSamples(pix []byte, n, alpha int) int {
+	for i := alpha; i < len(pix); i += n {
+		if pix[i] != 0xFF {
+			return 4
+		}
+	}
+	return 3
+}
+
+// tiffPredict replaces each sample of the rows of pixels by its difference to the same sample of the
+// pixel on its left, as specified in section 14 of the TIFF spec.
+func tiffPredict(pix []byte, width, samples, bits int) {
+	bytesPerSample := bits / 8
+	rowBytes, pixelBytes := width*samples*bytesPerSample, samples*bytesPerSample
+	if rowBytes == 0 {
+		return
+	}
+
+	for row := 0; row+rowBytes <= len(pix); row += rowBytes {
+		// Going backwards keeps the samples on the left unchanged until they're subtracted
+		for i := row + rowBytes - bytesPerSample; i >= row+pixelBytes; i -= bytesPerSample {
+			if bytesPerSample == 2 {
+				v := binary.LittleEndian.Uint16(pix[i:]) - binary.LittleEndian.Uint16(pix[i-pixelBytes:])
+				binary.LittleEndian.PutUint16(pix[i:], v)
+			} else {
+				pix[i] -= pix[i-pixelBytes]
+			}
+		}
+	}
+}
+
+// tiffCompress compresses the pixel data, returning it along with the value of the compression field.
+func tiffCompress(pix []byte, compression TIFFCompression) ([]byte, uint32, error) {
+	switch compression {
+	case TIFFLZW:
+		return tiffLZW(pix), 5, nil
+	case TIFFDeflate:
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(pix); err != nil {
+			return nil, 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, 0, err
+		}
+		return buf.Bytes(), 8, nil
+	}
+	return pix, 1, nil
+}
+
+// tiffPage is a TIFF document with its header replaced, which selects the page read by the decoder.
+type tiffPage struct {
+	data   []byte
+	header [8]byte
+}
+
+func (p *tiffPage) Read(b []byte) (int, error) {
+	return 0, errors.New("imgio: tiffPage is only read at offsets")
+}
+
+func (p *tiffPage) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(p.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, p.data[off:])
+	if off < int64(len(p.header)) {
+		copy(b, p.header[off:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// tiffPageOffsets returns the byte order of the document and the offsets of the image file
+// directories of its pages.
+func tiffPageOffsets(data []byte) (binary.ByteOrder, []uint32, error) {
+	if len(data) < 8 {
+		return nil, nil, tiff.FormatError("malformed header")
+	}
+	var order binary.ByteOrder
+	switch string(data[:4]) {
+	case "II\x2A\x00":
+		order = binary.LittleEndian
+	case "MM\x00\x2A":
+		order = binary.BigEndian
+	default:
+		return nil, nil, tiff.FormatError("malformed header")
+	}
+
+	var offsets []uint32
+	seen := map[uint32]bool{}
+	for offset := order.Uint32(data[4:]); offset != 0; {
+		// Stop at a loop in the chain of directories
+		if seen[offset] || uint64(offset)+2 > uint64(len(data)) {
+			break
+		}
+		seen[offset] = true
+		offsets = append(offsets, offset)
+
+		next := uint64(offset) + 2 + 12*uint64(order.Uint16(data[offset:]))
+		if next+4 > uint64(len(data)) {
+			break
+		}
+		offset = order.Uint32(data[next:])
+	}
+
+	if len(offsets) == 0 {
+		return nil, nil, tiff.FormatError("no pages")
+	}
+	return order, offsets, nil
+}
+
+// tiffLZW compresses the data with the LZW variant of TIFF, which writes the codes most significant
+// bit first and widens them one code earlier than GIF.
+func tiffLZW(data []byte) []byte {
+	const (
+		clearCode = 256
+		eoi       = 257
+		maxCode   = 4094
+	)
+
+	var out []byte
+	var bits uint32
+	var nBits uint
+	width := uint(9)
+	write := func(code uint32) {
+		bits |= code << (32 - width - nBits)
+		nBits += width
+		for nBits >= 8 {
+			out = append(out, byte(bits>>24))
+			bits <<= 8
+			nBits -= 8
+		}
+	}
+
+	// The table maps a code and the byte following it to the code of the sequence
+	table := make(map[uint32]uint32)
+	hi := uint32(eoi)
+	// next accounts for a code being written, widening the codes when the decoder will
+	next := func() {
+		hi++
+		if hi == maxCode {
+			write(clearCode)
+			width, hi = 9, eoi
+			clear(table)
+			return
+		}
+		if hi+1 == 1<<width {
+			width++
+		}
+	}
+
+	write(clearCode)
+	if len(data) > 0 {
+		code := uint32(data[0])
+		for _, c := range data[1:] {
+			key := code<<8 | uint32(c)
+			if v, ok := table[key]; ok {
+				code = v
+				continue
+			}
+			write(code)
+			next()
+			if hi != eoi {
+				table[key] = hi
+			}
+			code = uint32(c)
+		}
+		write(code)
+		next()
+	}
+	write(eoi)
+	if nBits > 0 {
+		out = append(out, byte(bits>>24))
+	}
+	return out
+}
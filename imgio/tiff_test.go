@@ -0,0 +1,178 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/tiff/lzw"
+)
+
+func TestTIFFEncoder(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 17, 5))
+	gray16 := image.NewGray16(image.Rect(0, 0, 17, 5))
+	opaque := image.NewRGBA(image.Rect(0, 0, 17, 5))
+	translucent := gradient(image.Rect(0, 0, 17, 5))
+	deep := image.NewNRGBA64(image.Rect(0, 0, 17, 5))
+	deepOpaque := image.NewRGBA64(image.Rect(-3, 2, 14, 7))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 7)
+	}
+	for i := range gray16.Pix {
+		gray16.Pix[i] = uint8(i * 13)
+	}
+	for i := range opaque.Pix {
+		opaque.Pix[i] = uint8(i * 5)
+		if i%4 == 3 {
+			opaque.Pix[i] = 0xFF
+		}
+	}
+	for i := 3; i < len(translucent.Pix); i += 4 {
+		translucent.Pix[i] = uint8(i * 3)
+	}
+	for i := range deep.Pix {
+		deep.Pix[i] = uint8(i * 31)
+	}
+	for i := range deepOpaque.Pix {
+		deepOpaque.Pix[i] = uint8(i * 11)
+		if i%8 >= 6 {
+			deepOpaque.Pix[i] = 0xFF
+		}
+	}
+
+	images := []struct {
+		description string
+		value       image.Image
+		model       color.Model
+	}{
+		{description: "gray", value: gray, model: color.GrayModel},
+		{description: "gray16", value: gray16, model: color.Gray16Model},
+		{description: "opaque", value: opaque, model: color.RGBAModel},
+		{description: "translucent", value: translucent, model: color.NRGBAModel},
+		{description: "16-bit translucent", value: deep, model: color.NRGBA64Model},
+		{description: "16-bit opaque", value: deepOpaque, model: color.RGBA64Model},
+	}
+	options := []struct {
+		description string
+		value       *TIFFOptions
+	}{
+		{description: "default", value: nil},
+		{description: "lzw", value: &TIFFOptions{Compression: TIFFLZW}},
+		{description: "deflate", value: &TIFFOptions{Compression: TIFFDeflate}},
+		{description: "lzw predictor", value: &TIFFOptions{Compression: TIFFLZW, Predictor: true}},
+		{description: "deflate predictor", value: &TIFFOptions{Compression: TIFFDeflate, Predictor: true}},
+	}
+
+	for _, img := range images {
+		for _, o := range options {
+			description := "TIFFEncoder " + img.description + " " + o.description
+			var buf bytes.Buffer
+			if err := TIFFEncoder(o.value)(&buf, img.value); err != nil {
+				t.Fatalf("%s: %v", description, err)
+			}
+
+			decoded, err := tiff.Decode(&buf)
+			if err != nil {
+				t.Fatalf("%s: %v", description, err)
+			}
+			if decoded.ColorModel() != img.model {
+				t.Errorf("%s: expected: %T actual: %T", description, img.model.Convert(color.Black), decoded.ColorModel().Convert(color.Black))
+			}
+
+			b := img.value.Bounds()
+			if decoded.Bounds() != image.Rect(0, 0, b.Dx(), b.Dy()) {
+				t.Fatalf("%s: expected: %v actual: %v", description, b, decoded.Bounds())
+			}
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					expected := img.model.Convert(img.value.At(b.Min.X+x, b.Min.Y+y))
+					if actual := decoded.At(x, y); actual != expected {
+						t.Fatalf("%s: expected: %v actual: %v at %d, %d", description, expected, actual, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTIFFLZW(t *testing.T) {
+	random := make([]byte, 100000)
+	seed := uint32(1)
+	for i := range random {
+		seed = seed*1664525 + 1013904223
+		random[i] = byte(seed >> 24)
+	}
+
+	cases := []struct {
+		description string
+		value       []byte
+	}{
+		{description: "empty", value: []byte{}},
+		{description: "single", value: []byte{42}},
+		{description: "repetitive", value: bytes.Repeat([]byte("abcabcabd"), 10000)},
+		{description: "random", value: random},
+	}
+
+	for _, c := range cases {
+		actual, err := io.ReadAll(lzw.NewReader(bytes.NewReader(tiffLZW(c.value)), lzw.MSB, 8))
+		if err != nil {
+			t.Errorf("%s: %v", "tiffLZW "+c.description, err)
+			continue
+		}
+		if !bytes.Equal(actual, c.value) {
+			t.Errorf("%s: decoded %d bytes different from the %d encoded", "tiffLZW "+c.description, len(actual), len(c.value))
+		}
+	}
+}
+
+func TestTIFFPages(t *testing.T) {
+	first := gradient(image.Rect(0, 0, 8, 4))
+	second := image.NewGray16(image.Rect(0, 0, 3, 6))
+	for i := range second.Pix {
+		second.Pix[i] = uint8(i * 17)
+	}
+	third := image.NewGray(image.Rect(0, 0, 1, 1))
+	third.Pix[0] = 0x80
+	pages := []image.Image{first, second, third}
+
+	for _, o := range []*TIFFOptions{nil, {Compression: TIFFLZW, Predictor: true}} {
+		var buf bytes.Buffer
+		if err := EncodeTIFFPages(&buf, pages, o); err != nil {
+			t.Fatalf("%s: %v", "EncodeTIFFPages", err)
+		}
+
+		// Other decoders only read the first page
+		data := buf.Bytes()
+		img, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil || format != "tiff" || img.Bounds() != first.Bounds() {
+			t.Errorf("%s: expected: %v actual: %v %v %v", "Decode first page", first.Bounds(), img, format, err)
+		}
+
+		decoded, err := DecodeTIFFPages(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", "DecodeTIFFPages", err)
+		}
+		if len(decoded) != len(pages) {
+			t.Fatalf("%s: expected: %d pages actual: %d", "DecodeTIFFPages", len(pages), len(decoded))
+		}
+		for i, page := range pages {
+			if decoded[i].Bounds() != page.Bounds() {
+				t.Errorf("%s: expected: %v actual: %v", "DecodeTIFFPages page", page.Bounds(), decoded[i].Bounds())
+				continue
+			}
+			if diff := meanAbsDiff(page, decoded[i]); diff != 0 {
+				t.Errorf("%s %d: expected no difference, actual: %.2f", "DecodeTIFFPages page", i, diff)
+			}
+		}
+	}
+
+	if err := EncodeTIFFPages(io.Discard, nil, nil); err != ErrNoPages {
+		t.Errorf("%s: expected: %v actual: %v", "EncodeTIFFPages", ErrNoPages, err)
+	}
+	if _, err := DecodeTIFFPages(bytes.NewReader([]byte("II*\x00"))); err == nil {
+		t.Errorf("%s: expected an error", "DecodeTIFFPages")
+	}
+}
@@ -0,0 +1,809 @@
+package imgio
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// This file implements a lossy VP8 key frame encoder, as specified in RFC 6386, which is the
+// bitstream of lossy WebP images. Every macroblock is predicted as a whole, choosing the 16x16
+// luma and 8x8 chroma mode with the lowest error, and all the coefficients are coded in a single
+// token partition.
+
+// Intra prediction modes of the macroblocks, specified in section 12.2.
+const (
+	vp8PredDC = iota
+	vp8PredV
+	vp8PredH
+	vp8PredTM
+)
+
+// Planes of the token probabilities, specified in section 13.3.
+const (
+	vp8PlaneY1WithY2 = iota
+	vp8PlaneY2
+	vp8PlaneUV
+	vp8PlaneY1SansY2
+)
+
+// vp8Bands maps the position of a coefficient to its band, with an extra entry for the position
+// past the last coefficient.
+var vp8Bands = [17]int{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+
+// vp8Zigzag maps the zig-zag order of the coefficients of a block to their natural order.
+var vp8Zigzag = [16]int{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+
+// vp8Cat3456 are the probabilities of the extra bits of the DCT_CAT3 to DCT_CAT6 tokens.
+var vp8Cat3456 = [4][]uint8{
+	{173, 148, 140},
+	{176, 155, 140, 135},
+	{180, 157, 141, 134, 130},
+	{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+}
+
+// vp8MaxLevel is the largest magnitude of a quantized coefficient, coded by a DCT_CAT6 token.
+const vp8MaxLevel = 2048
+
+var errVP8TooLarge = errors.New("imgio: image is too large to encode as lossy WebP")
+
+// vp8BoolEncoder is the boolean entropy encoder specified in section 7.3.
+type vp8BoolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func newVP8BoolEncoder() *vp8BoolEncoder {
+	return &vp8BoolEncoder{rng: 255, bitCount: 24}
+}
+
+// writeBool writes a bit, prob being the probability of it being false out of 256.
+func (e *vp8BoolEncoder) writeBool(prob uint8, bit bool) {
+	split := 1 + ((e.rng - 1) * uint32(prob) >> 8)
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			// Propagate the carry to the bytes already written
+			i := len(e.buf) - 1
+			for ; i >= 0 && e.buf[i] == 0xFF; i-- {
+				e.buf[i] = 0
+			}
+			if i >= 0 {
+				e.buf[i]++
+			}
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// writeLiteral writes the n lowest bits of v with an even probability, most significant first.
+func (e *vp8BoolEncoder) writeLiteral(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.writeBool(128, v>>uint(i)&1 != 0)
+	}
+}
+
+// flush pads the data so that the decoder can read past its last bit, and returns it.
+func (e *vp8BoolEncoder) flush() []byte {
+	for i := 0; i < 32; i++ {
+		e.writeBool(128, false)
+	}
+	return e.buf
+}
+
+// vp8Plane is a color plane of the image padded to whole macroblocks, along with its
+// reconstruction as seen by the decoder, which is used for prediction.
+type vp8Plane struct {
+	src, rec []uint8
+	stride   int
+}
+
+// vp8Macroblock holds the modes and quantized coefficients of a macroblock. The coefficients are
+// in zig-zag order, for the 16 luma blocks, the 4 Cb and 4 Cr blocks and the Y2 block.
+type vp8Macroblock struct {
+	ymode, uvmode int
+	skip          bool
+	coeffs        [25][16]int32
+}
+
+// vp8Encoder encodes a key frame.
+type vp8Encoder struct {
+	mbw, mbh int
+	y, u, v  vp8Plane
+	// Quantization factors of the DC and AC coefficients
+	y1q, y2q, uvq [2]int32
+	mbs           []vp8Macroblock
+}
+
+// vp8QualityToIndex maps a quality from 1 to 100 to a quantizer index, in a similar way as libwebp.
+func vp8QualityToIndex(quality int) int {
+	c := float64(clampInt(quality, 0, 100)) / 100
+	linear := 2*c - 1
+	if c < 0.75 {
+		linear = c * 2 / 3
+	}
+	return clampInt(int(math.Round(127*(1-math.Cbrt(linear)))), 0, 127)
+}
+
+// encodeVP8 returns the VP8 bitstream of the image with the provided quality.
+func encodeVP8(img image.Image, quality int) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, errors.New("imgio: image is empty")
+	}
+	if w > 1<<14-1 || h > 1<<14-1 {
+		return nil, errVP8TooLarge
+	}
+
+	qi := vp8QualityToIndex(quality)
+	e := &vp8Encoder{mbw: (w + 15) / 16, mbh: (h + 15) / 16}
+	e.y1q = [2]int32{vp8DequantDC[qi], vp8DequantAC[qi]}
+	e.y2q = [2]int32{vp8DequantDC[qi] * 2, max(vp8DequantAC[qi]*155/100, 8)}
+	e.uvq = [2]int32{vp8DequantDC[min(qi, 117)], vp8DequantAC[qi]}
+	e.convert(img)
+
+	e.mbs = make([]vp8Macroblock, e.mbw*e.mbh)
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.mbs[mby*e.mbw+mbx]
+			e.encodeLuma(mb, mbx, mby)
+			e.encodeChroma(mb, mbx, mby)
+			mb.skip = true
+			for i := range mb.coeffs {
+				for _, level := range mb.coeffs[i] {
+					mb.skip = mb.skip && level == 0
+				}
+			}
+		}
+	}
+
+	// Gather the token statistics to adapt the probabilities to the image
+	var stats [4][8][3][11][2]uint32
+	skipped := 0
+	for _, mb := range e.mbs {
+		if mb.skip {
+			skipped++
+		}
+	}
+	useSkip := skipped > 0
+	(&vp8TokenCoder{stats: &stats}).macroblocks(e.mbs, e.mbw, useSkip)
+
+	fp := newVP8BoolEncoder()
+	// Color space and clamping type
+	fp.writeLiteral(0, 2)
+	// No segmentation
+	fp.writeBool(128, false)
+	// Normal loop filter, with a level growing with the quantizer, sharpness and no deltas
+	fp.writeBool(128, false)
+	fp.writeLiteral(uint32(qi/2), 6)
+	fp.writeLiteral(0, 3)
+	fp.writeBool(128, false)
+	// A single token partition
+	fp.writeLiteral(0, 2)
+	// Quantizer index without deltas
+	fp.writeLiteral(uint32(qi), 7)
+	for i := 0; i < 5; i++ {
+		fp.writeBool(128, false)
+	}
+	// Refresh entropy probabilities
+	fp.writeBool(128, false)
+
+	probs := vp8DefaultTokenProb
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l := range probs[i][j][k] {
+					update := vp8TokenProbUpdateProb[i][j][k][l]
+					p, ok := vp8UpdatedProb(probs[i][j][k][l], update, stats[i][j][k][l])
+					fp.writeBool(update, ok)
+					if ok {
+						fp.writeLiteral(uint32(p), 8)
+						probs[i][j][k][l] = p
+					}
+				}
+			}
+		}
+	}
+
+	var skipProb uint8
+	fp.writeBool(128, useSkip)
+	if useSkip {
+		skipProb = uint8(clampInt((len(e.mbs)-skipped)*256/len(e.mbs), 1, 255))
+		fp.writeLiteral(uint32(skipProb), 8)
+	}
+
+	for _, mb := range e.mbs {
+		if useSkip {
+			fp.writeBool(skipProb, mb.skip)
+		}
+		// 16x16 luma prediction, then the luma and chroma modes, specified in section 11.2
+		fp.writeBool(145, true)
+		switch mb.ymode {
+		case vp8PredDC, vp8PredV:
+			fp.writeBool(156, false)
+			fp.writeBool(163, mb.ymode == vp8PredV)
+		default:
+			fp.writeBool(156, true)
+			fp.writeBool(128, mb.ymode == vp8PredTM)
+		}
+		fp.writeBool(142, mb.uvmode != vp8PredDC)
+		if mb.uvmode != vp8PredDC {
+			fp.writeBool(114, mb.uvmode != vp8PredV)
+			if mb.uvmode != vp8PredV {
+				fp.writeBool(183, mb.uvmode == vp8PredTM)
+			}
+		}
+	}
+	first := fp.flush()
+	if len(first) >= 1<<19 {
+		return nil, errVP8TooLarge
+	}
+
+	tp := newVP8BoolEncoder()
+	(&vp8TokenCoder{e: tp, probs: &probs}).macroblocks(e.mbs, e.mbw, useSkip)
+	tokens := tp.flush()
+
+	// Frame header of a shown key frame, specified in section 9.1
+	tag := uint32(len(first))<<5 | 1<<4
+	data := make([]byte, 0, 10+len(first)+len(tokens))
+	data = append(data, byte(tag), byte(tag>>8), byte(tag>>16), 0x9d, 0x01, 0x2a,
+		byte(w), byte(w>>8), byte(h), byte(h>>8))
+	data = append(data, first...)
+	data = append(data, tokens...)
+	return data, nil
+}
+
+// vp8UpdatedProb returns the probability that minimizes the cost of the counts of false and true
+// bits and whether it's worth updating the current probability to it.
+func vp8UpdatedProb(current, update uint8, counts [2]uint32) (uint8, bool) {
+	total := counts[0] + counts[1]
+	if total == 0 {
+		return current, false
+	}
+	p := uint8(clampInt(int((counts[0]*256+total/2)/total), 1, 255))
+	savings := vp8Cost(current, counts) - vp8Cost(p, counts) -
+		8 - vp8Cost(update, [2]uint32{0, 1}) + vp8Cost(update, [2]uint32{1, 0})
+	return p, savings > 0
+}
+
+// vp8Cost returns the number of bits needed to code the counts of false and true bits with the
+// provided probability.
+func vp8Cost(prob uint8, counts [2]uint32) float64 {
+	p := float64(prob) / 256
+	return -float64(counts[0])*math.Log2(p) - float64(counts[1])*math.Log2(1-p)
+}
+
+// convert fills the planes with the image converted to BT.601 YCbCr with 4:2:0 subsampling.
+// Edge pixels are replicated up to the macroblock boundaries.
+func (e *vp8Encoder) convert(img image.Image) {
+	src := toNRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	pw, ph := e.mbw*16, e.mbh*16
+
+	e.y = vp8Plane{src: make([]uint8, pw*ph), rec: make([]uint8, pw*ph), stride: pw}
+	e.u = vp8Plane{src: make([]uint8, pw*ph/4), rec: make([]uint8, pw*ph/4), stride: pw / 2}
+	e.v = vp8Plane{src: make([]uint8, pw*ph/4), rec: make([]uint8, pw*ph/4), stride: pw / 2}
+
+	rgb := func(x, y int) (int32, int32, int32) {
+		pos := min(y, h-1)*src.Stride + min(x, w-1)*4
+		return int32(src.Pix[pos]), int32(src.Pix[pos+1]), int32(src.Pix[pos+2])
+	}
+
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			r, g, b := rgb(x, y)
+			e.y.src[y*pw+x] = uint8((16839*r + 33059*g + 6420*b + 16<<16 + 1<<15) >> 16)
+		}
+	}
+	for y := 0; y < ph/2; y++ {
+		for x := 0; x < pw/2; x++ {
+			var r, g, b int32
+			for _, p := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := rgb(2*x+p[0], 2*y+p[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			// The sums of four pixels are scaled down by 2 more bits
+			e.u.src[y*pw/2+x] = uint8((-9719*r - 19081*g + 28800*b + 128<<18 + 1<<17) >> 18)
+			e.v.src[y*pw/2+x] = uint8((28800*r - 24116*g - 4684*b + 128<<18 + 1<<17) >> 18)
+		}
+	}
+}
+
+// encodeLuma chooses the luma prediction mode of the macroblock and quantizes its residue,
+// updating the reconstructed plane.
+func (e *vp8Encoder) encodeLuma(mb *vp8Macroblock, mbx, mby int) {
+	x0, y0 := mbx*16, mby*16
+	pred := make([]int32, 16*16)
+	mb.ymode = e.y.bestMode(x0, y0, 16, pred)
+
+	var coeffs [16][16]int32
+	var dc [16]int32
+	for b := 0; b < 16; b++ {
+		coeffs[b] = e.y.residue(x0, y0, 16, b, pred)
+		dc[b] = coeffs[b][0]
+	}
+
+	// The DC coefficients are transformed again and coded in the Y2 block
+	wht := vp8FWHT(&dc)
+	var dq [16]int32
+	for i, z := range vp8Zigzag {
+		level := vp8Quantize(wht[z], e.y2q[min(z, 1)])
+		mb.coeffs[24][i] = level
+		dq[z] = level * e.y2q[min(z, 1)]
+	}
+	dcs := vp8IWHT(&dq)
+
+	for b := 0; b < 16; b++ {
+		dq := [16]int32{dcs[b]}
+		for i := 1; i < 16; i++ {
+			z := vp8Zigzag[i]
+			level := vp8Quantize(coeffs[b][z], e.y1q[1])
+			mb.coeffs[b][i] = level
+			dq[z] = level * e.y1q[1]
+		}
+		e.y.reconstruct(x0, y0, 16, b, pred, &dq)
+	}
+}
+
+// encodeChroma chooses the chroma prediction mode of the macroblock and quantizes its residue,
+// updating the reconstructed planes.
+func (e *vp8Encoder) encodeChroma(mb *vp8Macroblock, mbx, mby int) {
+	x0, y0 := mbx*8, mby*8
+	predU, predV := make([]int32, 8*8), make([]int32, 8*8)
+
+	best := int64(math.MaxInt64)
+	for mode := vp8PredDC; mode <= vp8PredTM; mode++ {
+		e.u.predict(x0, y0, 8, mode, predU)
+		e.v.predict(x0, y0, 8, mode, predV)
+		if sse := e.u.sse(x0, y0, 8, predU) + e.v.sse(x0, y0, 8, predV); sse < best {
+			best, mb.uvmode = sse, mode
+		}
+	}
+	e.u.predict(x0, y0, 8, mb.uvmode, predU)
+	e.v.predict(x0, y0, 8, mb.uvmode, predV)
+
+	for c, pl := range []*vp8Plane{&e.u, &e.v} {
+		pred := predU
+		if c == 1 {
+			pred = predV
+		}
+		for b := 0; b < 4; b++ {
+			coeffs := pl.residue(x0, y0, 8, b, pred)
+			var dq [16]int32
+			for i, z := range vp8Zigzag {
+				level := vp8Quantize(coeffs[z], e.uvq[min(z, 1)])
+				mb.coeffs[16+c*4+b][i] = level
+				dq[z] = level * e.uvq[min(z, 1)]
+			}
+			pl.reconstruct(x0, y0, 8, b, pred, &dq)
+		}
+	}
+}
+
+// vp8Quantize returns the quantized level of a coefficient, rounded to the nearest.
+func vp8Quantize(c, q int32) int32 {
+	level := (abs32(c) + q/2) / q
+	level = min(level, vp8MaxLevel)
+	if c < 0 {
+		return -level
+	}
+	return level
+}
+
+// bestMode fills pred with the prediction mode of the n×n block at x0, y0 with the lowest error
+// and returns the mode.
+func (pl *vp8Plane) bestMode(x0, y0, n int, pred []int32) int {
+	bestMode, best := vp8PredDC, int64(math.MaxInt64)
+	for mode := vp8PredDC; mode <= vp8PredTM; mode++ {
+		pl.predict(x0, y0, n, mode, pred)
+		if sse := pl.sse(x0, y0, n, pred); sse < best {
+			bestMode, best = mode, sse
+		}
+	}
+	pl.predict(x0, y0, n, bestMode, pred)
+	return bestMode
+}
+
+// sse returns the sum of squared errors of the prediction of the n×n block at x0, y0.
+func (pl *vp8Plane) sse(x0, y0, n int, pred []int32) int64 {
+	var sum int64
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			d := int64(int32(pl.src[(y0+j)*pl.stride+x0+i]) - pred[j*n+i])
+			sum += d * d
+		}
+	}
+	return sum
+}
+
+// predict fills pred with the prediction of the n×n block at x0, y0 in the provided mode, from
+// the reconstructed pixels above and to the left of it as specified in section 12.2.
+func (pl *vp8Plane) predict(x0, y0, n, mode int, pred []int32) {
+	var top, left [16]int32
+	hasTop, hasLeft := y0 > 0, x0 > 0
+	for i := 0; i < n; i++ {
+		top[i], left[i] = 127, 129
+		if hasTop {
+			top[i] = int32(pl.rec[(y0-1)*pl.stride+x0+i])
+		}
+		if hasLeft {
+			left[i] = int32(pl.rec[(y0+i)*pl.stride+x0-1])
+		}
+	}
+	topLeft := int32(127)
+	if hasTop && hasLeft {
+		topLeft = int32(pl.rec[(y0-1)*pl.stride+x0-1])
+	} else if hasTop {
+		topLeft = 129
+	}
+
+	switch mode {
+	case vp8PredDC:
+		shift := 3
+		if n == 16 {
+			shift = 4
+		}
+		var sum int32
+		for i := 0; i < n; i++ {
+			if hasTop {
+				sum += top[i]
+			}
+			if hasLeft {
+				sum += left[i]
+			}
+		}
+		dc := int32(128)
+		if hasTop && hasLeft {
+			dc = (sum + int32(n)) >> (shift + 1)
+		} else if hasTop || hasLeft {
+			dc = (sum + int32(n)/2) >> shift
+		}
+		for i := range pred[:n*n] {
+			pred[i] = dc
+		}
+	case vp8PredV:
+		for j := 0; j < n; j++ {
+			copy(pred[j*n:j*n+n], top[:n])
+		}
+	case vp8PredH:
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				pred[j*n+i] = left[j]
+			}
+		}
+	case vp8PredTM:
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				pred[j*n+i] = int32(clip8(left[j] + top[i] - topLeft))
+			}
+		}
+	}
+}
+
+// residue returns the forward DCT of the difference between the source and the prediction of the
+// 4x4 block b of the n×n block at x0, y0.
+func (pl *vp8Plane) residue(x0, y0, n, b int, pred []int32) [16]int32 {
+	bx, by := b%(n/4)*4, b/(n/4)*4
+	var res [16]int32
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			res[j*4+i] = int32(pl.src[(y0+by+j)*pl.stride+x0+bx+i]) - pred[(by+j)*n+bx+i]
+		}
+	}
+	return vp8FDCT(&res)
+}
+
+// reconstruct adds the inverse DCT of the dequantized coefficients to the prediction of the 4x4
+// block b of the n×n block at x0, y0, and stores it in the reconstructed plane.
+func (pl *vp8Plane) reconstruct(x0, y0, n, b int, pred []int32, dq *[16]int32) {
+	bx, by := b%(n/4)*4, b/(n/4)*4
+	res := vp8IDCT(dq)
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			pl.rec[(y0+by+j)*pl.stride+x0+bx+i] = clip8(pred[(by+j)*n+bx+i] + res[j*4+i])
+		}
+	}
+}
+
+// vp8FDCT is the forward DCT of a 4x4 block, as implemented by libvpx.
+func vp8FDCT(in *[16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		ip := in[i*4 : i*4+4]
+		a := (ip[0] + ip[3]) * 8
+		b := (ip[1] + ip[2]) * 8
+		c := (ip[1] - ip[2]) * 8
+		d := (ip[0] - ip[3]) * 8
+		tmp[i*4+0] = a + b
+		tmp[i*4+2] = a - b
+		tmp[i*4+1] = (c*2217 + d*5352 + 14500) >> 12
+		tmp[i*4+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[12+i]
+		b := tmp[4+i] + tmp[8+i]
+		c := tmp[4+i] - tmp[8+i]
+		d := tmp[i] - tmp[12+i]
+		out[i] = (a + b + 7) >> 4
+		out[8+i] = (a - b + 7) >> 4
+		out[4+i] = (c*2217+d*5352+12000)>>16 + btoi32(d != 0)
+		out[12+i] = (d*2217 - c*5352 + 51000) >> 16
+	}
+	return out
+}
+
+// vp8IDCT is the inverse DCT of a 4x4 block, specified in section 14.3.
+func vp8IDCT(in *[16]int32) [16]int32 {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := in[i] + in[8+i]
+		b := in[i] - in[8+i]
+		c := (in[4+i]*c2)>>16 - (in[12+i]*c1)>>16
+		d := (in[4+i]*c1)>>16 + (in[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	var out [16]int32
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		out[j*4+0] = (a + d) >> 3
+		out[j*4+1] = (b + c) >> 3
+		out[j*4+2] = (b - c) >> 3
+		out[j*4+3] = (a - d) >> 3
+	}
+	return out
+}
+
+// vp8FWHT is the forward Walsh-Hadamard transform of the DC coefficients of the 16 luma blocks,
+// as implemented by libvpx.
+func vp8FWHT(in *[16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		ip := in[i*4 : i*4+4]
+		a := (ip[0] + ip[2]) << 2
+		d := (ip[1] + ip[3]) << 2
+		c := (ip[1] - ip[3]) << 2
+		b := (ip[0] - ip[2]) << 2
+		tmp[i*4+0] = a + d + btoi32(a != 0)
+		tmp[i*4+1] = b + c
+		tmp[i*4+2] = b - c
+		tmp[i*4+3] = a - d
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[8+i]
+		d := tmp[4+i] + tmp[12+i]
+		c := tmp[4+i] - tmp[12+i]
+		b := tmp[i] - tmp[8+i]
+		for k, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			if v < 0 {
+				v++
+			}
+			out[k*4+i] = (v + 3) >> 3
+		}
+	}
+	return out
+}
+
+// vp8IWHT is the inverse Walsh-Hadamard transform specified in section 14.3, returning the DC
+// coefficients of the 16 luma blocks.
+func vp8IWHT(in *[16]int32) [16]int32 {
+	var m, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := in[i] + in[12+i]
+		a1 := in[4+i] + in[8+i]
+		a2 := in[4+i] - in[8+i]
+		a3 := in[i] - in[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[i*4+3]
+		a1 := m[i*4+1] + m[i*4+2]
+		a2 := m[i*4+1] - m[i*4+2]
+		a3 := dc - m[i*4+3]
+		out[i*4+0] = (a0 + a1) >> 3
+		out[i*4+1] = (a3 + a2) >> 3
+		out[i*4+2] = (a0 - a1) >> 3
+		out[i*4+3] = (a3 - a2) >> 3
+	}
+	return out
+}
+
+// vp8TokenCoder codes the coefficients of the macroblocks as tokens, specified in section 13.
+// When e is nil, the bits are only counted into stats.
+type vp8TokenCoder struct {
+	e     *vp8BoolEncoder
+	probs *[4][8][3][11]uint8
+	stats *[4][8][3][11][2]uint32
+}
+
+// put writes a bit coded with the token probability of the node of the token tree.
+func (t *vp8TokenCoder) put(plane, band, ctx, node int, bit bool) {
+	if t.e == nil {
+		t.stats[plane][band][ctx][node][btoi32(bit)]++
+		return
+	}
+	t.e.writeBool(t.probs[plane][band][ctx][node], bit)
+}
+
+// putFixed writes a bit coded with a fixed probability.
+func (t *vp8TokenCoder) putFixed(prob uint8, bit bool) {
+	if t.e != nil {
+		t.e.writeBool(prob, bit)
+	}
+}
+
+// macroblocks codes the coefficients of all the macroblocks, keeping track of which neighbouring
+// blocks have non-zero coefficients as the context of the first token of each block.
+func (t *vp8TokenCoder) macroblocks(mbs []vp8Macroblock, mbw int, useSkip bool) {
+	// Non-zero flags of the 4 luma, 2 Cb and 2 Cr columns or rows of blocks and the Y2 block
+	top := make([][9]int, mbw)
+	var left [9]int
+	for i := range mbs {
+		mb, mbx := &mbs[i], i%mbw
+		if mbx == 0 {
+			left = [9]int{}
+		}
+		up := &top[mbx]
+		if useSkip && mb.skip {
+			left, *up = [9]int{}, [9]int{}
+			continue
+		}
+
+		nz := t.block(vp8PlaneY2, left[8]+up[8], &mb.coeffs[24], 0)
+		left[8], up[8] = nz, nz
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				nz := t.block(vp8PlaneY1WithY2, left[y]+up[x], &mb.coeffs[y*4+x], 1)
+				left[y], up[x] = nz, nz
+			}
+		}
+		for c := 0; c < 2; c++ {
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 2; x++ {
+					nz := t.block(vp8PlaneUV, left[4+c*2+y]+up[4+c*2+x], &mb.coeffs[16+c*4+y*2+x], 0)
+					left[4+c*2+y], up[4+c*2+x] = nz, nz
+				}
+			}
+		}
+	}
+}
+
+// block codes the coefficients of a block from position first on, and returns 1 if any token
+// other than an end of block was coded.
+func (t *vp8TokenCoder) block(plane, ctx int, levels *[16]int32, first int) int {
+	last := -1
+	for i := 15; i >= first; i-- {
+		if levels[i] != 0 {
+			last = i
+			break
+		}
+	}
+
+	band := vp8Bands[first]
+	t.put(plane, band, ctx, 0, last >= 0)
+	if last < 0 {
+		return 0
+	}
+
+	for n := first; n < 16; {
+		v := levels[n]
+		n++
+		if v == 0 {
+			t.put(plane, band, ctx, 1, false)
+			band, ctx = vp8Bands[n], 0
+			continue
+		}
+		t.put(plane, band, ctx, 1, true)
+
+		a := abs32(v)
+		if a == 1 {
+			t.put(plane, band, ctx, 2, false)
+			band, ctx = vp8Bands[n], 1
+		} else {
+			t.put(plane, band, ctx, 2, true)
+			t.magnitude(plane, band, ctx, a)
+			band, ctx = vp8Bands[n], 2
+		}
+		t.putFixed(128, v < 0)
+
+		if n == 16 {
+			break
+		}
+		t.put(plane, band, ctx, 0, n <= last)
+		if n > last {
+			break
+		}
+	}
+	return 1
+}
+
+// magnitude codes a coefficient magnitude of at least 2 with the token tree of section 13.2.
+func (t *vp8TokenCoder) magnitude(plane, band, ctx int, a int32) {
+	switch {
+	case a <= 4:
+		t.put(plane, band, ctx, 3, false)
+		t.put(plane, band, ctx, 4, a > 2)
+		if a > 2 {
+			t.put(plane, band, ctx, 5, a == 4)
+		}
+	case a <= 10:
+		t.put(plane, band, ctx, 3, true)
+		t.put(plane, band, ctx, 6, false)
+		t.put(plane, band, ctx, 7, a > 6)
+		if a <= 6 {
+			t.putFixed(159, a == 6)
+		} else {
+			t.putFixed(165, (a-7)&2 != 0)
+			t.putFixed(145, (a-7)&1 != 0)
+		}
+	default:
+		t.put(plane, band, ctx, 3, true)
+		t.put(plane, band, ctx, 6, true)
+		cat := 0
+		for cat < 3 && a >= 3+(16<<cat) {
+			cat++
+		}
+		t.put(plane, band, ctx, 8, cat >= 2)
+		t.put(plane, band, ctx, 9+cat/2, cat&1 != 0)
+		extra := a - 3 - (8 << cat)
+		tab := vp8Cat3456[cat]
+		for i, prob := range tab {
+			t.putFixed(prob, extra>>uint(len(tab)-1-i)&1 != 0)
+		}
+	}
+}
+
+func clip8(v int32) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+func clampInt(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func btoi32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
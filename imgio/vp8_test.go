@@ -0,0 +1,80 @@
+package imgio
+
+import (
+	"testing"
+)
+
+func TestVP8QualityToIndex(t *testing.T) {
+	cases := []struct {
+		quality  int
+		expected int
+	}{
+		{quality: 0, expected: 127},
+		{quality: 1, expected: 103},
+		{quality: 75, expected: 26},
+		{quality: 100, expected: 0},
+		{quality: 150, expected: 0},
+	}
+
+	for _, c := range cases {
+		if actual := vp8QualityToIndex(c.quality); actual != c.expected {
+			t.Errorf("%s: expected: %d actual: %d", "vp8QualityToIndex", c.expected, actual)
+		}
+	}
+}
+
+func TestVP8Transforms(t *testing.T) {
+	cases := []struct {
+		description string
+		value       [16]int32
+	}{
+		{description: "zero", value: [16]int32{}},
+		{description: "flat", value: [16]int32{100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100}},
+		{description: "ramp", value: [16]int32{-120, -100, -80, -60, -40, -20, 0, 20, 40, 60, 80, 100, 120, 140, 160, 180}},
+		{description: "checkers", value: [16]int32{255, -255, 255, -255, -255, 255, -255, 255, 255, -255, 255, -255, -255, 255, -255, 255}},
+	}
+
+	for _, c := range cases {
+		coeffs := vp8FDCT(&c.value)
+		if actual := vp8IDCT(&coeffs); !within(actual, c.value, 1) {
+			t.Errorf("%s: expected: %v actual: %v", "vp8IDCT "+c.description, c.value, actual)
+		}
+
+		// The DC coefficients are 8 times the mean of a block, the transform keeps them exactly
+		var dc [16]int32
+		for i := range dc {
+			dc[i] = c.value[i] * 8
+		}
+		wht := vp8FWHT(&dc)
+		if actual := vp8IWHT(&wht); !within(actual, dc, 1) {
+			t.Errorf("%s: expected: %v actual: %v", "vp8IWHT "+c.description, dc, actual)
+		}
+	}
+}
+
+func TestVP8Quantize(t *testing.T) {
+	cases := []struct {
+		value, q, expected int32
+	}{
+		{value: 0, q: 10, expected: 0},
+		{value: 14, q: 10, expected: 1},
+		{value: 15, q: 10, expected: 2},
+		{value: -15, q: 10, expected: -2},
+		{value: 100000, q: 4, expected: vp8MaxLevel},
+	}
+
+	for _, c := range cases {
+		if actual := vp8Quantize(c.value, c.q); actual != c.expected {
+			t.Errorf("%s: expected: %d actual: %d", "vp8Quantize", c.expected, actual)
+		}
+	}
+}
+
+func within(a, b [16]int32, tolerance int32) bool {
+	for i := range a {
+		if abs32(a[i]-b[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
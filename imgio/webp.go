@@ -0,0 +1,160 @@
+package imgio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// WEBPOptions are the WebP encoding parameters.
+// Lossless set to true encodes the exact pixels of the image. Otherwise the image is compressed
+// lossily with the provided Quality, ranging from 1 to 100, higher is better. Default of 75 is used if zero.
+type WEBPOptions struct {
+	Lossless bool
+	Quality  int
+}
+
+// WEBPEncoderWithOptions returns an encoder to WebP with the provided options.
+// Default parameters are used if a nil *WEBPOptions is passed.
+// The alpha channel of lossy images is stored losslessly.
+//
+// Usage example:
+//
+//	err := imgio.Save("output.webp", img, imgio.WEBPEncoderWithOptions(&imgio.WEBPOptions{Quality: 80}))
+func WEBPEncoderWithOptions(o *WEBPOptions) Encoder {
+	lossless, quality := false, 75
+	if o != nil {
+		lossless = o.Lossless
+		if o.Quality != 0 {
+			quality = o.Quality
+		}
+	}
+
+	return func(w io.Writer, img image.Image) error {
+		if lossless {
+			return nativewebp.Encode(w, img, nil)
+		}
+		data, err := encodeLossyWebP(img, quality)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+}
+
+// webpChunk is a chunk of a WebP RIFF file.
+type webpChunk struct {
+	name    string
+	payload []byte
+}
+
+// webpFile returns the WebP file made of the chunks, and false if it's too large for the format.
+func webpFile(chunks []webpChunk) ([]byte, bool) {
+	result := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		result = append(result, c.name...)
+		result = binary.LittleEndian.AppendUint32(result, uint32(len(c.payload)))
+		result = append(result, c.payload...)
+		// Chunks are padded to an even size
+		if len(c.payload)&1 != 0 {
+			result = append(result, 0)
+		}
+	}
+	if uint64(len(result)-8) > 0xFFFFFFFF {
+		return nil, false
+	}
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(result)-8))
+	return result, true
+}
+
+// webpVP8X returns the payload of the extended format header of an image with the bounds and flags.
+func webpVP8X(b image.Rectangle, flags byte) []byte {
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+	putUint24 := func(b []byte, v int) { b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16) }
+	putUint24(vp8x[4:], b.Dx()-1)
+	putUint24(vp8x[7:], b.Dy()-1)
+	return vp8x
+}
+
+// encodeLossyWebP returns a WebP file holding the image as a VP8 key frame. Translucent images
+// have their alpha channel stored in an ALPH chunk, compressed losslessly.
+func encodeLossyWebP(img image.Image, quality int) ([]byte, error) {
+	src := toNRGBA(img)
+
+	frame, err := encodeVP8(src, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	opaque := true
+	for i := 3; i < len(src.Pix); i += 4 {
+		if src.Pix[i] != 0xFF {
+			opaque = false
+			break
+		}
+	}
+
+	chunks := []webpChunk{{"VP8 ", frame}}
+	if !opaque {
+		alph, err := webpAlpha(src)
+		if err != nil {
+			return nil, err
+		}
+		chunks = []webpChunk{{"VP8X", webpVP8X(src.Bounds(), webpFlagAlpha)}, {"ALPH", alph}, chunks[0]}
+	}
+
+	result, ok := webpFile(chunks)
+	if !ok {
+		return nil, errVP8TooLarge
+	}
+	return result, nil
+}
+
+// webpAlpha returns the payload of the ALPH chunk of the image, which is the alpha channel stored
+// in the green channel of a VP8L bitstream without its header.
+func webpAlpha(img *image.NRGBA) ([]byte, error) {
+	green := image.NewNRGBA(img.Bounds())
+	for i := 3; i < len(img.Pix); i += 4 {
+		green.Pix[i-2] = img.Pix[i]
+		green.Pix[i] = 0xFF
+	}
+
+	var buf bytes.Buffer
+	if err := nativewebp.Encode(&buf, green, nil); err != nil {
+		return nil, err
+	}
+
+	var lossless []byte
+	webpChunks(buf.Bytes(), func(name string, payload []byte) bool {
+		if name == "VP8L" {
+			lossless = payload
+			return false
+		}
+		return true
+	})
+	// The 5 byte VP8L header holds the signature and the size of the image
+	if len(lossless) < 5 {
+		return nil, errors.New("imgio: unexpected lossless WebP bitstream")
+	}
+
+	// The header byte selects the lossless compression without filtering
+	return append([]byte{1}, lossless[5:]...), nil
+}
+
+// toNRGBA returns the non-premultiplied version of the image with its bounds starting at 0, 0.
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	if src, ok := img.(*image.NRGBA); ok && b.Min == (image.Point{}) {
+		return src
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
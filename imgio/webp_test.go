@@ -0,0 +1,113 @@
+package imgio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWEBPEncoderWithOptions(t *testing.T) {
+	translucent := gradient(image.Rect(0, 0, 37, 21))
+	for i := 3; i < len(translucent.Pix); i += 4 {
+		translucent.Pix[i] = uint8(i)
+	}
+
+	cases := []struct {
+		description string
+		img         *image.NRGBA
+		options     *WEBPOptions
+		maxDiff     float64
+	}{
+		{description: "default", img: gradient(image.Rect(0, 0, 37, 21)), options: nil, maxDiff: 4},
+		{description: "lossy", img: gradient(image.Rect(0, 0, 37, 21)), options: &WEBPOptions{Quality: 90}, maxDiff: 4},
+		{description: "lossy single pixel", img: gradient(image.Rect(0, 0, 1, 1)), options: &WEBPOptions{Quality: 90}, maxDiff: 4},
+		{description: "lossy offset bounds", img: gradient(image.Rect(5, 3, 30, 40)), options: &WEBPOptions{Quality: 90}, maxDiff: 4},
+		{description: "lossy translucent", img: translucent, options: &WEBPOptions{Quality: 90}, maxDiff: 4},
+		{description: "lossless", img: translucent, options: &WEBPOptions{Lossless: true}, maxDiff: 0},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := WEBPEncoderWithOptions(c.options)(&buf, c.img); err != nil {
+			t.Fatalf("%s: %v", "WEBPEncoderWithOptions "+c.description, err)
+		}
+
+		decoded, format, err := image.Decode(&buf)
+		if err != nil {
+			t.Errorf("%s: %v", "WEBPEncoderWithOptions "+c.description, err)
+			continue
+		}
+		if format != "webp" || decoded.Bounds().Size() != c.img.Bounds().Size() {
+			t.Errorf("%s: unexpected decoded %s image of bounds %v", "WEBPEncoderWithOptions "+c.description, format, decoded.Bounds())
+			continue
+		}
+
+		// The alpha channel is always stored losslessly
+		b, db := c.img.Bounds(), decoded.Bounds()
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				expected := c.img.NRGBAAt(b.Min.X+x, b.Min.Y+y).A
+				if actual := color.NRGBAModel.Convert(decoded.At(db.Min.X+x, db.Min.Y+y)).(color.NRGBA).A; actual != expected {
+					t.Fatalf("%s: expected alpha: %d actual: %d", "WEBPEncoderWithOptions "+c.description, expected, actual)
+				}
+			}
+		}
+
+		if c.options == nil || !c.options.Lossless {
+			decoded = limitedRange(decoded)
+		}
+		if diff := meanAbsDiff(opaque(c.img), opaque(decoded)); diff > c.maxDiff {
+			t.Errorf("%s: mean difference too large: %.2f", "WEBPEncoderWithOptions "+c.description, diff)
+		}
+	}
+}
+
+func TestWEBPEncoderQuality(t *testing.T) {
+	img := gradient(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		if i%4 != 3 {
+			img.Pix[i] ^= uint8(i * 7919 >> 3)
+		}
+	}
+
+	var low, high bytes.Buffer
+	WEBPEncoderWithOptions(&WEBPOptions{Quality: 10})(&low, img)
+	WEBPEncoderWithOptions(&WEBPOptions{Quality: 95})(&high, img)
+	if low.Len() >= high.Len() {
+		t.Errorf("WEBPEncoderQuality: expected a smaller file at lower quality, actual sizes: %d %d", low.Len(), high.Len())
+	}
+}
+
+// opaque returns the colors of the image without their alpha.
+func opaque(img image.Image) *image.NRGBA {
+	result := toNRGBA(img)
+	result = &image.NRGBA{Pix: bytes.Clone(result.Pix), Stride: result.Stride, Rect: result.Rect}
+	for i := 3; i < len(result.Pix); i += 4 {
+		result.Pix[i] = 0xFF
+	}
+	return result
+}
+
+// limitedRange returns the lossy WebP image converted with the BT.601 limited range formulas of
+// the VP8 spec, as the decoder returns an *image.YCbCr which assumes the full range of JPEG.
+func limitedRange(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	result := image.NewNRGBA(b)
+	clamp := func(v float64) uint8 { return uint8(min(max(v+0.5, 0), 255)) }
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var c color.NYCbCrA
+			switch img := img.(type) {
+			case *image.YCbCr:
+				c = color.NYCbCrA{YCbCr: img.YCbCrAt(x, y), A: 0xFF}
+			case *image.NYCbCrA:
+				c = img.NYCbCrAAt(x, y)
+			}
+			luma := 1.164 * (float64(c.Y) - 16)
+			cb, cr := float64(c.Cb)-128, float64(c.Cr)-128
+			result.SetNRGBA(x, y, color.NRGBA{clamp(luma + 1.596*cr), clamp(luma - 0.392*cb - 0.813*cr), clamp(luma + 2.017*cb), c.A})
+		}
+	}
+	return result
+}
@@ -16,3 +16,15 @@ func Max(a, b int) int {
 	}
 	return b
 }
+
+// Clamp returns the value if it fits within the parameters min and max.
+// Otherwise returns the closest boundary parameter value.
+func Clamp(value, min, max int) int {
+	if value > max {
+		return max
+	}
+	if value < min {
+		return min
+	}
+	return value
+}
@@ -79,3 +79,41 @@ func TestMax(t *testing.T) {
 		}
 	}
 }
+
+func TestClamp(t *testing.T) {
+	cases := []struct {
+		value, min, max, expected int
+	}{
+		{
+			value:    0,
+			min:      0,
+			max:      0,
+			expected: 0,
+		},
+		{
+			value:    5,
+			min:      0,
+			max:      10,
+			expected: 5,
+		},
+		{
+			value:    -1,
+			min:      0,
+			max:      10,
+			expected: 0,
+		},
+		{
+			value:    11,
+			min:      0,
+			max:      10,
+			expected: 10,
+		},
+	}
+
+	for _, c := range cases {
+		actual := Clamp(c.value, c.min, c.max)
+		if actual != c.expected {
+			t.Errorf("Clamp: expected: %v actual: %v", c.expected, actual)
+		}
+	}
+}
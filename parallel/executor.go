@@ -0,0 +1,116 @@
+package parallel
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+// Options are the parameters of an Executor.
+type Options struct {
+	// MaxWorkers is the maximum number of goroutines an operation works on, including the one calling it.
+	// GOMAXPROCS is used if it's 0 or less.
+	MaxWorkers int
+	// Serial runs the operations on the goroutines calling them only, as if MaxWorkers was 1.
+	Serial bool
+}
+
+// Executor runs the work of operations on the goroutines calling them, helped by extra goroutines whose
+// number is limited across all the operations using it. It acts as a semaphore rather than a pool:
+// each operation starts new goroutines for its work, which exit when it's done, and at most MaxWorkers-1
+// of them run at once among all the operations. This way concurrent operations split the CPUs between
+// themselves rather than each of them starting MaxWorkers goroutines, and an operation started while
+// all the slots are taken simply runs on fewer goroutines.
+// A nil *Executor starts up to GOMAXPROCS goroutines for each operation, without limiting their total.
+type Executor struct {
+	maxWorkers int
+	// slots holds a value for each extra goroutine running the work of an operation
+	slots chan struct{}
+}
+
+// NewExecutor returns a new Executor with the provided options.
+// Default parameters are used if a nil *Options is passed.
+//
+// Usage example:
+//
+//	// Let all the requests of a server share 8 CPUs
+//	executor := parallel.NewExecutor(&parallel.Options{MaxWorkers: 8})
+func NewExecutor(o *Options) *Executor {
+	maxWorkers := runtime.GOMAXPROCS(0)
+	if o != nil {
+		if o.MaxWorkers > 0 {
+			maxWorkers = o.MaxWorkers
+		}
+		if o.Serial {
+			maxWorkers = 1
+		}
+	}
+
+	return &Executor{maxWorkers: maxWorkers, slots: make(chan struct{}, maxWorkers-1)}
+}
+
+// MaxWorkers returns the maximum number of goroutines an operation works on.
+func (e *Executor) MaxWorkers() int {
+	if e == nil {
+		return runtime.GOMAXPROCS(0)
+	}
+	return e.maxWorkers
+}
+
+// acquire takes up to n slots for extra goroutines without waiting for them, and returns how many it took.
+func (e *Executor) acquire(n int) int {
+	if e == nil {
+		return n
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case e.slots <- struct{}{}:
+		default:
+			return i
+		}
+	}
+	return n
+}
+
+// release frees the slot of an extra goroutine once it's done.
+func (e *Executor) release() {
+	if e != nil {
+		<-e.slots
+	}
+}
+
+// defaultExecutor is the executor of the operations whose context doesn't set one.
+var defaultExecutor atomic.Pointer[Executor]
+
+// SetDefault sets the executor of the operations whose context doesn't set one with WithExecutor,
+// which includes all the operations not taking a context. A nil *Executor restores the default of
+// starting up to GOMAXPROCS goroutines for each operation.
+//
+// Usage example:
+//
+//	// Run every operation on the goroutine calling it
+//	parallel.SetDefault(parallel.NewExecutor(&parallel.Options{Serial: true}))
+func SetDefault(e *Executor) {
+	defaultExecutor.Store(e)
+}
+
+// executorKey is the context key of the executor of an operation.
+type executorKey struct{}
+
+// WithExecutor returns a copy of ctx whose operations run on e.
+//
+// Usage example:
+//
+//	ctx = parallel.WithExecutor(r.Context(), executor)
+//	result, err := transform.ResizeCtx(ctx, img, 800, 600, transform.Linear)
+func WithExecutor(ctx context.Context, e *Executor) context.Context {
+	return context.WithValue(ctx, executorKey{}, e)
+}
+
+// executorFrom returns the executor set on ctx, or the default one.
+func executorFrom(ctx context.Context) *Executor {
+	if e, ok := ctx.Value(executorKey{}).(*Executor); ok {
+		return e
+	}
+	return defaultExecutor.Load()
+}
@@ -0,0 +1,101 @@
+package parallel
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewExecutor(t *testing.T) {
+	cases := []struct {
+		description string
+		options     *Options
+		expected    int
+	}{
+		{"nil options", nil, runtime.GOMAXPROCS(0)},
+		{"default max workers", &Options{}, runtime.GOMAXPROCS(0)},
+		{"max workers", &Options{MaxWorkers: 3}, 3},
+		{"serial", &Options{MaxWorkers: 3, Serial: true}, 1},
+	}
+
+	for _, c := range cases {
+		e := NewExecutor(c.options)
+		if actual := e.MaxWorkers(); actual != c.expected {
+			t.Errorf("%s: expected: %v actual: %v", "NewExecutor "+c.description, c.expected, actual)
+		}
+		if actual := cap(e.slots); actual != c.expected-1 {
+			t.Errorf("%s: expected: %v actual: %v", "NewExecutor pool "+c.description, c.expected-1, actual)
+		}
+	}
+
+	var e *Executor
+	if actual := e.MaxWorkers(); actual != runtime.GOMAXPROCS(0) {
+		t.Errorf("%s: expected: %v actual: %v", "nil Executor MaxWorkers", runtime.GOMAXPROCS(0), actual)
+	}
+}
+
+func TestExecutorPool(t *testing.T) {
+	e := NewExecutor(&Options{MaxWorkers: 3})
+	if actual := e.acquire(5); actual != 2 {
+		t.Errorf("%s: expected: %v actual: %v", "Executor acquire", 2, actual)
+	}
+	// The pool is shared, so another operation only gets the goroutines given back
+	if actual := e.acquire(5); actual != 0 {
+		t.Errorf("%s: expected: %v actual: %v", "Executor acquire busy", 0, actual)
+	}
+	e.release()
+	if actual := e.acquire(5); actual != 1 {
+		t.Errorf("%s: expected: %v actual: %v", "Executor acquire released", 1, actual)
+	}
+}
+
+// concurrency returns the largest number of goroutines running fn at once during LineCtx with ctx.
+func concurrency(ctx context.Context, length int) int {
+	var running, peak atomic.Int64
+	LineCtx(WithScheduler(ctx, &Scheduler{Workers: 64, ChunkSize: 1}), length, func(start, end int) {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		runtime.Gosched()
+		running.Add(-1)
+	})
+	return int(peak.Load())
+}
+
+func TestExecutorLimits(t *testing.T) {
+	serial := NewExecutor(&Options{Serial: true})
+	if actual := concurrency(WithExecutor(context.Background(), serial), 1000); actual != 1 {
+		t.Errorf("%s: expected: %v actual: %v", "Executor serial", 1, actual)
+	}
+
+	limited := NewExecutor(&Options{MaxWorkers: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if actual := concurrency(WithExecutor(context.Background(), limited), 1000); actual > 2 {
+				t.Errorf("%s: expected: %v actual: %v", "Executor max workers", "at most 2", actual)
+			}
+		}()
+	}
+	wg.Wait()
+	if actual := len(limited.slots); actual != 0 {
+		t.Errorf("%s: expected: %v actual: %v", "Executor pool released", 0, actual)
+	}
+
+	// The default executor runs the operations whose context doesn't set one
+	SetDefault(serial)
+	defer SetDefault(nil)
+	if actual := concurrency(context.Background(), 1000); actual != 1 {
+		t.Errorf("%s: expected: %v actual: %v", "SetDefault serial", 1, actual)
+	}
+	var chunks []int
+	Line(10, func(start, end int) { chunks = append(chunks, start) })
+	if len(chunks) == 0 || !sort.IntsAreSorted(chunks) {
+		t.Errorf("%s: expected: %v actual: %v", "SetDefault serial Line", "chunks in order", chunks)
+	}
+}
@@ -2,38 +2,65 @@
 package parallel
 
 import (
-	"runtime"
-	"sync"
+	"context"
+	"image"
 )
 
-func init() {
-	runtime.GOMAXPROCS(runtime.NumCPU())
+// Line dispatches a parameter fn into multiple goroutines by splitting the parameter length into
+// chunks, which the goroutines of the default executor balance between themselves.
+func Line(length int, fn func(start, end int)) {
+	LineCtx(context.Background(), length, fn)
 }
 
-// Line dispatches a parameter fn into multiple goroutines by splitting the parameter length
-// by the number of available CPUs and assigning the length parts into each fn.
-func Line(length int, fn func(start, end int)) {
-	procs := runtime.GOMAXPROCS(0)
-	counter := length
-	partSize := length / procs
-	if procs <= 1 || partSize <= procs {
-		fn(0, length)
-	} else {
-		var wg sync.WaitGroup
-		for counter > 0 {
-			start := counter - partSize
-			end := counter
-			if start < 0 {
-				start = 0
-			}
-			counter -= partSize
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				fn(start, end)
-			}()
-		}
+// linePartsPerProc is the number of chunks the length is split into for each worker when the scheduler
+// doesn't set a chunk size, so that the workers can balance them and stop early without waiting for a
+// large chunk to complete.
+const linePartsPerProc = 4
+
+// LineCtx dispatches a parameter fn into multiple goroutines like Line, but only starts the chunks of the
+// parameter length while ctx isn't done. It returns nil once fn has been called over the whole length, or
+// ctx.Err() if ctx was done before that. The work is dispatched with the scheduler set with WithScheduler
+// on the executor set with WithExecutor, if any, and the progress callback of ctx is called after each
+// chunk completes.
+//
+// Usage example:
+//
+//	err := parallel.LineCtx(ctx, height, func(start, end int) {
+//		for y := start; y < end; y++ {
+//			// Process row y
+//		}
+//	})
+func LineCtx(ctx context.Context, length int, fn func(start, end int)) error {
+	return schedulerFrom(ctx).Line(ctx, length, fn)
+}
+
+// Tiles dispatches a parameter fn into multiple goroutines by splitting the rectangle r into square tiles,
+// fn being called with the bounds of each of them. Tiles balance the work better than lines when the cost
+// of the pixels varies across the image.
+//
+// Usage example:
+//
+//	parallel.Tiles(dst.Bounds(), func(tile image.Rectangle) {
+//		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+//			for x := tile.Min.X; x < tile.Max.X; x++ {
+//				// Process pixel (x, y)
+//			}
+//		}
+//	})
+func Tiles(r image.Rectangle, fn func(tile image.Rectangle)) {
+	TilesCtx(context.Background(), r, fn)
+}
 
-		wg.Wait()
-	}
+// TilesCtx dispatches a parameter fn into multiple goroutines like Tiles, but only starts the tiles while
+// ctx isn't done. It returns nil once fn has been called over the whole rectangle, or ctx.Err() if ctx was
+// done before that. The work is dispatched with the scheduler set with WithScheduler on the executor set
+// with WithExecutor, if any, and the progress callback of ctx is called after each tile completes.
+//
+// Usage example:
+//
+//	err := parallel.TilesCtx(ctx, dst.Bounds(), func(tile image.Rectangle) {
+//		// Process the pixels of the tile
+//	})
+func TilesCtx(ctx context.Context, r image.Rectangle, fn func(tile image.Rectangle)) error {
+	return schedulerFrom(ctx).Tiles(ctx, r, fn)
 }
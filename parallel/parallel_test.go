@@ -1,6 +1,10 @@
 package parallel
 
-import "testing"
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
 
 func TestParallelize(t *testing.T) {
 	for n := 0; n < 1024; n++ {
@@ -19,3 +23,44 @@ func TestParallelize(t *testing.T) {
 		}
 	}
 }
+
+func TestLineCtx(t *testing.T) {
+	for n := 0; n < 1024; n++ {
+		data := make([]bool, n)
+
+		err := LineCtx(context.Background(), len(data), func(start, end int) {
+			for i := start; i < end; i++ {
+				data[i] = !data[i]
+			}
+		})
+		if err != nil {
+			t.Fatalf("%s: expected: %v actual: %v", "LineCtx", nil, err)
+		}
+
+		for _, d := range data {
+			if !d {
+				t.Errorf("Test LineCtx failed. Failure at n = %v", n)
+			}
+		}
+	}
+}
+
+func TestLineCtxCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	if err := LineCtx(ctx, 100, func(start, end int) { called = true }); err != context.Canceled || called {
+		t.Errorf("%s: expected: %v actual: %v, called: %v", "LineCtx canceled before", context.Canceled, err, called)
+	}
+
+	// Parts which didn't start when the context is canceled are skipped
+	ctx, cancel = context.WithCancel(context.Background())
+	var rows atomic.Int64
+	err := LineCtx(ctx, 100000, func(start, end int) {
+		rows.Add(int64(end - start))
+		cancel()
+	})
+	if err != context.Canceled || rows.Load() >= 100000 {
+		t.Errorf("%s: expected: %v actual: %v, rows: %v", "LineCtx canceled during", context.Canceled, err, rows.Load())
+	}
+}
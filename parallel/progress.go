@@ -0,0 +1,68 @@
+package parallel
+
+import (
+	"context"
+	"sync"
+)
+
+// ProgressFunc is called with the fraction of an operation that is done, in the range 0.0 to 1.0.
+// Calls are never concurrent, and the fraction never decreases.
+type ProgressFunc func(done float64)
+
+// progressKey is the context key of the progress of an operation.
+type progressKey struct{}
+
+// progress is the part of an operation, from one fraction to another, whose progress is reported to fn.
+type progress struct {
+	mu       *sync.Mutex
+	fn       ProgressFunc
+	from, to float64
+}
+
+// WithProgress returns a copy of ctx whose operations report their progress to fn.
+//
+// Usage example:
+//
+//	ctx = parallel.WithProgress(ctx, func(done float64) {
+//		log.Printf("%.0f%% done", done*100)
+//	})
+//	result, err := transform.ResizeCtx(ctx, img, 800, 600, transform.Linear)
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progress{mu: &sync.Mutex{}, fn: fn, from: 0, to: 1})
+}
+
+// ProgressRange returns a copy of ctx for a step of an operation, whose progress is reported as the
+// range from one fraction to another of the progress of ctx. It returns ctx if it has no progress callback.
+//
+// Usage example:
+//
+//	// The first of two passes reports the first half of the progress
+//	err := parallel.LineCtx(parallel.ProgressRange(ctx, 0, 0.5), height, fn)
+func ProgressRange(ctx context.Context, from, to float64) context.Context {
+	p, ok := ctx.Value(progressKey{}).(*progress)
+	if !ok {
+		return ctx
+	}
+	span := p.to - p.from
+	return context.WithValue(ctx, progressKey{}, &progress{mu: p.mu, fn: p.fn, from: p.from + from*span, to: p.from + to*span})
+}
+
+// progressReporter returns a function adding to the amount of work done out of the total, which reports the
+// progress to the callback of ctx, if any, and returns the amount of work done so far.
+func progressReporter(ctx context.Context, total int) func(n int) int {
+	p, _ := ctx.Value(progressKey{}).(*progress)
+
+	var mu sync.Mutex
+	done := 0
+	return func(n int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		done += n
+		if p != nil && n > 0 {
+			p.mu.Lock()
+			p.fn(p.from + (p.to-p.from)*float64(done)/float64(total))
+			p.mu.Unlock()
+		}
+		return done
+	}
+}
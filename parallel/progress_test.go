@@ -0,0 +1,48 @@
+package parallel
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	var reports []float64
+	ctx := WithProgress(context.Background(), func(done float64) {
+		reports = append(reports, done)
+	})
+
+	if err := LineCtx(ProgressRange(ctx, 0, 0.25), 1000, func(start, end int) {}); err != nil {
+		t.Fatalf("%s: expected: %v actual: %v", "Progress", nil, err)
+	}
+	if last := reports[len(reports)-1]; math.Abs(last-0.25) > 1e-9 {
+		t.Errorf("%s: expected: %v actual: %v", "Progress first step", 0.25, last)
+	}
+
+	if err := LineCtx(ProgressRange(ctx, 0.25, 1), 1000, func(start, end int) {}); err != nil {
+		t.Fatalf("%s: expected: %v actual: %v", "Progress", nil, err)
+	}
+	if last := reports[len(reports)-1]; math.Abs(last-1) > 1e-9 {
+		t.Errorf("%s: expected: %v actual: %v", "Progress second step", 1.0, last)
+	}
+
+	for i := 1; i < len(reports); i++ {
+		if reports[i] < reports[i-1] {
+			t.Errorf("%s: expected: %v actual: %v", "Progress", "increasing fractions", reports)
+			break
+		}
+	}
+
+	// A nested range maps into the range of its parent
+	reports = nil
+	nested := ProgressRange(ProgressRange(ctx, 0.5, 1), 0, 0.5)
+	LineCtx(nested, 10, func(start, end int) {})
+	if last := reports[len(reports)-1]; math.Abs(last-0.75) > 1e-9 {
+		t.Errorf("%s: expected: %v actual: %v", "Progress nested step", 0.75, last)
+	}
+
+	// Contexts without a callback are left as they are
+	if actual := ProgressRange(context.Background(), 0, 0.5); actual != context.Background() {
+		t.Errorf("%s: expected: %v actual: %v", "ProgressRange without callback", context.Background(), actual)
+	}
+}
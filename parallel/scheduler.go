@@ -0,0 +1,216 @@
+package parallel
+
+import (
+	"context"
+	"image"
+	"sync"
+)
+
+// defaultTileSize is the width and height in pixels of the tiles dispatched by Tiles when the
+// scheduler doesn't set a chunk size, small enough for the pixels of a tile to stay in the CPU caches.
+const defaultTileSize = 64
+
+// Scheduler dispatches chunks of work to a number of goroutines. Each goroutine starts with its own
+// contiguous share of the chunks and, once done with them, steals the remaining chunks of the others,
+// so that uneven workloads still keep every goroutine busy.
+// A nil *Scheduler uses the default parameters.
+type Scheduler struct {
+	// Workers is the number of goroutines processing the chunks, which the executor of the operation may
+	// reduce. The MaxWorkers of the executor is used if it's 0 or less.
+	Workers int
+	// ChunkSize is the number of lines of a chunk dispatched by Line, and the width and height in pixels
+	// of a tile dispatched by Tiles. A size depending on the length is used for lines if it's 0 or less,
+	// and tiles of 64x64 pixels.
+	ChunkSize int
+}
+
+// schedulerKey is the context key of the scheduler of an operation.
+type schedulerKey struct{}
+
+// WithScheduler returns a copy of ctx whose operations dispatch their work with s.
+//
+// Usage example:
+//
+//	// Leave some CPUs for other requests and balance the work in smaller chunks
+//	ctx = parallel.WithScheduler(ctx, &parallel.Scheduler{Workers: 4, ChunkSize: 16})
+//	result, err := blur.GaussianCtx(ctx, img, 3.0)
+func WithScheduler(ctx context.Context, s *Scheduler) context.Context {
+	return context.WithValue(ctx, schedulerKey{}, s)
+}
+
+// schedulerFrom returns the scheduler set on ctx, or nil for the default one.
+func schedulerFrom(ctx context.Context) *Scheduler {
+	s, _ := ctx.Value(schedulerKey{}).(*Scheduler)
+	return s
+}
+
+// Line dispatches fn over the range from 0 to length split into chunks of lines. It returns nil once fn
+// has been called over the whole length, or ctx.Err() if ctx was done before that, in which case the
+// chunks which didn't start are skipped. The progress callback of ctx, if any, is called after each chunk
+// completes.
+//
+// Usage example:
+//
+//	s := &parallel.Scheduler{Workers: 2, ChunkSize: 8}
+//	err := s.Line(ctx, height, func(start, end int) {
+//		for y := start; y < end; y++ {
+//			// Process row y
+//		}
+//	})
+func (s *Scheduler) Line(ctx context.Context, length int, fn func(start, end int)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if length <= 0 {
+		return nil
+	}
+
+	size := s.chunkSize()
+	if size <= 0 {
+		size = max(1, length/(s.workers(executorFrom(ctx))*linePartsPerProc))
+	}
+
+	return s.dispatch(ctx, (length+size-1)/size, length, func(i int) int {
+		start := i * size
+		end := min(start+size, length)
+		fn(start, end)
+		return end - start
+	})
+}
+
+// Tiles dispatches fn over the rectangle r split into square tiles, fn being called with the bounds of
+// each tile, which are contained in r. It returns nil once fn has been called over the whole rectangle, or
+// ctx.Err() if ctx was done before that, in which case the tiles which didn't start are skipped.
+// The progress callback of ctx, if any, is called after each tile completes.
+//
+// Usage example:
+//
+//	err := (&parallel.Scheduler{ChunkSize: 32}).Tiles(ctx, dst.Bounds(), func(tile image.Rectangle) {
+//		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+//			for x := tile.Min.X; x < tile.Max.X; x++ {
+//				// Process pixel (x, y)
+//			}
+//		}
+//	})
+func (s *Scheduler) Tiles(ctx context.Context, r image.Rectangle, fn func(tile image.Rectangle)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.Empty() {
+		return nil
+	}
+
+	size := s.chunkSize()
+	if size <= 0 {
+		size = defaultTileSize
+	}
+	cols := (r.Dx() + size - 1) / size
+	rows := (r.Dy() + size - 1) / size
+
+	return s.dispatch(ctx, cols*rows, r.Dx()*r.Dy(), func(i int) int {
+		origin := r.Min.Add(image.Pt(i%cols*size, i/cols*size))
+		tile := image.Rectangle{Min: origin, Max: origin.Add(image.Pt(size, size))}.Intersect(r)
+		fn(tile)
+		return tile.Dx() * tile.Dy()
+	})
+}
+
+// workers returns the number of goroutines of the scheduler when running on the executor e.
+func (s *Scheduler) workers(e *Executor) int {
+	if s == nil || s.Workers <= 0 {
+		return e.MaxWorkers()
+	}
+	if e == nil {
+		return s.Workers
+	}
+	return min(s.Workers, e.MaxWorkers())
+}
+
+// chunkSize returns the chunk size of the scheduler, 0 or less standing for the default one.
+func (s *Scheduler) chunkSize() int {
+	if s == nil {
+		return 0
+	}
+	return s.ChunkSize
+}
+
+// dispatch runs the chunks 0 to n-1 with run, which returns the amount of work done by the chunk out of
+// the total. Chunks are only started while ctx isn't done. The calling goroutine works on the chunks too,
+// helped by as many goroutines as the executor of ctx provides.
+func (s *Scheduler) dispatch(ctx context.Context, n, total int, run func(i int) int) error {
+	e := executorFrom(ctx)
+	workers := 1 + e.acquire(min(s.workers(e), n)-1)
+	report := progressReporter(ctx, total)
+
+	if workers <= 1 {
+		for i := 0; i < n && ctx.Err() == nil; i++ {
+			report(run(i))
+		}
+	} else {
+		// Each worker owns a contiguous range of chunks, which keeps neighbouring chunks on the same
+		// goroutine until there is nothing left to steal
+		queues := make([]chunkQueue, workers)
+		for w := range queues {
+			queues[w] = chunkQueue{next: w * n / workers, end: (w + 1) * n / workers}
+		}
+
+		work := func(w int) {
+			for ctx.Err() == nil {
+				i, ok := queues[w].takeFirst()
+				for v := 1; !ok && v < workers; v++ {
+					i, ok = queues[(w+v)%workers].takeLast()
+				}
+				if !ok {
+					return
+				}
+				report(run(i))
+			}
+		}
+
+		var wg sync.WaitGroup
+		for w := 1; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer e.release()
+				work(w)
+			}()
+		}
+		work(0)
+		wg.Wait()
+	}
+
+	if report(0) < total {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// chunkQueue is the range of chunks left to a worker, which it takes from the start while other
+// workers steal them from the end.
+type chunkQueue struct {
+	mu        sync.Mutex
+	next, end int
+}
+
+// takeFirst removes the first chunk of the queue, returning false if it's empty.
+func (q *chunkQueue) takeFirst() (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.next >= q.end {
+		return 0, false
+	}
+	q.next++
+	return q.next - 1, true
+}
+
+// takeLast removes the last chunk of the queue, returning false if it's empty.
+func (q *chunkQueue) takeLast() (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.next >= q.end {
+		return 0, false
+	}
+	q.end--
+	return q.end, true
+}
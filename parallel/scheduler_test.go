@@ -0,0 +1,135 @@
+package parallel
+
+import (
+	"context"
+	"image"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerLine(t *testing.T) {
+	cases := []struct {
+		description string
+		scheduler   *Scheduler
+	}{
+		{"nil", nil},
+		{"serial", &Scheduler{Workers: 1}},
+		{"chunks of 1", &Scheduler{Workers: 3, ChunkSize: 1}},
+		{"chunks of 7", &Scheduler{Workers: 8, ChunkSize: 7}},
+	}
+
+	for _, c := range cases {
+		for n := 0; n < 300; n++ {
+			data := make([]int32, n)
+			err := c.scheduler.Line(context.Background(), n, func(start, end int) {
+				if c.scheduler != nil && c.scheduler.ChunkSize > 0 && end-start > c.scheduler.ChunkSize {
+					t.Errorf("%s: expected: %v actual: %v", "Scheduler.Line chunk size "+c.description, c.scheduler.ChunkSize, end-start)
+				}
+				for i := start; i < end; i++ {
+					atomic.AddInt32(&data[i], 1)
+				}
+			})
+			if err != nil {
+				t.Fatalf("%s: expected: %v actual: %v", "Scheduler.Line "+c.description, nil, err)
+			}
+
+			for i, d := range data {
+				if d != 1 {
+					t.Fatalf("%s: expected: %v actual: %v at %v of %v", "Scheduler.Line "+c.description, 1, d, i, n)
+				}
+			}
+		}
+	}
+}
+
+func TestSchedulerWorkStealing(t *testing.T) {
+	// The chunks owned by the first worker are slow, so the second one steals the last of them
+	// before the first worker gets halfway through
+	s := &Scheduler{Workers: 2, ChunkSize: 1}
+	var halfway, stolen atomic.Bool
+
+	err := s.Line(context.Background(), 20, func(start, end int) {
+		if start == 9 && !halfway.Load() {
+			stolen.Store(true)
+		}
+		if start < 10 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if start == 5 {
+			halfway.Store(true)
+		}
+	})
+	if err != nil || !stolen.Load() {
+		t.Errorf("%s: expected: %v actual: %v, stolen: %v", "Scheduler work stealing", nil, err, stolen.Load())
+	}
+}
+
+func TestSchedulerTiles(t *testing.T) {
+	cases := []struct {
+		description string
+		scheduler   *Scheduler
+		rect        image.Rectangle
+		tiles       int
+	}{
+		{"empty", nil, image.Rect(0, 0, 0, 10), 0},
+		{"default size", nil, image.Rect(0, 0, 130, 64), 3},
+		{"offset", &Scheduler{Workers: 2, ChunkSize: 4}, image.Rect(-3, 2, 7, 11), 9},
+		{"single pixel tiles", &Scheduler{Workers: 4, ChunkSize: 1}, image.Rect(1, 1, 6, 4), 15},
+	}
+
+	for _, c := range cases {
+		covered := make(map[image.Point]int)
+		var tiles atomic.Int64
+		done := make(chan image.Rectangle)
+		go func() {
+			c.scheduler.Tiles(context.Background(), c.rect, func(tile image.Rectangle) {
+				tiles.Add(1)
+				done <- tile
+			})
+			close(done)
+		}()
+		for tile := range done {
+			if !tile.In(c.rect) {
+				t.Errorf("%s: expected: %v actual: %v", "Scheduler.Tiles "+c.description, "tile in "+c.rect.String(), tile)
+			}
+			for y := tile.Min.Y; y < tile.Max.Y; y++ {
+				for x := tile.Min.X; x < tile.Max.X; x++ {
+					covered[image.Pt(x, y)]++
+				}
+			}
+		}
+
+		if int(tiles.Load()) != c.tiles || len(covered) != c.rect.Dx()*c.rect.Dy() {
+			t.Errorf("%s: expected: %v actual: %v tiles covering %v pixels", "Scheduler.Tiles "+c.description, c.tiles, tiles.Load(), len(covered))
+		}
+		for p, n := range covered {
+			if n != 1 {
+				t.Errorf("%s: expected: %v actual: %v at %v", "Scheduler.Tiles "+c.description, 1, n, p)
+			}
+		}
+	}
+}
+
+func TestSchedulerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var pixels atomic.Int64
+	err := TilesCtx(WithScheduler(ctx, &Scheduler{Workers: 2, ChunkSize: 2}), image.Rect(0, 0, 100, 100), func(tile image.Rectangle) {
+		pixels.Add(int64(tile.Dx() * tile.Dy()))
+		cancel()
+	})
+	if err != context.Canceled || pixels.Load() >= 100*100 {
+		t.Errorf("%s: expected: %v actual: %v, pixels: %v", "TilesCtx canceled", context.Canceled, err, pixels.Load())
+	}
+
+	// The scheduler set on the context is used by LineCtx
+	var maxChunk atomic.Int64
+	LineCtx(WithScheduler(context.Background(), &Scheduler{ChunkSize: 3}), 100, func(start, end int) {
+		if int64(end-start) > maxChunk.Load() {
+			maxChunk.Store(int64(end - start))
+		}
+	})
+	if maxChunk.Load() != 3 {
+		t.Errorf("%s: expected: %v actual: %v", "LineCtx with scheduler", 3, maxChunk.Load())
+	}
+}
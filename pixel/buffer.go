@@ -0,0 +1,169 @@
+/*Package pixel provides pixel buffers generic over the sample size and channel count of images.*/
+package pixel
+
+import (
+	"image"
+)
+
+// Sample is the type of the channel values held by a Buffer.
+type Sample interface {
+	~uint8 | ~uint16
+}
+
+// Layout describes the channels of each pixel of a Buffer.
+type Layout uint8
+
+const (
+	// Gray pixels have a single luminance channel.
+	Gray Layout = iota
+	// RGBA pixels have red, green, blue and alpha channels, the colors being premultiplied by the alpha value.
+	RGBA
+	// NRGBA pixels have red, green, blue and alpha channels, the colors not being premultiplied.
+	NRGBA
+)
+
+// Channels returns the number of channels of each pixel in the layout.
+func (l Layout) Channels() int {
+	if l == Gray {
+		return 1
+	}
+	return 4
+}
+
+// Buffer holds the pixels of an image as interleaved channel values of type T.
+type Buffer[T Sample] struct {
+	// Pix holds the channel values of the pixels. The pixel at (x, y) starts
+	// at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*Layout.Channels()].
+	Pix []T
+	// Stride is the Pix stride (in number of samples) between vertically adjacent pixels.
+	Stride int
+	// Rect is the buffer's bounds.
+	Rect image.Rectangle
+	// Layout describes the channels of each pixel.
+	Layout Layout
+}
+
+// NewBuffer returns a new Buffer with the given bounds and layout.
+//
+// Usage example:
+//
+//	buf := pixel.NewBuffer[uint16](image.Rect(0, 0, 640, 480), pixel.NRGBA)
+func NewBuffer[T Sample](r image.Rectangle, l Layout) *Buffer[T] {
+	return &Buffer[T]{
+		Pix:    make([]T, l.Channels()*r.Dx()*r.Dy()),
+		Stride: l.Channels() * r.Dx(),
+		Rect:   r,
+		Layout: l,
+	}
+}
+
+// Channels returns the number of channels of each pixel.
+func (b *Buffer[T]) Channels() int {
+	return b.Layout.Channels()
+}
+
+// Max returns the largest value of a sample, standing for a fully saturated channel.
+func (b *Buffer[T]) Max() T {
+	return ^T(0)
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to the pixel at (x, y).
+func (b *Buffer[T]) PixOffset(x, y int) int {
+	return (y-b.Rect.Min.Y)*b.Stride + (x-b.Rect.Min.X)*b.Channels()
+}
+
+// Clone returns a copy of the buffer which doesn't share its pixels.
+func (b *Buffer[T]) Clone() *Buffer[T] {
+	dst := NewBuffer[T](b.Rect, b.Layout)
+	rowLen := b.Rect.Dx() * b.Channels()
+	for y := 0; y < b.Rect.Dy(); y++ {
+		copy(dst.Pix[y*dst.Stride:y*dst.Stride+rowLen], b.Pix[y*b.Stride:y*b.Stride+rowLen])
+	}
+	return dst
+}
+
+// Image is the set of image types whose pixels can be processed as a Buffer.
+type Image interface {
+	*image.Gray | *image.Gray16 | *image.RGBA | *image.RGBA64 | *image.NRGBA | *image.NRGBA64
+}
+
+// Process runs fn8 on the pixels of an image with 8-bit samples or fn16 on those of an image with
+// 16-bit samples, and returns the resulting buffer as an image of the same type as img.
+// The buffer passed to fn8 shares its pixels with img, so it must not be modified.
+//
+// Usage example:
+//
+//	result := pixel.Process(img,
+//		func(b *pixel.Buffer[uint8]) *pixel.Buffer[uint8] { return invert(b) },
+//		func(b *pixel.Buffer[uint16]) *pixel.Buffer[uint16] { return invert(b) })
+func Process[I Image](img I, fn8 func(*Buffer[uint8]) *Buffer[uint8], fn16 func(*Buffer[uint16]) *Buffer[uint16]) I {
+	var result image.Image
+
+	switch src := any(img).(type) {
+	case *image.Gray:
+		result = toImage8(fn8(&Buffer[uint8]{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect, Layout: Gray}))
+	case *image.RGBA:
+		result = toImage8(fn8(&Buffer[uint8]{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect, Layout: RGBA}))
+	case *image.NRGBA:
+		result = toImage8(fn8(&Buffer[uint8]{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect, Layout: NRGBA}))
+	case *image.Gray16:
+		result = toImage16(fn16(fromBigEndian(src.Pix, src.Stride, src.Rect, Gray)))
+	case *image.RGBA64:
+		result = toImage16(fn16(fromBigEndian(src.Pix, src.Stride, src.Rect, RGBA)))
+	case *image.NRGBA64:
+		result = toImage16(fn16(fromBigEndian(src.Pix, src.Stride, src.Rect, NRGBA)))
+	}
+
+	return result.(I)
+}
+
+// toImage8 returns the image type of the layout holding the pixels of the buffer, which it shares.
+func toImage8(b *Buffer[uint8]) image.Image {
+	switch b.Layout {
+	case Gray:
+		return &image.Gray{Pix: b.Pix, Stride: b.Stride, Rect: b.Rect}
+	case NRGBA:
+		return &image.NRGBA{Pix: b.Pix, Stride: b.Stride, Rect: b.Rect}
+	default:
+		return &image.RGBA{Pix: b.Pix, Stride: b.Stride, Rect: b.Rect}
+	}
+}
+
+// toImage16 returns the 16-bit image type of the layout holding a copy of the pixels of the buffer,
+// stored in big endian byte order.
+func toImage16(b *Buffer[uint16]) image.Image {
+	n := b.Channels()
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	pix := make([]uint8, w*h*n*2)
+	for y := 0; y < h; y++ {
+		src := b.Pix[y*b.Stride : y*b.Stride+w*n]
+		dst := pix[y*w*n*2:]
+		for i, v := range src {
+			dst[i*2+0] = uint8(v >> 8)
+			dst[i*2+1] = uint8(v)
+		}
+	}
+
+	switch b.Layout {
+	case Gray:
+		return &image.Gray16{Pix: pix, Stride: w * 2, Rect: b.Rect}
+	case NRGBA:
+		return &image.NRGBA64{Pix: pix, Stride: w * 8, Rect: b.Rect}
+	default:
+		return &image.RGBA64{Pix: pix, Stride: w * 8, Rect: b.Rect}
+	}
+}
+
+// fromBigEndian returns a new buffer holding the 16-bit samples stored in big endian byte order in pix.
+func fromBigEndian(pix []uint8, stride int, r image.Rectangle, l Layout) *Buffer[uint16] {
+	b := NewBuffer[uint16](r, l)
+	rowLen := r.Dx() * b.Channels()
+	for y := 0; y < r.Dy(); y++ {
+		src := pix[y*stride:]
+		dst := b.Pix[y*b.Stride : y*b.Stride+rowLen]
+		for i := range dst {
+			dst[i] = uint16(src[i*2+0])<<8 | uint16(src[i*2+1])
+		}
+	}
+	return b
+}
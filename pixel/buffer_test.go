@@ -0,0 +1,141 @@
+package pixel
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutChannels(t *testing.T) {
+	cases := []struct {
+		layout   Layout
+		expected int
+	}{
+		{Gray, 1},
+		{RGBA, 4},
+		{NRGBA, 4},
+	}
+
+	for _, c := range cases {
+		if actual := c.layout.Channels(); actual != c.expected {
+			t.Errorf("%s: expected: %v actual: %v", "LayoutChannels", c.expected, actual)
+		}
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer[uint16](image.Rect(-1, 2, 2, 4), NRGBA)
+	if len(b.Pix) != 3*2*4 || b.Stride != 3*4 {
+		t.Errorf("%s: expected: %v actual: %v", "NewBuffer", "24 samples with a stride of 12", b)
+	}
+	if actual := b.Max(); actual != 0xFFFF {
+		t.Errorf("%s: expected: %v actual: %v", "Max 16-bit", 0xFFFF, actual)
+	}
+	if actual := NewBuffer[uint8](image.Rect(0, 0, 1, 1), Gray).Max(); actual != 0xFF {
+		t.Errorf("%s: expected: %v actual: %v", "Max 8-bit", 0xFF, actual)
+	}
+	if actual := b.PixOffset(1, 3); actual != 1*12+2*4 {
+		t.Errorf("%s: expected: %v actual: %v", "PixOffset", 1*12+2*4, actual)
+	}
+
+	b.Pix[b.PixOffset(0, 2)] = 0x1234
+	clone := b.Clone()
+	clone.Pix[0] = 0
+	if !reflect.DeepEqual(clone.Rect, b.Rect) || clone.Layout != b.Layout || b.Pix[b.PixOffset(0, 2)] != 0x1234 {
+		t.Errorf("%s: expected: %v actual: %v", "Clone", b, clone)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	gray16 := image.NewGray16(image.Rect(1, 1, 3, 2))
+	gray16.SetGray16(1, 1, color.Gray16{0x1234})
+	gray16.SetGray16(2, 1, color.Gray16{0xFEDC})
+	nrgba64 := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	nrgba64.SetNRGBA64(0, 0, color.NRGBA64{0x0102, 0x0304, 0x0506, 0x0708})
+
+	// Each image is processed as a copy of its buffer, which must keep its pixels and type
+	cases := []struct {
+		description string
+		value       image.Image
+		layout      Layout
+		samples     []uint16
+	}{
+		{
+			description: "Gray",
+			value:       &image.Gray{Pix: []uint8{1, 2}, Stride: 2, Rect: image.Rect(0, 0, 2, 1)},
+			layout:      Gray,
+			samples:     []uint16{1, 2},
+		},
+		{
+			description: "RGBA",
+			value:       &image.RGBA{Pix: []uint8{1, 2, 3, 4}, Stride: 4, Rect: image.Rect(0, 0, 1, 1)},
+			layout:      RGBA,
+			samples:     []uint16{1, 2, 3, 4},
+		},
+		{
+			description: "NRGBA",
+			value:       &image.NRGBA{Pix: []uint8{5, 6, 7, 8}, Stride: 4, Rect: image.Rect(0, 0, 1, 1)},
+			layout:      NRGBA,
+			samples:     []uint16{5, 6, 7, 8},
+		},
+		{
+			description: "Gray16",
+			value:       gray16,
+			layout:      Gray,
+			samples:     []uint16{0x1234, 0xFEDC},
+		},
+		{
+			description: "RGBA64",
+			value:       &image.RGBA64{Pix: []uint8{1, 2, 3, 4, 5, 6, 7, 8}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+			layout:      RGBA,
+			samples:     []uint16{0x0102, 0x0304, 0x0506, 0x0708},
+		},
+		{
+			description: "NRGBA64",
+			value:       nrgba64,
+			layout:      NRGBA,
+			samples:     []uint16{0x0102, 0x0304, 0x0506, 0x0708},
+		},
+	}
+
+	for _, c := range cases {
+		var layout Layout
+		var samples []uint16
+		fn8 := func(b *Buffer[uint8]) *Buffer[uint8] {
+			layout = b.Layout
+			for _, v := range b.Pix {
+				samples = append(samples, uint16(v))
+			}
+			return b.Clone()
+		}
+		fn16 := func(b *Buffer[uint16]) *Buffer[uint16] {
+			layout = b.Layout
+			samples = append(samples, b.Pix...)
+			return b.Clone()
+		}
+
+		var actual image.Image
+		switch img := c.value.(type) {
+		case *image.Gray:
+			actual = Process(img, fn8, fn16)
+		case *image.RGBA:
+			actual = Process(img, fn8, fn16)
+		case *image.NRGBA:
+			actual = Process(img, fn8, fn16)
+		case *image.Gray16:
+			actual = Process(img, fn8, fn16)
+		case *image.RGBA64:
+			actual = Process(img, fn8, fn16)
+		case *image.NRGBA64:
+			actual = Process(img, fn8, fn16)
+		}
+
+		if layout != c.layout || !reflect.DeepEqual(samples, c.samples) {
+			t.Errorf("%s: expected: %v %v actual: %v %v", "Process "+c.description, c.layout, c.samples, layout, samples)
+		}
+		if !reflect.DeepEqual(actual, c.value) {
+			t.Errorf("%s: expected: %#v actual: %#v", "Process "+c.description, c.value, actual)
+		}
+	}
+}
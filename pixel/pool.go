@@ -0,0 +1,43 @@
+package pixel
+
+import (
+	"image"
+	"sync"
+)
+
+// RGBAPool holds images which are no longer used so that they can be reused, rather than allocating
+// a new image for each of a steady stream of operations. The zero value is ready to use, and an
+// RGBAPool is safe for concurrent use.
+type RGBAPool struct {
+	pool sync.Pool
+}
+
+// Get returns an image with the bounds r, reusing the pixels of an image put back into the pool if
+// any is large enough. The pixels of a reused image are left as they were, so they must be overwritten.
+//
+// Usage example:
+//
+//	var pool pixel.RGBAPool
+//	dst := pool.Get(image.Rect(0, 0, 800, 600))
+//	transform.ResizeInto(dst, img, transform.Linear)
+//	// Encode dst...
+//	pool.Put(dst)
+func (p *RGBAPool) Get(r image.Rectangle) *image.RGBA {
+	n := 4 * r.Dx() * r.Dy()
+	img, _ := p.pool.Get().(*image.RGBA)
+	if img == nil {
+		return image.NewRGBA(r)
+	}
+	if cap(img.Pix) < n {
+		img.Pix = make([]uint8, n)
+	}
+	img.Pix, img.Stride, img.Rect = img.Pix[:n], 4*r.Dx(), r
+	return img
+}
+
+// Put puts an image back into the pool for Get to reuse. The image must not be used after that.
+func (p *RGBAPool) Put(img *image.RGBA) {
+	if img != nil {
+		p.pool.Put(img)
+	}
+}
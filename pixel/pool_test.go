@@ -0,0 +1,33 @@
+package pixel
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRGBAPool(t *testing.T) {
+	var pool RGBAPool
+
+	img := pool.Get(image.Rect(1, 2, 5, 4))
+	if !img.Rect.Eq(image.Rect(1, 2, 5, 4)) || img.Stride != 16 || len(img.Pix) != 32 {
+		t.Errorf("%s: expected: %v actual: %v", "RGBAPool Get", "4x2 image at (1, 2)", img.Rect)
+	}
+
+	// Images put back are reused as long as they're large enough, the sync.Pool possibly dropping them
+	img.Pix[0] = 7
+	pool.Put(img)
+	smaller := pool.Get(image.Rect(0, 0, 2, 3))
+	if !smaller.Rect.Eq(image.Rect(0, 0, 2, 3)) || smaller.Stride != 8 || len(smaller.Pix) != 24 {
+		t.Errorf("%s: expected: %v actual: %v", "RGBAPool Get smaller", "2x3 image", smaller.Rect)
+	}
+	pool.Put(smaller)
+	larger := pool.Get(image.Rect(0, 0, 10, 10))
+	if !larger.Rect.Eq(image.Rect(0, 0, 10, 10)) || larger.Stride != 40 || len(larger.Pix) != 400 {
+		t.Errorf("%s: expected: %v actual: %v", "RGBAPool Get larger", "10x10 image", larger.Rect)
+	}
+
+	pool.Put(nil)
+	if img := pool.Get(image.Rect(0, 0, 1, 1)); img == nil || len(img.Pix) != 4 {
+		t.Errorf("%s: expected: %v actual: %v", "RGBAPool Put nil", "1x1 image", img)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"image/color"
 
 	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/pixel"
 	"github.com/anthonynsimon/bild/util"
 )
 
@@ -43,3 +45,59 @@ func Threshold(img image.Image, level uint8) *image.Gray {
 
 	return dst
 }
+
+// ThresholdNative returns a copy of the image in which pixels whose rank is smaller than the param
+// level are set to black and the others to white, running directly on its pixels and returning an
+// image of the same type. Transparent pixels are set to white, and the result is fully opaque.
+// Level is normalized to the range 0.0 to 1.0 of the channel values, so that 16-bit images keep their precision.
+//
+// Usage example:
+//
+//	result := segment.ThresholdNative(gray16, 0.5)
+func ThresholdNative[I pixel.Image](img I, level float64) I {
+	return pixel.Process(img, thresholdBuffer[uint8](level), thresholdBuffer[uint16](level))
+}
+
+// thresholdBuffer returns the function applying the threshold of the normalized level to a pixel buffer.
+func thresholdBuffer[T pixel.Sample](level float64) func(*pixel.Buffer[T]) *pixel.Buffer[T] {
+	return func(src *pixel.Buffer[T]) *pixel.Buffer[T] {
+		n := src.Channels()
+		maxValue := src.Max()
+		threshold := level * float64(maxValue)
+		dst := pixel.NewBuffer[T](src.Rect, src.Layout)
+
+		parallel.Line(src.Rect.Dy(), func(start, end int) {
+			for y := start; y < end; y++ {
+				for x := 0; x < src.Rect.Dx(); x++ {
+					srcPos := y*src.Stride + x*n
+					dstPos := y*dst.Stride + x*n
+					c := src.Pix[srcPos : srcPos+n : srcPos+n]
+
+					value := maxValue
+					if n == 1 {
+						if float64(c[0]) < threshold {
+							value = 0
+						}
+						dst.Pix[dstPos] = value
+						continue
+					}
+
+					// Colors are ranked premultiplied, and transparent pixels are always white
+					rank := float64(c[0])*0.3 + float64(c[1])*0.6 + float64(c[2])*0.1
+					if src.Layout == pixel.NRGBA {
+						rank = rank * float64(c[3]) / float64(maxValue)
+					}
+					if c[3] != 0 && rank < threshold {
+						value = 0
+					}
+					dst.Pix[dstPos+0] = value
+					dst.Pix[dstPos+1] = value
+					dst.Pix[dstPos+2] = value
+					dst.Pix[dstPos+3] = maxValue
+				}
+			}
+		})
+
+		return dst
+	}
+}
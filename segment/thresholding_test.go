@@ -2,6 +2,7 @@ package segment
 
 import (
 	"image"
+	"reflect"
 	"testing"
 
 	"github.com/anthonynsimon/bild/util"
@@ -98,3 +99,71 @@ func TestThreshold(t *testing.T) {
 		}
 	}
 }
+
+func TestThresholdNative(t *testing.T) {
+	gray := &image.Gray{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3,
+		Pix:    []uint8{0x7F, 0x80, 0xFF},
+	}
+	expectedGray := &image.Gray{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3,
+		Pix:    []uint8{0x00, 0xFF, 0xFF},
+	}
+	if actual := ThresholdNative(gray, 128.0/255); !util.GrayImageEqual(actual, expectedGray) {
+		t.Errorf("%s: expected: %v actual: %v", "ThresholdNative Gray", expectedGray.Pix, actual.Pix)
+	}
+
+	// Values between those of 8 bits are kept apart
+	gray16 := &image.Gray16{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 2,
+		Pix:    []uint8{0x80, 0x00, 0x80, 0x01},
+	}
+	expectedGray16 := &image.Gray16{
+		Rect:   image.Rect(0, 0, 2, 1),
+		Stride: 2 * 2,
+		Pix:    []uint8{0x00, 0x00, 0xFF, 0xFF},
+	}
+	if actual := ThresholdNative(gray16, float64(0x8001)/0xFFFF); !reflect.DeepEqual(actual, expectedGray16) {
+		t.Errorf("%s: expected: %v actual: %v", "ThresholdNative Gray16", expectedGray16.Pix, actual.Pix)
+	}
+
+	// Colors are ranked premultiplied, transparent pixels being white
+	nrgba := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0xFF, 0xFF, 0xFF, 0x40, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00,
+		},
+	}
+	expectedNRGBA := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		},
+	}
+	if actual := ThresholdNative(nrgba, 0.5); !reflect.DeepEqual(actual, expectedNRGBA) {
+		t.Errorf("%s: expected: %v actual: %v", "ThresholdNative NRGBA", expectedNRGBA.Pix, actual.Pix)
+	}
+
+	// Matches the RGBA threshold
+	rgba := &image.RGBA{
+		Rect:   image.Rect(0, 0, 2, 2),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+			0xFF, 0xFF, 0xFF, 0xFF, 0x7F, 0x7F, 0x7F, 0x80,
+		},
+	}
+	expected := Threshold(rgba, 0x80)
+	actual := ThresholdNative(rgba, 128.0/255)
+	for i, v := range expected.Pix {
+		if actual.Pix[i*4] != v {
+			t.Errorf("%s: expected: %v actual: %v", "ThresholdNative RGBA", expected.Pix, actual.Pix)
+			break
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+)
+
+// Affine is a 2D affine transformation matrix stored in row-major order, where the
+// implicit last row is 0, 0, 1. A point x, y is transformed into:
+//
+//	x' = m[0]*x + m[1]*y + m[2]
+//	y' = m[3]*x + m[4]*y + m[5]
+//
+// Point coordinates are continuous, so the top-left corner of the image is at 0, 0 and the
+// center of pixel x, y is at x+0.5, y+0.5.
+// Transformations can be chained by calling the methods in the order they should be applied.
+//
+// Usage example:
+//
+//	// Rotate 30 degrees clockwise around the point 100, 50 and then scale by 2
+//	m := transform.IdentityAffine().Translate(-100, -50).Rotate(30).Translate(100, 50).Scale(2, 2)
+type Affine [6]float64
+
+// IdentityAffine returns the affine transformation that leaves every point in place.
+func IdentityAffine() Affine {
+	return Affine{1, 0, 0, 0, 1, 0}
+}
+
+// Mul returns the affine transformation that results of first applying n and then m.
+func (m Affine) Mul(n Affine) Affine {
+	return Affine{
+		m[0]*n[0] + m[1]*n[3],
+		m[0]*n[1] + m[1]*n[4],
+		m[0]*n[2] + m[1]*n[5] + m[2],
+		m[3]*n[0] + m[4]*n[3],
+		m[3]*n[1] + m[4]*n[4],
+		m[3]*n[2] + m[4]*n[5] + m[5],
+	}
+}
+
+// Translate returns the affine transformation that applies m and then moves
+// the result by dx on the x-axis and by dy on the y-axis.
+func (m Affine) Translate(dx, dy float64) Affine {
+	return Affine{1, 0, dx, 0, 1, dy}.Mul(m)
+}
+
+// Scale returns the affine transformation that applies m and then scales
+// the result by sx on the x-axis and by sy on the y-axis, relative to the origin.
+func (m Affine) Scale(sx, sy float64) Affine {
+	return Affine{sx, 0, 0, 0, sy, 0}.Mul(m)
+}
+
+// Rotate returns the affine transformation that applies m and then rotates
+// the result around the origin. Parameter angle is in degrees and it's applied clockwise.
+func (m Affine) Rotate(angle float64) Affine {
+	sin, cos := math.Sincos(angle * (math.Pi / 180))
+	return Affine{cos, -sin, 0, sin, cos, 0}.Mul(m)
+}
+
+// Shear returns the affine transformation that applies m and then shears the result
+// by the factor kx along the x-axis and by ky along the y-axis, relative to the origin.
+func (m Affine) Shear(kx, ky float64) Affine {
+	return Affine{1, kx, 0, ky, 1, 0}.Mul(m)
+}
+
+// Det returns the determinant of the affine transformation matrix.
+func (m Affine) Det() float64 {
+	return m[0]*m[4] - m[1]*m[3]
+}
+
+// Invert returns the affine transformation that reverts m.
+// Returns false if m is not invertible, for example when scaling by zero.
+func (m Affine) Invert() (Affine, bool) {
+	det := m.Det()
+	if math.Abs(det) < 1e-12 {
+		return Affine{}, false
+	}
+
+	return Affine{
+		m[4] / det,
+		-m[1] / det,
+		(m[1]*m[5] - m[2]*m[4]) / det,
+		-m[3] / det,
+		m[0] / det,
+		(m[2]*m[3] - m[0]*m[5]) / det,
+	}, true
+}
+
+// Apply returns the position of the point x, y after being transformed by m.
+func (m Affine) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]
+}
+
+// AffineOptions are the affine warp parameters.
+// Filter is the resampling filter used when interpolating between the sample points.
+// Default of Linear is used if nil is passed.
+// Bounds is the area of the destination plane to be rendered, the result image will have its size
+// and the top-left corner of Bounds will be located at the origin. Default of the source image bounds
+// is used if an empty rectangle is passed.
+// Background is the color used for the pixels that fall outside of the source image.
+// Default of transparent is used if nil is passed.
+type AffineOptions struct {
+	Filter     *ResampleFilter
+	Bounds     image.Rectangle
+	Background color.Color
+}
+
+// AffineWarp returns a new image with the affine transformation m applied to it.
+// The transformation is done in a single resampling pass, so transformations should be combined
+// into a single Affine rather than applying them one after the other.
+// Default parameters are used if a nil *AffineOptions is passed.
+// If m is not invertible the result is filled with the background color.
+//
+// Usage example:
+//
+//	// Rotate 30 degrees clockwise around the center of a 200x100 image using the Lanczos filter
+//	m := transform.IdentityAffine().Translate(-100, -50).Rotate(30).Translate(100, 50)
+//	result := transform.AffineWarp(img, m, &transform.AffineOptions{Filter: &transform.Lanczos})
+func AffineWarp(img image.Image, m Affine, options *AffineOptions) *image.RGBA {
+	src := clone.AsShallowRGBA(img)
+
+	filter := Linear
+	bounds := image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy())
+	bg := color.RGBA{}
+	if options != nil {
+		if options.Filter != nil {
+			filter = *options.Filter
+		}
+		if !options.Bounds.Empty() {
+			bounds = options.Bounds
+		}
+		if options.Background != nil {
+			bg = color.RGBAModel.Convert(options.Background).(color.RGBA)
+		}
+	}
+
+	// Each destination pixel is mapped back to its position in the source image
+	inv, ok := m.Invert()
+	if !ok {
+		return warp(src, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64, bool) {
+			return 0, 0, false
+		}, filter, bg)
+	}
+
+	offsetX, offsetY := float64(bounds.Min.X), float64(bounds.Min.Y)
+
+	return warp(src, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64, bool) {
+		ix, iy := inv.Apply(x+offsetX, y+offsetY)
+		return ix, iy, true
+	}, filter, bg)
+}
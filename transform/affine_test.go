@@ -0,0 +1,195 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestAffineApply(t *testing.T) {
+	cases := []struct {
+		description string
+		m           Affine
+		x, y        float64
+		expectedX   float64
+		expectedY   float64
+	}{
+		{
+			description: "identity",
+			m:           IdentityAffine(),
+			x:           3, y: 4,
+			expectedX: 3, expectedY: 4,
+		},
+		{
+			description: "translate",
+			m:           IdentityAffine().Translate(2, -1),
+			x:           3, y: 4,
+			expectedX: 5, expectedY: 3,
+		},
+		{
+			description: "scale",
+			m:           IdentityAffine().Scale(2, 0.5),
+			x:           3, y: 4,
+			expectedX: 6, expectedY: 2,
+		},
+		{
+			description: "rotate 90 clockwise",
+			m:           IdentityAffine().Rotate(90),
+			x:           1, y: 0,
+			expectedX: 0, expectedY: 1,
+		},
+		{
+			description: "shear",
+			m:           IdentityAffine().Shear(1, 0),
+			x:           1, y: 2,
+			expectedX: 3, expectedY: 2,
+		},
+		{
+			description: "translate then scale",
+			m:           IdentityAffine().Translate(1, 1).Scale(2, 2),
+			x:           1, y: 2,
+			expectedX: 4, expectedY: 6,
+		},
+		{
+			description: "scale then translate",
+			m:           IdentityAffine().Scale(2, 2).Translate(1, 1),
+			x:           1, y: 2,
+			expectedX: 3, expectedY: 5,
+		},
+	}
+
+	for _, c := range cases {
+		x, y := c.m.Apply(c.x, c.y)
+		if math.Abs(x-c.expectedX) > 1e-9 || math.Abs(y-c.expectedY) > 1e-9 {
+			t.Errorf("%s: expected: %v, %v actual: %v, %v", "Affine.Apply "+c.description, c.expectedX, c.expectedY, x, y)
+		}
+	}
+}
+
+func TestAffineInvert(t *testing.T) {
+	m := IdentityAffine().Translate(-5, 3).Rotate(33).Scale(1.5, 0.75).Shear(0.2, 0)
+
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatal("Affine.Invert: expected matrix to be invertible")
+	}
+
+	x, y := inv.Apply(m.Apply(12, -7))
+	if math.Abs(x-12) > 1e-9 || math.Abs(y+7) > 1e-9 {
+		t.Errorf("Affine.Invert: expected: 12, -7 actual: %v, %v", x, y)
+	}
+
+	if _, ok := IdentityAffine().Scale(0, 1).Invert(); ok {
+		t.Error("Affine.Invert: expected singular matrix not to be invertible")
+	}
+}
+
+func TestAffineWarp(t *testing.T) {
+	cases := []struct {
+		description string
+		m           Affine
+		options     *AffineOptions
+		value       image.Image
+		expected    *image.RGBA
+	}{
+		{
+			description: "identity",
+			m:           IdentityAffine(),
+			options:     nil,
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+		},
+		{
+			description: "half pixel translation",
+			m:           IdentityAffine().Translate(0.5, 0),
+			options:     &AffineOptions{Filter: &Linear, Bounds: image.Rect(0, 0, 3, 1)},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 12,
+				Pix: []uint8{
+					0x40, 0x40, 0x40, 0x80, 0x60, 0x60, 0x60, 0xFF, 0x20, 0x20, 0x20, 0x80,
+				},
+			},
+		},
+		{
+			description: "scale 2x with bounds offset",
+			m:           IdentityAffine().Scale(2, 2),
+			options:     &AffineOptions{Filter: &NearestNeighbor, Bounds: image.Rect(2, 0, 4, 2)},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+				},
+			},
+		},
+		{
+			description: "singular matrix with background",
+			m:           IdentityAffine().Scale(0, 0),
+			options:     &AffineOptions{Background: color.RGBA{0xFF, 0x00, 0x00, 0xFF}},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 8,
+				Pix: []uint8{
+					0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00, 0xFF,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := AffineWarp(c.value, c.m, c.options)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s:\nexpected:%v\nactual:%v", "AffineWarp "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
+		}
+	}
+}
+
+func BenchmarkAffineWarp(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1024, 1024))
+	m := IdentityAffine().Translate(-512, -512).Rotate(30).Scale(0.8, 0.8).Translate(512, 512)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = AffineWarp(img, m, nil)
+	}
+}
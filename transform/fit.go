@@ -0,0 +1,128 @@
+package transform
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/math/integer"
+)
+
+// Anchor is the position of the area to be kept when cropping an image.
+type Anchor int
+
+// Anchor positions, relative to the source image.
+const (
+	Center Anchor = iota
+	TopLeft
+	Top
+	TopRight
+	Left
+	Right
+	BottomLeft
+	Bottom
+	BottomRight
+)
+
+// Fit returns a new image scaled to be as large as possible while fitting inside the width and height box,
+// preserving the aspect ratio of the source image. One of the result dimensions might be smaller than the box.
+// The filter param corresponds to the Resampling Filter to be used when interpolating between the sample points.
+//
+// Usage example:
+//
+//	// A 1600x900 image results in a 800x450 image
+//	result := transform.Fit(img, 800, 600, transform.Lanczos)
+func Fit(img image.Image, width, height int, filter ResampleFilter) *image.RGBA {
+	if width <= 0 || height <= 0 || img.Bounds().Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	w, h := fitSize(img.Bounds().Dx(), img.Bounds().Dy(), width, height)
+	return Resize(img, w, h, filter)
+}
+
+// Fill returns a new image of exactly the width and height provided, scaled to cover the whole area while
+// preserving the aspect ratio of the source image. The part of the image that doesn't fit is cropped,
+// keeping the area at the position given by anchor.
+// The filter param corresponds to the Resampling Filter to be used when interpolating between the sample points.
+//
+// Usage example:
+//
+//	// Crop the sides of a 1600x900 image, keeping its center, and scale it to 600x600
+//	result := transform.Fill(img, 600, 600, transform.Center, transform.Lanczos)
+func Fill(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.RGBA {
+	if width <= 0 || height <= 0 || img.Bounds().Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	// Crop the source image to the aspect ratio of the result first,
+	// so that no pixels are resampled only to be discarded afterwards.
+	scale := math.Max(float64(width)/float64(srcW), float64(height)/float64(srcH))
+	cropW := integer.Clamp(int(float64(width)/scale+0.5), 1, srcW)
+	cropH := integer.Clamp(int(float64(height)/scale+0.5), 1, srcH)
+
+	origin := anchorPoint(anchor, srcW, srcH, cropW, cropH).Add(bounds.Min)
+	src := clone.AsShallowRGBA(img).SubImage(image.Rectangle{origin, origin.Add(image.Pt(cropW, cropH))})
+
+	return Resize(src, width, height, filter)
+}
+
+// Thumbnail returns a new image scaled down to fit inside the width and height box, preserving the
+// aspect ratio of the source image as Fit does. Images that already fit inside the box are not enlarged.
+// The filter param corresponds to the Resampling Filter to be used when interpolating between the sample points.
+//
+// Usage example:
+//
+//	result := transform.Thumbnail(img, 128, 128, transform.Box)
+func Thumbnail(img image.Image, width, height int, filter ResampleFilter) *image.RGBA {
+	if width <= 0 || height <= 0 || img.Bounds().Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	if srcW <= width && srcH <= height {
+		dst := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+		draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+		return dst
+	}
+
+	return Fit(img, width, height, filter)
+}
+
+// fitSize returns the largest size with the aspect ratio of srcW and srcH that fits inside width and height.
+func fitSize(srcW, srcH, width, height int) (int, int) {
+	scale := math.Min(float64(width)/float64(srcW), float64(height)/float64(srcH))
+	w := integer.Clamp(int(float64(srcW)*scale+0.5), 1, width)
+	h := integer.Clamp(int(float64(srcH)*scale+0.5), 1, height)
+	return w, h
+}
+
+// anchorPoint returns the top-left corner of an area of size w and h
+// placed inside an area of size outerW and outerH at the anchor position.
+func anchorPoint(anchor Anchor, outerW, outerH, w, h int) image.Point {
+	var x, y int
+
+	switch anchor {
+	case TopLeft, Left, BottomLeft:
+		x = 0
+	case TopRight, Right, BottomRight:
+		x = outerW - w
+	default:
+		x = (outerW - w) / 2
+	}
+
+	switch anchor {
+	case TopLeft, Top, TopRight:
+		y = 0
+	case BottomLeft, Bottom, BottomRight:
+		y = outerH - h
+	default:
+		y = (outerH - h) / 2
+	}
+
+	return image.Pt(x, y)
+}
@@ -0,0 +1,187 @@
+package transform
+
+import (
+	"image"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestFit(t *testing.T) {
+	cases := []struct {
+		description    string
+		width, height  int
+		value          image.Image
+		expectedBounds image.Rectangle
+	}{
+		{
+			description:    "landscape into square box",
+			width:          100,
+			height:         100,
+			value:          image.NewRGBA(image.Rect(0, 0, 400, 200)),
+			expectedBounds: image.Rect(0, 0, 100, 50),
+		},
+		{
+			description:    "portrait into landscape box",
+			width:          300,
+			height:         100,
+			value:          image.NewRGBA(image.Rect(0, 0, 200, 400)),
+			expectedBounds: image.Rect(0, 0, 50, 100),
+		},
+		{
+			description:    "enlarge small image",
+			width:          100,
+			height:         100,
+			value:          image.NewRGBA(image.Rect(0, 0, 20, 10)),
+			expectedBounds: image.Rect(0, 0, 100, 50),
+		},
+		{
+			description:    "very thin image keeps at least one pixel",
+			width:          10,
+			height:         10,
+			value:          image.NewRGBA(image.Rect(0, 0, 1000, 1)),
+			expectedBounds: image.Rect(0, 0, 10, 1),
+		},
+		{
+			description:    "empty box",
+			width:          0,
+			height:         100,
+			value:          image.NewRGBA(image.Rect(0, 0, 20, 10)),
+			expectedBounds: image.Rect(0, 0, 0, 0),
+		},
+	}
+
+	for _, c := range cases {
+		actual := Fit(c.value, c.width, c.height, Linear)
+		if actual.Bounds() != c.expectedBounds {
+			t.Errorf("%s: expected: %v actual: %v", "Fit "+c.description, c.expectedBounds, actual.Bounds())
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	// Each column of the source image holds a different color
+	value := &image.RGBA{
+		Rect:   image.Rect(0, 0, 4, 2),
+		Stride: 16,
+		Pix: []uint8{
+			0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF, 0x30, 0x30, 0x30, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+			0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF, 0x30, 0x30, 0x30, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+		},
+	}
+
+	cases := []struct {
+		description string
+		width       int
+		height      int
+		anchor      Anchor
+		value       image.Image
+		expected    *image.RGBA
+	}{
+		{
+			description: "center",
+			width:       2,
+			height:      2,
+			anchor:      Center,
+			value:       value,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x20, 0x20, 0x20, 0xFF, 0x30, 0x30, 0x30, 0xFF,
+					0x20, 0x20, 0x20, 0xFF, 0x30, 0x30, 0x30, 0xFF,
+				},
+			},
+		},
+		{
+			description: "top-left",
+			width:       2,
+			height:      2,
+			anchor:      TopLeft,
+			value:       value,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF,
+					0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF,
+				},
+			},
+		},
+		{
+			description: "right, scaled down",
+			width:       1,
+			height:      1,
+			anchor:      Right,
+			value:       value,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 1, 1),
+				Stride: 4,
+				Pix: []uint8{
+					0x38, 0x38, 0x38, 0xFF,
+				},
+			},
+		},
+		{
+			description: "sub-image with offset bounds",
+			width:       1,
+			height:      2,
+			anchor:      BottomLeft,
+			value:       value.SubImage(image.Rect(1, 0, 4, 2)),
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 1, 2),
+				Stride: 4,
+				Pix: []uint8{
+					0x20, 0x20, 0x20, 0xFF,
+					0x20, 0x20, 0x20, 0xFF,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := Fill(c.value, c.width, c.height, c.anchor, Linear)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s:\nexpected:%v\nactual:%v", "Fill "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	cases := []struct {
+		description    string
+		width, height  int
+		value          image.Image
+		expectedBounds image.Rectangle
+	}{
+		{
+			description:    "scale down",
+			width:          64,
+			height:         64,
+			value:          image.NewRGBA(image.Rect(0, 0, 256, 128)),
+			expectedBounds: image.Rect(0, 0, 64, 32),
+		},
+		{
+			description:    "small image is not enlarged",
+			width:          64,
+			height:         64,
+			value:          image.NewRGBA(image.Rect(10, 10, 42, 26)),
+			expectedBounds: image.Rect(0, 0, 32, 16),
+		},
+	}
+
+	for _, c := range cases {
+		actual := Thumbnail(c.value, c.width, c.height, Linear)
+		if actual.Bounds() != c.expectedBounds {
+			t.Errorf("%s: expected: %v actual: %v", "Thumbnail "+c.description, c.expectedBounds, actual.Bounds())
+		}
+	}
+}
+
+func BenchmarkFill(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1600, 900))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = Fill(img, 256, 256, Center, Linear)
+	}
+}
@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+)
+
+// LensCoefficients are the Brown–Conrady lens distortion coefficients.
+// K1, K2 and K3 are the radial coefficients, negative values correspond to barrel distortion
+// and positive values to pincushion distortion. P1 and P2 are the tangential coefficients,
+// which model a lens that isn't parallel to the sensor.
+// The coefficients apply to positions normalized so that the distance from the
+// center of the image to its corners is 1.
+type LensCoefficients struct {
+	K1, K2, K3 float64
+	P1, P2     float64
+}
+
+// distort returns the position of the undistorted normalized point x, y as seen through the lens.
+func (c LensCoefficients) distort(x, y float64) (float64, float64) {
+	r2 := x*x + y*y
+	radial := 1 + r2*(c.K1+r2*(c.K2+r2*c.K3))
+	return x*radial + 2*c.P1*x*y + c.P2*(r2+2*x*x),
+		y*radial + c.P1*(r2+2*y*y) + 2*c.P2*x*y
+}
+
+// undistort returns the undistorted position of the normalized point x, y as seen through the lens.
+// The model has no closed form inverse, so it's solved by fixed-point iteration.
+// Returns false if the iteration doesn't converge.
+func (c LensCoefficients) undistort(x, y float64) (float64, float64, bool) {
+	ux, uy := x, y
+	for i := 0; i < 20; i++ {
+		dx, dy := c.distort(ux, uy)
+		ex, ey := dx-x, dy-y
+		if math.Abs(ex) < 1e-9 && math.Abs(ey) < 1e-9 {
+			return ux, uy, true
+		}
+		ux, uy = ux-ex, uy-ey
+	}
+
+	dx, dy := c.distort(ux, uy)
+	return ux, uy, math.Abs(dx-x) < 1e-4 && math.Abs(dy-y) < 1e-4
+}
+
+// LensDistortOptions are the lens distortion parameters.
+// Undistort set to true removes the distortion described by the coefficients, as needed to correct the
+// images taken with the lens. Set to false, the distortion is applied instead, simulating the lens.
+// ScaleToFit set to true scales the result so that it's fully covered by the source image, without
+// empty areas at its edges. Otherwise the scale at the center of the image is preserved.
+// Background is the color used for the pixels that fall outside of the source image.
+// Default of transparent is used if nil is passed.
+type LensDistortOptions struct {
+	Undistort  bool
+	ScaleToFit bool
+	Background color.Color
+}
+
+// LensDistort returns a new image with the radial and tangential lens distortion described by
+// the Brown–Conrady coefficients applied to it, or removed if the Undistort option is set.
+// The filter param corresponds to the Resampling Filter used when interpolating between the sample points.
+// Default parameters are used if a nil *LensDistortOptions is passed.
+//
+// Usage example:
+//
+//	// Correct the barrel distortion of a wide angle camera
+//	coeffs := transform.LensCoefficients{K1: -0.25, K2: 0.05}
+//	result := transform.LensDistort(img, coeffs, transform.Lanczos, &transform.LensDistortOptions{Undistort: true, ScaleToFit: true})
+func LensDistort(img image.Image, coeffs LensCoefficients, filter ResampleFilter, options *LensDistortOptions) *image.RGBA {
+	src := clone.AsShallowRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	undistort, scaleToFit := false, false
+	bg := color.RGBA{}
+	if options != nil {
+		undistort = options.Undistort
+		scaleToFit = options.ScaleToFit
+		if options.Background != nil {
+			bg = color.RGBAModel.Convert(options.Background).(color.RGBA)
+		}
+	}
+
+	// Each destination point is mapped back to its position in the source image, so correcting
+	// the distortion applies the lens model, and simulating it requires the inverse of the model.
+	mapping := func(x, y float64) (float64, float64, bool) {
+		if undistort {
+			x, y = coeffs.distort(x, y)
+			return x, y, true
+		}
+		return coeffs.undistort(x, y)
+	}
+
+	cx, cy := float64(w)/2, float64(h)/2
+	norm := math.Hypot(cx, cy)
+	if norm == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	scale := 1.0
+	if scaleToFit {
+		scale = lensFitScale(mapping, cx/norm, cy/norm)
+	}
+
+	return warp(src, w, h, func(x, y float64) (float64, float64, bool) {
+		sx, sy, ok := mapping((x-cx)/norm*scale, (y-cy)/norm*scale)
+		return sx*norm + cx, sy*norm + cy, ok
+	}, filter, bg)
+}
+
+// lensFitScale returns the largest scale for which the edges of the rectangle from -hw, -hh to hw, hh
+// are mapped inside of that same rectangle.
+func lensFitScale(mapping func(x, y float64) (float64, float64, bool), hw, hh float64) float64 {
+	const steps = 64
+
+	fits := func(scale float64) bool {
+		for i := 0; i <= steps; i++ {
+			t := float64(i)/steps*2 - 1
+			points := [4][2]float64{{t * hw, -hh}, {t * hw, hh}, {-hw, t * hh}, {hw, t * hh}}
+			for _, p := range points {
+				x, y, ok := mapping(p[0]*scale, p[1]*scale)
+				if !ok || math.Abs(x) > hw+1e-9 || math.Abs(y) > hh+1e-9 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Binary search, as the edges move outwards as the scale grows. Larger scales are out of the
+	// range in which the lens model is meaningful.
+	lo, hi := 0.0, 4.0
+	for i := 0; i < 40; i++ {
+		mid := (lo + hi) / 2
+		if fits(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	if lo == 0 {
+		return 1
+	}
+	return lo
+}
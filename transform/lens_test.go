@@ -0,0 +1,128 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+// gradientImage returns an opaque image of size w and h with smooth red and green gradients.
+func gradientImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 255 / (w - 1)), uint8(y * 255 / (h - 1)), 0x80, 0xFF})
+		}
+	}
+	return img
+}
+
+func TestLensCoefficientsUndistort(t *testing.T) {
+	coeffs := LensCoefficients{K1: -0.2, K2: 0.05, K3: -0.01, P1: 0.01, P2: -0.005}
+
+	for _, p := range [][2]float64{{0, 0}, {0.3, -0.2}, {-0.5, 0.6}, {0.8, 0.5}} {
+		ux, uy, ok := coeffs.undistort(p[0], p[1])
+		if !ok {
+			t.Errorf("undistort: expected %v to converge", p)
+			continue
+		}
+		x, y := coeffs.distort(ux, uy)
+		if math.Abs(x-p[0]) > 1e-6 || math.Abs(y-p[1]) > 1e-6 {
+			t.Errorf("undistort: expected: %v actual: %v, %v", p, x, y)
+		}
+	}
+}
+
+func TestLensDistort(t *testing.T) {
+	value := gradientImage(16, 12)
+
+	cases := []struct {
+		description string
+		coeffs      LensCoefficients
+		options     *LensDistortOptions
+		check       func(img *image.RGBA) bool
+	}{
+		{
+			description: "no distortion",
+			coeffs:      LensCoefficients{},
+			options:     nil,
+			check: func(img *image.RGBA) bool {
+				return util.RGBAImageEqual(img, value)
+			},
+		},
+		{
+			description: "no distortion undistort",
+			coeffs:      LensCoefficients{},
+			options:     &LensDistortOptions{Undistort: true},
+			check: func(img *image.RGBA) bool {
+				return util.RGBAImageEqual(img, value)
+			},
+		},
+		{
+			description: "barrel leaves the corners empty",
+			coeffs:      LensCoefficients{K1: -0.3},
+			options:     nil,
+			check: func(img *image.RGBA) bool {
+				return img.RGBAAt(0, 0).A == 0 && img.RGBAAt(15, 11).A == 0 && img.RGBAAt(8, 6) == value.RGBAAt(8, 6)
+			},
+		},
+		{
+			description: "barrel scaled to fit",
+			coeffs:      LensCoefficients{K1: -0.3},
+			options:     &LensDistortOptions{ScaleToFit: true},
+			check: func(img *image.RGBA) bool {
+				return img.RGBAAt(0, 0).A == 0xFF && img.RGBAAt(15, 11).A == 0xFF && img.RGBAAt(0, 6).A == 0xFF
+			},
+		},
+		{
+			description: "pincushion correction with background",
+			coeffs:      LensCoefficients{K1: 0.3},
+			options:     &LensDistortOptions{Undistort: true, Background: color.RGBA{0x00, 0x00, 0xFF, 0xFF}},
+			check: func(img *image.RGBA) bool {
+				return img.RGBAAt(0, 0) == color.RGBA{0x00, 0x00, 0xFF, 0xFF}
+			},
+		},
+		{
+			description: "pincushion correction scaled to fit",
+			coeffs:      LensCoefficients{K1: 0.3},
+			options:     &LensDistortOptions{Undistort: true, ScaleToFit: true, Background: color.RGBA{0x00, 0x00, 0xFF, 0xFF}},
+			check: func(img *image.RGBA) bool {
+				return img.RGBAAt(0, 0).B != 0xFF && img.RGBAAt(15, 11).B != 0xFF
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := LensDistort(value, c.coeffs, Linear, c.options)
+		if !c.check(actual) {
+			t.Errorf("%s: unexpected result:\n%v", "LensDistort "+c.description, util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestLensDistortRoundTrip(t *testing.T) {
+	value := gradientImage(64, 48)
+	coeffs := LensCoefficients{K1: -0.15, K2: 0.02, P1: 0.005}
+
+	distorted := LensDistort(value, coeffs, Linear, nil)
+	actual := LensDistort(distorted, coeffs, Linear, &LensDistortOptions{Undistort: true})
+
+	// The gradients are linear, so the interpolation error is small away from the edges
+	inner := image.Rect(16, 12, 48, 36)
+	if !util.RGBAImageApproxEqual(actual.SubImage(inner).(*image.RGBA), value.SubImage(inner).(*image.RGBA), 2) {
+		t.Errorf("LensDistort round trip: expected:%v\nactual:%v",
+			util.RGBAToString(value.SubImage(inner).(*image.RGBA)), util.RGBAToString(actual.SubImage(inner).(*image.RGBA)))
+	}
+}
+
+func BenchmarkLensDistort(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1024, 768))
+	coeffs := LensCoefficients{K1: -0.25, K2: 0.05}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = LensDistort(img, coeffs, Linear, nil)
+	}
+}
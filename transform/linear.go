@@ -0,0 +1,252 @@
+package transform
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/anthonynsimon/bild/math/f64"
+	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/pixel"
+)
+
+// srgbToLinearTable maps each 8-bit sRGB value to its linear light value in the range 0.0 to 1.0.
+var srgbToLinearTable [256]float64
+
+func init() {
+	for i := range srgbToLinearTable {
+		srgbToLinearTable[i] = srgbToLinear(float64(i) / 255)
+	}
+}
+
+// srgbToLinear decodes the sRGB value v in the range 0.0 to 1.0 into linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes the linear light value v in the range 0.0 to 1.0 into sRGB.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// linearBuffer holds the pixels of an image in linear light, four float64 values per pixel
+// in RGBA order. Color values are premultiplied by the alpha value, all in the range 0.0 to 1.0.
+type linearBuffer struct {
+	Pix           []float64
+	Width, Height int
+}
+
+// linearBuffers holds the linear buffers between the passes of a resize for reuse.
+var linearBuffers sync.Pool
+
+// getLinearBuffer returns a linearBuffer of the given size from linearBuffers, whose values are left as
+// they were, or a new one if there is none large enough.
+func getLinearBuffer(w, h int) *linearBuffer {
+	buf, _ := linearBuffers.Get().(*linearBuffer)
+	if buf == nil {
+		buf = &linearBuffer{}
+	}
+	if cap(buf.Pix) < w*h*4 {
+		buf.Pix = make([]float64, w*h*4)
+	}
+	buf.Pix, buf.Width, buf.Height = buf.Pix[:w*h*4], w, h
+	return buf
+}
+
+// newLinearBuffer decodes the sRGB values of the pixel buffer img into buf, which has the same size.
+// Grayscale values are stored into each color channel of opaque pixels.
+func newLinearBuffer[T pixel.Sample](ctx context.Context, buf *linearBuffer, img *pixel.Buffer[T]) error {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	n := img.Channels()
+	maxValue := img.Max()
+	// 8-bit values are decoded with a lookup table
+	decode := func(v T) float64 {
+		if maxValue == 0xFF {
+			return srgbToLinearTable[uint8(v)]
+		}
+		return srgbToLinear(float64(v) / float64(maxValue))
+	}
+
+	return parallel.LineCtx(ctx, h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				srcPos := y*img.Stride + x*n
+				dstPos := (y*w + x) * 4
+
+				if img.Layout == pixel.Gray {
+					v := decode(img.Pix[srcPos])
+					buf.Pix[dstPos+0], buf.Pix[dstPos+1], buf.Pix[dstPos+2], buf.Pix[dstPos+3] = v, v, v, 1
+					continue
+				}
+
+				a := img.Pix[srcPos+3]
+				if a == 0 {
+					clear(buf.Pix[dstPos : dstPos+4])
+					continue
+				}
+
+				if a == maxValue || img.Layout == pixel.NRGBA {
+					alpha := float64(a) / float64(maxValue)
+					buf.Pix[dstPos+0] = decode(img.Pix[srcPos+0]) * alpha
+					buf.Pix[dstPos+1] = decode(img.Pix[srcPos+1]) * alpha
+					buf.Pix[dstPos+2] = decode(img.Pix[srcPos+2]) * alpha
+					buf.Pix[dstPos+3] = alpha
+					continue
+				}
+
+				// The transfer function applies to the straight color values, not premultiplied ones
+				alpha := float64(a) / float64(maxValue)
+				for c := 0; c < 3; c++ {
+					v := f64.Clamp(float64(img.Pix[srcPos+c])/float64(maxValue)/alpha, 0, 1)
+					buf.Pix[dstPos+c] = srgbToLinear(v) * alpha
+				}
+				buf.Pix[dstPos+3] = alpha
+			}
+		}
+	})
+}
+
+// fromLinearBuffer encodes the linear light values of buf back into sRGB and writes them into the pixel
+// buffer dst, which has the same size.
+func fromLinearBuffer[T pixel.Sample](ctx context.Context, dst *pixel.Buffer[T], buf *linearBuffer) error {
+	w, h := buf.Width, buf.Height
+	n := dst.Channels()
+	maxValue := float64(dst.Max())
+
+	return parallel.LineCtx(ctx, h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				srcPos := (y*w + x) * 4
+				dstPos := y*dst.Stride + x*n
+
+				// Un-premultiply before clamping the alpha value, as filters with negative lobes
+				// can overshoot it and the color must keep its proportion to it.
+				alpha := buf.Pix[srcPos+3]
+				if alpha <= 0 {
+					clear(dst.Pix[dstPos : dstPos+n])
+					continue
+				}
+
+				if dst.Layout == pixel.Gray {
+					v := linearToSRGB(f64.Clamp(buf.Pix[srcPos]/alpha, 0, 1))
+					dst.Pix[dstPos] = T(v*maxValue + 0.5)
+					continue
+				}
+
+				a := math.Min(alpha, 1)
+				for c := 0; c < 3; c++ {
+					v := linearToSRGB(f64.Clamp(buf.Pix[srcPos+c]/alpha, 0, 1))
+					if dst.Layout == pixel.RGBA {
+						v *= a
+					}
+					dst.Pix[dstPos+c] = T(v*maxValue + 0.5)
+				}
+				dst.Pix[dstPos+3] = T(a*maxValue + 0.5)
+			}
+		}
+	})
+}
+
+// resampleHorizontalLinear resamples the rows of src to the width of dst, which has the same height.
+func resampleHorizontalLinear(ctx context.Context, dst, src *linearBuffer, filter ResampleFilter) error {
+	srcWidth, srcHeight := src.Width, src.Height
+	width := dst.Width
+
+	delta := float64(srcWidth) / float64(width)
+	// Scale must be at least 1. Special case for image size reduction filter radius.
+	scale := math.Max(delta, 1.0)
+
+	filterRadius := math.Ceil(scale * filter.Support)
+
+	return parallel.LineCtx(ctx, srcHeight, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				// value of x from src
+				ix := (float64(x)+0.5)*delta - 0.5
+				istart, iend := int(ix-filterRadius+0.5), int(ix+filterRadius)
+
+				if istart < 0 {
+					istart = 0
+				}
+				if iend >= srcWidth {
+					iend = srcWidth - 1
+				}
+
+				var r, g, b, a float64
+				var sum float64
+				for kx := istart; kx <= iend; kx++ {
+					srcPos := (y*srcWidth + kx) * 4
+					// normalize the sample position to be evaluated by the filter
+					normPos := (float64(kx) - ix) / scale
+					fValue := filter.Fn(normPos)
+
+					r += src.Pix[srcPos+0] * fValue
+					g += src.Pix[srcPos+1] * fValue
+					b += src.Pix[srcPos+2] * fValue
+					a += src.Pix[srcPos+3] * fValue
+					sum += fValue
+				}
+
+				dstPos := (y*width + x) * 4
+				dst.Pix[dstPos+0] = r / sum
+				dst.Pix[dstPos+1] = g / sum
+				dst.Pix[dstPos+2] = b / sum
+				dst.Pix[dstPos+3] = a / sum
+			}
+		}
+	})
+}
+
+// resampleVerticalLinear resamples the columns of src to the height of dst, which has the same width.
+func resampleVerticalLinear(ctx context.Context, dst, src *linearBuffer, filter ResampleFilter) error {
+	srcWidth, srcHeight := src.Width, src.Height
+	height := dst.Height
+
+	delta := float64(srcHeight) / float64(height)
+	scale := math.Max(delta, 1.0)
+
+	filterRadius := math.Ceil(scale * filter.Support)
+
+	return parallel.LineCtx(ctx, height, func(start, end int) {
+		for y := start; y < end; y++ {
+			iy := (float64(y)+0.5)*delta - 0.5
+
+			istart, iend := int(iy-filterRadius+0.5), int(iy+filterRadius)
+
+			if istart < 0 {
+				istart = 0
+			}
+			if iend >= srcHeight {
+				iend = srcHeight - 1
+			}
+
+			for x := 0; x < srcWidth; x++ {
+				var r, g, b, a float64
+				var sum float64
+				for ky := istart; ky <= iend; ky++ {
+					srcPos := (ky*srcWidth + x) * 4
+					normPos := (float64(ky) - iy) / scale
+					fValue := filter.Fn(normPos)
+
+					r += src.Pix[srcPos+0] * fValue
+					g += src.Pix[srcPos+1] * fValue
+					b += src.Pix[srcPos+2] * fValue
+					a += src.Pix[srcPos+3] * fValue
+					sum += fValue
+				}
+
+				dstPos := (y*srcWidth + x) * 4
+				dst.Pix[dstPos+0] = r / sum
+				dst.Pix[dstPos+1] = g / sum
+				dst.Pix[dstPos+2] = b / sum
+				dst.Pix[dstPos+3] = a / sum
+			}
+		}
+	})
+}
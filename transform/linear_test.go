@@ -0,0 +1,20 @@
+package transform
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSRGBLinearRoundTrip(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		v := float64(i) / 255
+		actual := linearToSRGB(srgbToLinear(v))
+		if math.Abs(actual-v) > 1e-9 {
+			t.Errorf("sRGB round trip of %d: expected: %v actual: %v", i, v, actual)
+		}
+	}
+
+	if actual := srgbToLinear(0.5); math.Abs(actual-0.214041) > 1e-6 {
+		t.Errorf("srgbToLinear: expected: %v actual: %v", 0.214041, actual)
+	}
+}
@@ -0,0 +1,139 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+)
+
+// PointF is a point with sub-pixel precision, relative to the top-left corner of an image.
+type PointF struct {
+	X, Y float64
+}
+
+// PerspectiveOptions are the perspective warp parameters.
+// Bounds is the area of the destination plane to be rendered, the result image will have its size
+// and the top-left corner of Bounds will be located at the origin. Default of the source image bounds
+// is used if an empty rectangle is passed.
+// Background is the color used for the pixels that fall outside of the source image.
+// Default of transparent is used if nil is passed.
+type PerspectiveOptions struct {
+	Bounds     image.Rectangle
+	Background color.Color
+}
+
+// Perspective returns a warped image in which the four from points of the source image are mapped to
+// the four to points, interpolating any points in between with a projective transformation (homography).
+// The points are given in order, so from[i] will be mapped to to[i], and must be relative to
+// the top-left corner of the image. Points can be placed between pixels, as is the case of
+// the corners found by a feature detector.
+// The filter param corresponds to the Resampling Filter used when interpolating between the sample points.
+// Default parameters are used if a nil *PerspectiveOptions is passed.
+// If no transformation can be found for the provided points, for example because three of them
+// lie on the same line, a copy of the source image is returned.
+//
+// Usage example:
+//
+//	// Rectify a photographed document into a 600x800 image
+//	from := [4]transform.PointF{{120.5, 80}, {610, 130.25}, {580, 790}, {90, 740.5}}
+//	to := [4]transform.PointF{{0, 0}, {600, 0}, {600, 800}, {0, 800}}
+//	result := transform.Perspective(img, from, to, transform.Linear, &transform.PerspectiveOptions{Bounds: image.Rect(0, 0, 600, 800)})
+func Perspective(img image.Image, from, to [4]PointF, filter ResampleFilter, options *PerspectiveOptions) *image.RGBA {
+	src := clone.AsShallowRGBA(img)
+
+	bounds := image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy())
+	bg := color.RGBA{}
+	if options != nil {
+		if !options.Bounds.Empty() {
+			bounds = options.Bounds
+		}
+		if options.Background != nil {
+			bg = color.RGBAModel.Convert(options.Background).(color.RGBA)
+		}
+	}
+
+	// The homography is solved for the inverse direction, as each destination
+	// pixel needs to be mapped back to its position in the source image.
+	h, ok := solveHomography(to, from)
+	if !ok {
+		return clone.AsRGBA(src)
+	}
+
+	offsetX, offsetY := float64(bounds.Min.X), float64(bounds.Min.Y)
+
+	return warp(src, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64, bool) {
+		return h.apply(x+offsetX, y+offsetY)
+	}, filter, bg)
+}
+
+// homography is a 3x3 projective transformation matrix stored in row-major order.
+type homography [9]float64
+
+// apply returns the position of the point x, y after being transformed by the homography.
+// Returns false if the point is mapped to infinity.
+func (h homography) apply(x, y float64) (float64, float64, bool) {
+	w := h[6]*x + h[7]*y + h[8]
+	if math.Abs(w) < 1e-12 {
+		return 0, 0, false
+	}
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w, true
+}
+
+// det returns the determinant of the homography matrix.
+func (h homography) det() float64 {
+	return h[0]*(h[4]*h[8]-h[5]*h[7]) - h[1]*(h[3]*h[8]-h[5]*h[6]) + h[2]*(h[3]*h[7]-h[4]*h[6])
+}
+
+// solveHomography returns the homography that maps each of the points in a to the point with the
+// same index in b. Returns false if the points are degenerate and no such homography exists.
+func solveHomography(a, b [4]PointF) (homography, bool) {
+	// Each point pair contributes two equations to the 8x8 linear system,
+	// the last element of the matrix is fixed to 1.
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := a[i].X, a[i].Y
+		u, v := b[i].X, b[i].Y
+		m[i*2] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		m[i*2+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-10 {
+			return homography{}, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < 8; row++ {
+			f := m[row][col] / m[col][col]
+			for k := col; k < 9; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+
+	var h homography
+	for row := 7; row >= 0; row-- {
+		sum := m[row][8]
+		for k := row + 1; k < 8; k++ {
+			sum -= m[row][k] * h[k]
+		}
+		h[row] = sum / m[row][row]
+	}
+	h[8] = 1
+
+	// A singular matrix would collapse the image into a line or a point
+	if math.Abs(h.det()) < 1e-12 {
+		return homography{}, false
+	}
+
+	return h, true
+}
@@ -0,0 +1,177 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestPerspective(t *testing.T) {
+	square := [4]PointF{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+
+	cases := []struct {
+		description string
+		from        [4]PointF
+		to          [4]PointF
+		filter      ResampleFilter
+		options     *PerspectiveOptions
+		value       image.Image
+		expected    *image.RGBA
+	}{
+		{
+			description: "identity",
+			from:        square,
+			to:          square,
+			filter:      Linear,
+			options:     nil,
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+		},
+		{
+			description: "mirror corners",
+			from:        square,
+			to:          [4]PointF{{2, 0}, {0, 0}, {0, 2}, {2, 2}},
+			filter:      NearestNeighbor,
+			options:     nil,
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0xFF, 0xFF, 0xFF, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+					0xC0, 0xC0, 0xC0, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+				},
+			},
+		},
+		{
+			description: "shift with background and bounds",
+			from:        square,
+			to:          [4]PointF{{1, 0}, {3, 0}, {3, 2}, {1, 2}},
+			filter:      NearestNeighbor,
+			options: &PerspectiveOptions{
+				Bounds:     image.Rect(0, 0, 3, 2),
+				Background: color.RGBA{0x00, 0x00, 0xFF, 0xFF},
+			},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 2),
+				Stride: 12,
+				Pix: []uint8{
+					0x00, 0x00, 0xFF, 0xFF, 0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x00, 0x00, 0xFF, 0xFF, 0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+		},
+		{
+			description: "sub-pixel shift",
+			from:        square,
+			to:          [4]PointF{{0.5, 0}, {2.5, 0}, {2.5, 2}, {0.5, 2}},
+			filter:      Linear,
+			options:     &PerspectiveOptions{Bounds: image.Rect(0, 0, 3, 2)},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 2),
+				Stride: 12,
+				Pix: []uint8{
+					0x40, 0x40, 0x40, 0x80, 0xC0, 0xC0, 0xC0, 0xFF, 0x80, 0x80, 0x80, 0x80,
+					0x20, 0x20, 0x20, 0x80, 0x80, 0x80, 0x80, 0xFF, 0x60, 0x60, 0x60, 0x80,
+				},
+			},
+		},
+		{
+			description: "degenerate points",
+			from:        square,
+			to:          [4]PointF{{0, 0}, {1, 1}, {2, 2}, {0, 2}},
+			filter:      Linear,
+			options:     nil,
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := Perspective(c.value, c.from, c.to, c.filter, c.options)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s:\nexpected:%v\nactual:%v", "Perspective "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestSolveHomography(t *testing.T) {
+	from := [4]PointF{{10, 20}, {300, 5}, {280, 410}, {0, 390}}
+	to := [4]PointF{{0, 0}, {600, 0}, {600, 800}, {0, 800}}
+
+	h, ok := solveHomography(from, to)
+	if !ok {
+		t.Fatal("solveHomography: expected a solution for non-degenerate points")
+	}
+
+	for i := range from {
+		x, y, ok := h.apply(from[i].X, from[i].Y)
+		if !ok || math.Abs(x-to[i].X) > 1e-6 || math.Abs(y-to[i].Y) > 1e-6 {
+			t.Errorf("solveHomography: point %v expected to map to %v, actual: %v, %v", from[i], to[i], x, y)
+		}
+	}
+}
+
+func BenchmarkPerspective(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1024, 1024))
+	from := [4]PointF{{0, 0}, {1024, 0}, {1024, 1024}, {0, 1024}}
+	to := [4]PointF{{100, 50}, {900, 0}, {1024, 1024}, {0, 900}}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = Perspective(img, from, to, Linear, nil)
+	}
+}
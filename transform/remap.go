@@ -0,0 +1,230 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/parallel"
+)
+
+// Field is a grid of float64 values, one per pixel, stored in row-major order.
+type Field struct {
+	Values        []float64
+	Width, Height int
+}
+
+// NewField returns a new Field of the provided size with all its values set to zero.
+func NewField(width, height int) *Field {
+	return &Field{Values: make([]float64, width*height), Width: width, Height: height}
+}
+
+// FieldFromImage returns a new Field with the size of img, in which each value is the luminance of
+// the pixel in the same position in the range 0.0 to 1.0, multiplied by scale.
+//
+// Usage example:
+//
+//	// Map a 16-bit grayscale image to source x coordinates for a 1024 pixels wide image
+//	mapX := transform.FieldFromImage(grayX, 1023)
+func FieldFromImage(img image.Image, scale float64) *Field {
+	bounds := img.Bounds()
+	f := NewField(bounds.Dx(), bounds.Dy())
+
+	parallel.Line(f.Height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < f.Width; x++ {
+				c := color.Gray16Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray16)
+				f.Values[y*f.Width+x] = float64(c.Y) / 0xFFFF * scale
+			}
+		}
+	})
+
+	return f
+}
+
+// At returns the value at the position x, y.
+func (f *Field) At(x, y int) float64 {
+	return f.Values[y*f.Width+x]
+}
+
+// Set sets the value at the position x, y.
+func (f *Field) Set(x, y int, v float64) {
+	f.Values[y*f.Width+x] = v
+}
+
+// Remap returns a new image in which each pixel is sampled from the source image at the position
+// given by the maps. For every pixel x, y of the result, mapX.At(x, y) and mapY.At(x, y) are the x and y
+// coordinates of the source pixel, relative to the top-left pixel of the source image and allowing
+// fractional values. The result has the size of the smallest of the maps.
+// The filter param corresponds to the Resampling Filter used when interpolating between the sample points.
+// Positions outside of the source image, or NaN, result in transparent pixels.
+//
+// Usage example:
+//
+//	// Flip an image horizontally
+//	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+//	mapX, mapY := transform.NewField(w, h), transform.NewField(w, h)
+//	for y := 0; y < h; y++ {
+//		for x := 0; x < w; x++ {
+//			mapX.Set(x, y, float64(w-1-x))
+//			mapY.Set(x, y, float64(y))
+//		}
+//	}
+//	result := transform.Remap(img, mapX, mapY, transform.Linear)
+func Remap(img image.Image, mapX, mapY *Field, filter ResampleFilter) *image.RGBA {
+	src := clone.AsShallowRGBA(img)
+	width, height := mapX.Width, mapX.Height
+	if mapY.Width < width {
+		width = mapY.Width
+	}
+	if mapY.Height < height {
+		height = mapY.Height
+	}
+
+	return warp(src, width, height, func(x, y float64) (float64, float64, bool) {
+		ix, iy := int(x), int(y)
+		return mapX.At(ix, iy) + 0.5, mapY.At(ix, iy) + 0.5, true
+	}, filter, color.RGBA{})
+}
+
+// remapFn returns a new image of size w and h remapped from img with fn, which receives the continuous
+// coordinates of each pixel center of the result and returns the continuous coordinates to sample in img.
+func remapFn(img image.Image, w, h int, fn func(x, y float64) (float64, float64)) *image.RGBA {
+	mapX, mapY := NewField(w, h), NewField(w, h)
+
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				sx, sy := fn(float64(x)+0.5, float64(y)+0.5)
+				mapX.Set(x, y, sx-0.5)
+				mapY.Set(x, y, sy-0.5)
+			}
+		}
+	})
+
+	return Remap(img, mapX, mapY, Linear)
+}
+
+// Swirl returns a new image twisted around its center. Parameter angle is the rotation in degrees at the
+// center, which decreases smoothly down to zero at the distance radius from it, measured in pixels.
+// Positive angles twist clockwise.
+//
+// Usage example:
+//
+//	result := transform.Swirl(img, 180, 120)
+func Swirl(img image.Image, angle, radius float64) *image.RGBA {
+	bounds := img.Bounds()
+	cx, cy := float64(bounds.Dx())/2, float64(bounds.Dy())/2
+	rad := angle * math.Pi / 180
+
+	return remapFn(img, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64) {
+		dx, dy := x-cx, y-cy
+		dist := math.Hypot(dx, dy)
+		if dist >= radius {
+			return x, y
+		}
+
+		// Rotate back by the twist at this distance to find the source position
+		t := 1 - dist/radius
+		sin, cos := math.Sincos(-rad * t * t)
+		return cx + dx*cos - dy*sin, cy + dx*sin + dy*cos
+	})
+}
+
+// Ripple returns a new image displaced by sine waves, as seen through a rippled water surface.
+// Parameter amplitude is the max displacement in pixels and wavelength is the length of a wave in pixels.
+// A wavelength of zero or less leaves the image unchanged.
+//
+// Usage example:
+//
+//	result := transform.Ripple(img, 4, 32)
+func Ripple(img image.Image, amplitude, wavelength float64) *image.RGBA {
+	var k float64
+	if wavelength > 0 {
+		k = 2 * math.Pi / wavelength
+	}
+
+	return remapFn(img, img.Bounds().Dx(), img.Bounds().Dy(), func(x, y float64) (float64, float64) {
+		return x + amplitude*math.Sin(y*k), y + amplitude*math.Sin(x*k)
+	})
+}
+
+// Spherize returns a new image as if it was wrapped around a sphere inscribed in the image.
+// Parameter strength is of the range -1.0 to 1.0, positive values bulge the image outwards
+// and negative values pinch it inwards.
+//
+// Usage example:
+//
+//	result := transform.Spherize(img, 0.8)
+func Spherize(img image.Image, strength float64) *image.RGBA {
+	bounds := img.Bounds()
+	cx, cy := float64(bounds.Dx())/2, float64(bounds.Dy())/2
+	radius := math.Min(cx, cy)
+	strength = math.Max(-1, math.Min(1, strength))
+
+	return remapFn(img, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64) {
+		dx, dy := x-cx, y-cy
+		r := math.Hypot(dx, dy) / radius
+		if r >= 1 || r == 0 {
+			return x, y
+		}
+
+		// Interpolate between the distance on the flat image and the distance on the surface of the sphere,
+		// which is shorter near the center for the bulge and longer for the pinch.
+		var f float64
+		if strength >= 0 {
+			f = (1-strength)*r + strength*math.Asin(r)/(math.Pi/2)
+		} else {
+			f = (1+strength)*r - strength*math.Sin(r*math.Pi/2)
+		}
+		f /= r
+		return cx + dx*f, cy + dy*f
+	})
+}
+
+// CartesianToPolar returns a new image of the same size in which the x-axis is the angle and the
+// y-axis is the distance from the center of the source image. The angle starts at the right of the center
+// and grows clockwise, the distance grows from the center up to the corners of the image.
+//
+// Usage example:
+//
+//	result := transform.CartesianToPolar(img)
+func CartesianToPolar(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	cx, cy := w/2, h/2
+	maxR := math.Hypot(cx, cy)
+
+	return remapFn(img, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64) {
+		sin, cos := math.Sincos(x / w * 2 * math.Pi)
+		r := y / h * maxR
+		return cx + r*cos, cy + r*sin
+	})
+}
+
+// PolarToCartesian returns a new image of the same size that reverts CartesianToPolar, the x-axis of the
+// source image being the angle and the y-axis the distance from the center of the result.
+//
+// Usage example:
+//
+//	result := transform.PolarToCartesian(transform.CartesianToPolar(img))
+func PolarToCartesian(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	cx, cy := w/2, h/2
+	maxR := math.Hypot(cx, cy)
+
+	// The angle wraps around, so the columns at each side are padded with the ones
+	// from the opposite side to interpolate across them.
+	padded := clone.Pad(img, 1, 0, clone.EdgeWrap)
+
+	return remapFn(padded, bounds.Dx(), bounds.Dy(), func(x, y float64) (float64, float64) {
+		dx, dy := x-cx, y-cy
+		angle := math.Atan2(dy, dx)
+		if angle < 0 {
+			angle += 2 * math.Pi
+		}
+		return angle/(2*math.Pi)*w + 1, math.Hypot(dx, dy) / maxR * h
+	})
+}
@@ -0,0 +1,175 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestRemap(t *testing.T) {
+	value := &image.RGBA{
+		Rect:   image.Rect(0, 0, 2, 2),
+		Stride: 8,
+		Pix: []uint8{
+			0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+			0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+		},
+	}
+
+	cases := []struct {
+		description string
+		mapX        *Field
+		mapY        *Field
+		filter      ResampleFilter
+		expected    *image.RGBA
+	}{
+		{
+			description: "identity",
+			mapX:        &Field{Values: []float64{0, 1, 0, 1}, Width: 2, Height: 2},
+			mapY:        &Field{Values: []float64{0, 0, 1, 1}, Width: 2, Height: 2},
+			filter:      Linear,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x40, 0x40, 0x40, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+		},
+		{
+			description: "transpose",
+			mapX:        &Field{Values: []float64{0, 0, 1, 1}, Width: 2, Height: 2},
+			mapY:        &Field{Values: []float64{0, 1, 0, 1}, Width: 2, Height: 2},
+			filter:      NearestNeighbor,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF,
+				},
+			},
+		},
+		{
+			description: "fractional, outside and smaller map",
+			mapX:        &Field{Values: []float64{0.5, -5, math.NaN()}, Width: 3, Height: 1},
+			mapY:        &Field{Values: []float64{0, 0}, Width: 2, Height: 1},
+			filter:      Linear,
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 8,
+				Pix: []uint8{
+					0xC0, 0xC0, 0xC0, 0xFF, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := Remap(value, c.mapX, c.mapY, c.filter)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s:\nexpected:%v\nactual:%v", "Remap "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestFieldFromImage(t *testing.T) {
+	img := image.NewGray(image.Rect(1, 1, 3, 2))
+	img.SetGray(1, 1, color.Gray{0x00})
+	img.SetGray(2, 1, color.Gray{0xFF})
+
+	actual := FieldFromImage(img, 10)
+	if actual.Width != 2 || actual.Height != 1 || actual.At(0, 0) != 0 || actual.At(1, 0) != 10 {
+		t.Errorf("FieldFromImage: expected: [0 10] actual: %v", actual.Values)
+	}
+}
+
+func TestRemapEffects(t *testing.T) {
+	value := gradientImage(16, 16)
+
+	cases := []struct {
+		description string
+		fn          func(img image.Image) *image.RGBA
+		check       func(img *image.RGBA) bool
+	}{
+		{
+			description: "Swirl zero angle",
+			fn:          func(img image.Image) *image.RGBA { return Swirl(img, 0, 8) },
+			check:       func(img *image.RGBA) bool { return util.RGBAImageEqual(img, value) },
+		},
+		{
+			description: "Swirl keeps the pixels outside of the radius",
+			fn:          func(img image.Image) *image.RGBA { return Swirl(img, 90, 4) },
+			check: func(img *image.RGBA) bool {
+				return img.RGBAAt(0, 0) == value.RGBAAt(0, 0) && img.RGBAAt(15, 8) == value.RGBAAt(15, 8) && img.RGBAAt(9, 7) != value.RGBAAt(9, 7)
+			},
+		},
+		{
+			description: "Ripple zero amplitude",
+			fn:          func(img image.Image) *image.RGBA { return Ripple(img, 0, 8) },
+			check:       func(img *image.RGBA) bool { return util.RGBAImageEqual(img, value) },
+		},
+		{
+			description: "Ripple displaces pixels",
+			fn:          func(img image.Image) *image.RGBA { return Ripple(img, 2, 8) },
+			check:       func(img *image.RGBA) bool { return !util.RGBAImageEqual(img, value) },
+		},
+		{
+			description: "Spherize zero strength",
+			fn:          func(img image.Image) *image.RGBA { return Spherize(img, 0) },
+			check:       func(img *image.RGBA) bool { return util.RGBAImageEqual(img, value) },
+		},
+		{
+			description: "Spherize bulges the center",
+			fn:          func(img image.Image) *image.RGBA { return Spherize(img, 1) },
+			check: func(img *image.RGBA) bool {
+				// The pixel right of the center samples closer to the center, so it's darker in the red gradient
+				return img.RGBAAt(0, 0) == value.RGBAAt(0, 0) && img.RGBAAt(11, 8).R < value.RGBAAt(11, 8).R
+			},
+		},
+		{
+			description: "Spherize pinches the center",
+			fn:          func(img image.Image) *image.RGBA { return Spherize(img, -1) },
+			check:       func(img *image.RGBA) bool { return img.RGBAAt(11, 8).R > value.RGBAAt(11, 8).R },
+		},
+	}
+
+	for _, c := range cases {
+		actual := c.fn(value)
+		if !c.check(actual) {
+			t.Errorf("%s: unexpected result:\n%v", c.description, util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestPolarRoundTrip(t *testing.T) {
+	value := gradientImage(64, 64)
+
+	polar := CartesianToPolar(value)
+	if polar.Bounds() != value.Bounds() {
+		t.Fatalf("CartesianToPolar: expected bounds: %v actual: %v", value.Bounds(), polar.Bounds())
+	}
+	// The top row of the polar image is the center of the source image
+	if c := polar.RGBAAt(10, 0); math.Abs(float64(c.R)-float64(value.RGBAAt(32, 32).R)) > 4 {
+		t.Errorf("CartesianToPolar: expected the top row to be the center color, actual: %v", c)
+	}
+
+	actual := PolarToCartesian(polar)
+	inner := image.Rect(20, 20, 44, 44)
+	if !util.RGBAImageApproxEqual(actual.SubImage(inner).(*image.RGBA), value.SubImage(inner).(*image.RGBA), 8) {
+		t.Errorf("Polar round trip: expected:%v\nactual:%v",
+			util.RGBAToString(value.SubImage(inner).(*image.RGBA)), util.RGBAToString(actual.SubImage(inner).(*image.RGBA)))
+	}
+}
+
+func BenchmarkSwirl(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1024, 1024))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = Swirl(img, 180, 400)
+	}
+}
@@ -1,37 +1,303 @@
 package transform
 
 import (
+	"context"
 	"image"
 	"math"
+	"sync"
 
 	"github.com/anthonynsimon/bild/clone"
 	"github.com/anthonynsimon/bild/math/f64"
 	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/pixel"
 )
 
+// ColorSpace determines the representation of the color values while they are being resampled.
+type ColorSpace uint8
+
+const (
+	// SRGB resamples the gamma encoded values as they are stored in the image.
+	// It is the fastest option, but it darkens fine high contrast detail when downsampling.
+	SRGB ColorSpace = iota
+	// LinearRGB decodes the sRGB values into linear light before resampling and
+	// encodes them back afterwards, preserving the perceived brightness of the image.
+	LinearRGB
+)
+
+// ResizeOptions are the resize parameters.
+// ColorSpace is the color space in which the resampling is done. Default of SRGB is used
+// if a nil *ResizeOptions is passed.
+type ResizeOptions struct {
+	ColorSpace ColorSpace
+}
+
 // Resize returns a new image with its size adjusted to the new width and height. The filter
 // param corresponds to the Resampling Filter to be used when interpolating between the sample points.
+// Colors are filtered weighted by their alpha value, so transparent pixels don't bleed into their neighbours.
 //
 // Usage example:
 //
 //	result := transform.Resize(img, 800, 600, transform.Linear)
 func Resize(img image.Image, width, height int, filter ResampleFilter) *image.RGBA {
+	return ResizeWithOptions(img, width, height, filter, nil)
+}
+
+// ResizeCtx returns a new image with its size adjusted to the new width and height, like Resize, but
+// stops early and returns ctx.Err() if ctx is done before it completes. Its progress is reported to the
+// callback set with parallel.WithProgress.
+//
+// Usage example:
+//
+//	result, err := transform.ResizeCtx(ctx, img, 800, 600, transform.Linear)
+func ResizeCtx(ctx context.Context, img image.Image, width, height int, filter ResampleFilter) (*image.RGBA, error) {
+	return ResizeWithOptionsCtx(ctx, img, width, height, filter, nil)
+}
+
+// ResizeWithOptions returns a new image with its size adjusted to the new width and height,
+// as Resize does, using the provided options.
+// Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	// Downsample in linear light to keep fine detail from getting darker
+//	result := transform.ResizeWithOptions(img, 800, 600, transform.Lanczos, &transform.ResizeOptions{ColorSpace: transform.LinearRGB})
+func ResizeWithOptions(img image.Image, width, height int, filter ResampleFilter, options *ResizeOptions) *image.RGBA {
+	result, _ := ResizeWithOptionsCtx(context.Background(), img, width, height, filter, options)
+	return result
+}
+
+// ResizeWithOptionsCtx returns a new image with its size adjusted to the new width and height, like
+// ResizeWithOptions, but stops early and returns ctx.Err() if ctx is done before it completes.
+// Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	result, err := transform.ResizeWithOptionsCtx(ctx, img, 800, 600, transform.Lanczos, &transform.ResizeOptions{ColorSpace: transform.LinearRGB})
+func ResizeWithOptionsCtx(ctx context.Context, img image.Image, width, height int, filter ResampleFilter, options *ResizeOptions) (*image.RGBA, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if width <= 0 || height <= 0 || img.Bounds().Empty() {
-		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+		return image.NewRGBA(image.Rect(0, 0, 0, 0)), nil
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err := ResizeWithOptionsIntoCtx(ctx, dst, img, filter, options); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ResizeInto writes the image resized to the size of dst into dst, like Resize, rather than allocating
+// a new image. The images between the resampling passes are taken from a pool, so that resizing many
+// images to the same size allocates no pixels. Dst must not share its pixels with img.
+//
+// Usage example:
+//
+//	dst := image.NewRGBA(image.Rect(0, 0, 800, 600))
+//	transform.ResizeInto(dst, img, transform.Linear)
+func ResizeInto(dst *image.RGBA, img image.Image, filter ResampleFilter) {
+	ResizeWithOptionsIntoCtx(context.Background(), dst, img, filter, nil)
+}
+
+// ResizeIntoCtx writes the image resized to the size of dst into dst, like ResizeInto, but stops early
+// and returns ctx.Err() if ctx is done before it completes.
+//
+// Usage example:
+//
+//	err := transform.ResizeIntoCtx(ctx, dst, img, transform.Linear)
+func ResizeIntoCtx(ctx context.Context, dst *image.RGBA, img image.Image, filter ResampleFilter) error {
+	return ResizeWithOptionsIntoCtx(ctx, dst, img, filter, nil)
+}
+
+// ResizeWithOptionsInto writes the image resized to the size of dst into dst, like ResizeInto, using
+// the provided options. Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	transform.ResizeWithOptionsInto(dst, img, transform.Lanczos, &transform.ResizeOptions{ColorSpace: transform.LinearRGB})
+func ResizeWithOptionsInto(dst *image.RGBA, img image.Image, filter ResampleFilter, options *ResizeOptions) {
+	ResizeWithOptionsIntoCtx(context.Background(), dst, img, filter, options)
+}
+
+// ResizeWithOptionsIntoCtx writes the image resized to the size of dst into dst, like ResizeWithOptionsInto,
+// but stops early and returns ctx.Err() if ctx is done before it completes, in which case the pixels of
+// dst are left partly written.
+//
+// Usage example:
+//
+//	err := transform.ResizeWithOptionsIntoCtx(ctx, dst, img, transform.Lanczos, &transform.ResizeOptions{ColorSpace: transform.LinearRGB})
+func ResizeWithOptionsIntoCtx(ctx context.Context, dst *image.RGBA, img image.Image, filter ResampleFilter, options *ResizeOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
+	if width == 0 || height == 0 {
+		return nil
+	}
+	if img.Bounds().Empty() {
+		// There is nothing to resample, so dst is left transparent
+		for y := 0; y < height; y++ {
+			clear(dst.Pix[y*dst.Stride : y*dst.Stride+width*4])
+		}
+		return nil
 	}
 
 	src := clone.AsShallowRGBA(img)
-	var dst *image.RGBA
+	return resizeInto(ctx, rgbaBuffer(dst), rgbaBuffer(src), filter, options)
+}
+
+// Resize64 returns a new image with its size adjusted to the new width and height, like Resize but
+// keeping 16 bits per channel.
+//
+// Usage example:
+//
+//	result := transform.Resize64(img, 800, 600, transform.Linear)
+func Resize64(img image.Image, width, height int, filter ResampleFilter) *image.RGBA64 {
+	return ResizeWithOptions64(img, width, height, filter, nil)
+}
+
+// ResizeWithOptions64 returns a new image with its size adjusted to the new width and height,
+// as Resize64 does, using the provided options.
+// Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	result := transform.ResizeWithOptions64(img, 800, 600, transform.Lanczos, &transform.ResizeOptions{ColorSpace: transform.LinearRGB})
+func ResizeWithOptions64(img image.Image, width, height int, filter ResampleFilter, options *ResizeOptions) *image.RGBA64 {
+	return ResizeNative(clone.AsShallowRGBA64(img), width, height, filter, options)
+}
+
+// ResizeNative returns a new image with its size adjusted to the new width and height, like ResizeWithOptions
+// but running directly on the pixels of img and returning an image of the same type.
+// Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	// Resizes a grayscale image without converting it to RGBA
+//	result := transform.ResizeNative(gray, 800, 600, transform.Lanczos, nil)
+func ResizeNative[I pixel.Image](img I, width, height int, filter ResampleFilter, options *ResizeOptions) I {
+	return pixel.Process(img,
+		func(b *pixel.Buffer[uint8]) *pixel.Buffer[uint8] {
+			return ResizeBuffer(b, width, height, filter, options)
+		},
+		func(b *pixel.Buffer[uint16]) *pixel.Buffer[uint16] {
+			return ResizeBuffer(b, width, height, filter, options)
+		})
+}
+
+// ResizeBuffer returns a new pixel buffer of the same layout with its size adjusted to the new width and height,
+// as ResizeWithOptions does. The colors of non-premultiplied pixels are weighted by their alpha value,
+// so transparent pixels don't bleed into their neighbours.
+// Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	result := transform.ResizeBuffer(buf, 800, 600, transform.Linear, nil)
+func ResizeBuffer[T pixel.Sample](src *pixel.Buffer[T], width, height int, filter ResampleFilter, options *ResizeOptions) *pixel.Buffer[T] {
+	result, _ := ResizeBufferCtx(context.Background(), src, width, height, filter, options)
+	return result
+}
+
+// ResizeBufferCtx returns a new pixel buffer with its size adjusted to the new width and height, like
+// ResizeBuffer, but stops early and returns ctx.Err() if ctx is done before it completes. Its progress is
+// reported to the callback set with parallel.WithProgress.
+// Default parameters are used if a nil *ResizeOptions is passed.
+//
+// Usage example:
+//
+//	result, err := transform.ResizeBufferCtx(ctx, buf, 800, 600, transform.Linear, nil)
+func ResizeBufferCtx[T pixel.Sample](ctx context.Context, src *pixel.Buffer[T], width, height int, filter ResampleFilter, options *ResizeOptions) (*pixel.Buffer[T], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 || src.Rect.Empty() {
+		return pixel.NewBuffer[T](image.Rect(0, 0, 0, 0), src.Layout), nil
+	}
+
+	dst := pixel.NewBuffer[T](image.Rect(0, 0, width, height), src.Layout)
+	if err := resizeInto(ctx, dst, src, filter, options); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// resizeInto resamples src to the size of dst, which has the same layout and isn't empty, nor is src.
+func resizeInto[T pixel.Sample](ctx context.Context, dst, src *pixel.Buffer[T], filter ResampleFilter, options *ResizeOptions) error {
+	colorSpace := SRGB
+	if options != nil {
+		colorSpace = options.ColorSpace
+	}
+
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
 
 	// NearestNeighbor is a special case, it's faster to compute without convolution matrix.
+	// It also picks existing pixels as they are, so the color space makes no difference.
 	if filter.Support <= 0 {
-		dst = nearestNeighbor(src, width, height)
-	} else {
-		dst = resampleHorizontal(src, width, filter)
-		dst = resampleVertical(dst, height, filter)
+		return nearestNeighbor(ctx, dst, src)
+	}
+
+	if colorSpace == LinearRGB {
+		buf := getLinearBuffer(srcWidth, srcHeight)
+		defer linearBuffers.Put(buf)
+		horizontal := getLinearBuffer(width, srcHeight)
+		defer linearBuffers.Put(horizontal)
+		vertical := getLinearBuffer(width, height)
+		defer linearBuffers.Put(vertical)
+
+		// The resampling passes report most of the progress, the conversions being cheaper
+		if err := newLinearBuffer(parallel.ProgressRange(ctx, 0, 0.1), buf, src); err != nil {
+			return err
+		}
+		if err := resampleHorizontalLinear(parallel.ProgressRange(ctx, 0.1, 0.5), horizontal, buf, filter); err != nil {
+			return err
+		}
+		if err := resampleVerticalLinear(parallel.ProgressRange(ctx, 0.5, 0.9), vertical, horizontal, filter); err != nil {
+			return err
+		}
+		return fromLinearBuffer(parallel.ProgressRange(ctx, 0.9, 1), dst, vertical)
+	}
+
+	tmp := getIntermediate[T](width, srcHeight, src.Layout)
+	defer intermediatePool[T]().Put(tmp)
+
+	if err := resampleHorizontal(parallel.ProgressRange(ctx, 0, 0.5), tmp, src, filter); err != nil {
+		return err
+	}
+	return resampleVertical(parallel.ProgressRange(ctx, 0.5, 1), dst, tmp, filter)
+}
+
+// rgbaBuffer returns a pixel buffer sharing the pixels of img.
+func rgbaBuffer(img *image.RGBA) *pixel.Buffer[uint8] {
+	return &pixel.Buffer[uint8]{Pix: img.Pix, Stride: img.Stride, Rect: img.Rect, Layout: pixel.RGBA}
+}
+
+// intermediates holds the buffers between the resampling passes of Resize, those of 8-bit and 16-bit
+// samples being kept in separate pools.
+var intermediates [2]sync.Pool
+
+// intermediatePool returns the pool of intermediates holding buffers of samples of type T.
+func intermediatePool[T pixel.Sample]() *sync.Pool {
+	if uint64(^T(0)) > 0xFF {
+		return &intermediates[1]
 	}
+	return &intermediates[0]
+}
 
-	return dst
+// getIntermediate returns a buffer of the given size and layout from intermediates, whose values are left
+// as they were, or a new one if there is none large enough.
+func getIntermediate[T pixel.Sample](w, h int, l pixel.Layout) *pixel.Buffer[T] {
+	buf, _ := intermediatePool[T]().Get().(*pixel.Buffer[T])
+	if buf == nil {
+		buf = &pixel.Buffer[T]{}
+	}
+	n := w * h * l.Channels()
+	if cap(buf.Pix) < n {
+		buf.Pix = make([]T, n)
+	}
+	buf.Pix, buf.Stride, buf.Rect, buf.Layout = buf.Pix[:n], w*l.Channels(), image.Rect(0, 0, w, h), l
+	return buf
 }
 
 // Crop returns a new image which contains the intersection between the rect and the image provided as params.
@@ -46,135 +312,203 @@ func Crop(img image.Image, rect image.Rectangle) *image.RGBA {
 	return clone.AsRGBA(src.SubImage(rect))
 }
 
-func resampleHorizontal(src *image.RGBA, width int, filter ResampleFilter) *image.RGBA {
-	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
-	srcStride := src.Stride
-
-	delta := float64(srcWidth) / float64(width)
-	// Scale must be at least 1. Special case for image size reduction filter radius.
-	scale := math.Max(delta, 1.0)
+// resampleHorizontal resamples the rows of src to the width of dst, which has the same height and layout.
+func resampleHorizontal[T pixel.Sample](ctx context.Context, dst, src *pixel.Buffer[T], filter ResampleFilter) error {
+	srcHeight := src.Rect.Dy()
+	width := dst.Rect.Dx()
+	n := src.Channels()
+	r := newResampler(src.Rect.Dx(), width, filter)
 
-	dst := image.NewRGBA(image.Rect(0, 0, width, srcHeight))
-	dstStride := dst.Stride
+	return parallel.LineCtx(ctx, srcHeight, func(start, end int) {
+		// The weights of each column are computed once for all the rows
+		weights := make([]float64, 0, r.maxTaps())
+		for x := 0; x < width; x++ {
+			istart, weights, sum := r.weights(x, weights)
+			for y := start; y < end; y++ {
+				sums := weightedSum(src, y*src.Stride+istart*n, n, weights)
+				setWeighted(dst, y*dst.Stride+x*n, sums, sum)
+			}
+		}
+	})
+}
 
-	filterRadius := math.Ceil(scale * filter.Support)
+// resampleVertical resamples the columns of src to the height of dst, which has the same width and layout.
+func resampleVertical[T pixel.Sample](ctx context.Context, dst, src *pixel.Buffer[T], filter ResampleFilter) error {
+	srcWidth := src.Rect.Dx()
+	height := dst.Rect.Dy()
+	n := src.Channels()
+	r := newResampler(src.Rect.Dy(), height, filter)
 
-	parallel.Line(srcHeight, func(start, end int) {
+	return parallel.LineCtx(ctx, height, func(start, end int) {
+		weights := make([]float64, 0, r.maxTaps())
 		for y := start; y < end; y++ {
-			for x := 0; x < width; x++ {
-				// value of x from src
-				ix := (float64(x)+0.5)*delta - 0.5
-				istart, iend := int(ix-filterRadius+0.5), int(ix+filterRadius)
-
-				if istart < 0 {
-					istart = 0
-				}
-				if iend >= srcWidth {
-					iend = srcWidth - 1
-				}
-
-				var r, g, b, a float64
-				var sum float64
-				for kx := istart; kx <= iend; kx++ {
-
-					srcPos := y*srcStride + kx*4
-					// normalize the sample position to be evaluated by the filter
-					normPos := (float64(kx) - ix) / scale
-					fValue := filter.Fn(normPos)
-
-					r += float64(src.Pix[srcPos+0]) * fValue
-					g += float64(src.Pix[srcPos+1]) * fValue
-					b += float64(src.Pix[srcPos+2]) * fValue
-					a += float64(src.Pix[srcPos+3]) * fValue
-					sum += fValue
-				}
-
-				dstPos := y*dstStride + x*4
-				dst.Pix[dstPos+0] = uint8(f64.Clamp((r/sum)+0.5, 0, 255))
-				dst.Pix[dstPos+1] = uint8(f64.Clamp((g/sum)+0.5, 0, 255))
-				dst.Pix[dstPos+2] = uint8(f64.Clamp((b/sum)+0.5, 0, 255))
-				dst.Pix[dstPos+3] = uint8(f64.Clamp((a/sum)+0.5, 0, 255))
+			istart, weights, sum := r.weights(y, weights)
+			for x := 0; x < srcWidth; x++ {
+				sums := weightedSum(src, istart*src.Stride+x*n, src.Stride, weights)
+				setWeighted(dst, y*dst.Stride+x*n, sums, sum)
 			}
 		}
 	})
-
-	return dst
 }
 
-func resampleVertical(src *image.RGBA, height int, filter ResampleFilter) *image.RGBA {
-	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
-	srcStride := src.Stride
+// resampler computes the filter weights of the pixels of a row or column of srcN pixels resampled to a new size.
+type resampler struct {
+	srcN                 int
+	delta, scale, radius float64
+	filter               ResampleFilter
+}
 
-	delta := float64(srcHeight) / float64(height)
+// newResampler returns a resampler of srcN pixels to n pixels with the filter.
+func newResampler(srcN, n int, filter ResampleFilter) resampler {
+	delta := float64(srcN) / float64(n)
+	// Scale must be at least 1. Special case for image size reduction filter radius.
 	scale := math.Max(delta, 1.0)
 
-	dst := image.NewRGBA(image.Rect(0, 0, srcWidth, height))
-	dstStride := dst.Stride
+	return resampler{srcN: srcN, delta: delta, scale: scale, radius: math.Ceil(scale * filter.Support), filter: filter}
+}
 
-	filterRadius := math.Ceil(scale * filter.Support)
+// maxTaps returns the largest number of source pixels making up a resampled pixel.
+func (r resampler) maxTaps() int {
+	return int(2*r.radius) + 1
+}
 
-	parallel.Line(height, func(start, end int) {
-		for y := start; y < end; y++ {
-			iy := (float64(y)+0.5)*delta - 0.5
+// weights returns the index of the first source pixel making up the resampled pixel i, followed by the
+// weights of the source pixels from it on, stored in the provided slice, and the sum of the weights.
+func (r resampler) weights(i int, weights []float64) (int, []float64, float64) {
+	// value of i from src
+	is := (float64(i)+0.5)*r.delta - 0.5
+	istart, iend := int(is-r.radius+0.5), int(is+r.radius)
 
-			istart, iend := int(iy-filterRadius+0.5), int(iy+filterRadius)
+	if istart < 0 {
+		istart = 0
+	}
+	if iend >= r.srcN {
+		iend = r.srcN - 1
+	}
 
-			if istart < 0 {
-				istart = 0
-			}
-			if iend >= srcHeight {
-				iend = srcHeight - 1
-			}
+	weights = weights[:0]
+	var sum float64
+	for k := istart; k <= iend; k++ {
+		// normalize the sample position to be evaluated by the filter
+		normPos := (float64(k) - is) / r.scale
+		fValue := r.filter.Fn(normPos)
 
-			for x := 0; x < srcWidth; x++ {
-				var r, g, b, a float64
-				var sum float64
-				for ky := istart; ky <= iend; ky++ {
-
-					srcPos := ky*srcStride + x*4
-					normPos := (float64(ky) - iy) / scale
-					fValue := filter.Fn(normPos)
-
-					r += float64(src.Pix[srcPos+0]) * fValue
-					g += float64(src.Pix[srcPos+1]) * fValue
-					b += float64(src.Pix[srcPos+2]) * fValue
-					a += float64(src.Pix[srcPos+3]) * fValue
-					sum += fValue
-				}
-
-				dstPos := y*dstStride + x*4
-				dst.Pix[dstPos+0] = uint8(f64.Clamp((r/sum)+0.5, 0, 255))
-				dst.Pix[dstPos+1] = uint8(f64.Clamp((g/sum)+0.5, 0, 255))
-				dst.Pix[dstPos+2] = uint8(f64.Clamp((b/sum)+0.5, 0, 255))
-				dst.Pix[dstPos+3] = uint8(f64.Clamp((a/sum)+0.5, 0, 255))
-			}
-		}
-	})
-
-	return dst
+		weights = append(weights, fValue)
+		sum += fValue
+	}
+	return istart, weights, sum
 }
 
-func nearestNeighbor(src *image.RGBA, width, height int) *image.RGBA {
-	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+// nearestNeighbor resamples src to the size of dst, which has the same layout, picking the closest pixel of src.
+func nearestNeighbor[T pixel.Sample](ctx context.Context, dst, src *pixel.Buffer[T]) error {
+	srcW, srcH := src.Rect.Dx(), src.Rect.Dy()
 	srcStride := src.Stride
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
+	n := src.Channels()
 
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
 	dstStride := dst.Stride
 
 	dx := float64(srcW) / float64(width)
 	dy := float64(srcH) / float64(height)
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			pos := y*dstStride + x*4
-			ipos := int((float64(y)+0.5)*dy)*srcStride + int((float64(x)+0.5)*dx)*4
+	return parallel.LineCtx(ctx, height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				pos := y*dstStride + x*n
+				ipos := int((float64(y)+0.5)*dy)*srcStride + int((float64(x)+0.5)*dx)*n
 
-			dst.Pix[pos+0] = src.Pix[ipos+0]
-			dst.Pix[pos+1] = src.Pix[ipos+1]
-			dst.Pix[pos+2] = src.Pix[ipos+2]
-			dst.Pix[pos+3] = src.Pix[ipos+3]
+				copy(dst.Pix[pos:pos+n], src.Pix[ipos:ipos+n])
+			}
 		}
+	})
+}
+
+// weightedSum returns the sums of the channel values of the pixels from pos on, step apart, multiplied
+// by the weights. The colors of non-premultiplied pixels are premultiplied by their alpha value first.
+func weightedSum[T pixel.Sample](b *pixel.Buffer[T], pos, step int, weights []float64) [4]float64 {
+	var sums [4]float64
+
+	switch b.Layout {
+	case pixel.Gray:
+		for _, w := range weights {
+			sums[0] += float64(b.Pix[pos]) * w
+			pos += step
+		}
+	case pixel.NRGBA:
+		maxValue := float64(b.Max())
+		for _, w := range weights {
+			p := b.Pix[pos : pos+4 : pos+4]
+			a := float64(p[3])
+			f := a / maxValue * w
+			sums[0] += float64(p[0]) * f
+			sums[1] += float64(p[1]) * f
+			sums[2] += float64(p[2]) * f
+			sums[3] += a * w
+			pos += step
+		}
+	default:
+		for _, w := range weights {
+			p := b.Pix[pos : pos+4 : pos+4]
+			sums[0] += float64(p[0]) * w
+			sums[1] += float64(p[1]) * w
+			sums[2] += float64(p[2]) * w
+			sums[3] += float64(p[3]) * w
+			pos += step
+		}
+	}
+
+	return sums
+}
+
+// setWeighted rounds the weighted sums divided by their total weight into the pixel at pos, keeping the
+// result a valid color as setPremultiplied does. Non-premultiplied colors are divided back by the alpha value.
+func setWeighted[T pixel.Sample](b *pixel.Buffer[T], pos int, sums [4]float64, sum float64) {
+	maxValue := float64(b.Max())
+	if b.Layout == pixel.Gray {
+		b.Pix[pos] = T(f64.Clamp(sums[0]/sum+0.5, 0, maxValue))
+		return
 	}
 
-	return dst
+	r, g, bl, a := sums[0]/sum, sums[1]/sum, sums[2]/sum, sums[3]/sum
+	if a > maxValue {
+		f := maxValue / a
+		r, g, bl, a = r*f, g*f, bl*f, maxValue
+	}
+	alpha := T(f64.Clamp(a+0.5, 0, maxValue))
+	pix := b.Pix[pos : pos+4 : pos+4]
+	pix[3] = alpha
+
+	if b.Layout == pixel.NRGBA {
+		if alpha == 0 {
+			pix[0], pix[1], pix[2] = 0, 0, 0
+			return
+		}
+		f := maxValue / a
+		pix[0] = T(f64.Clamp(r*f+0.5, 0, maxValue))
+		pix[1] = T(f64.Clamp(g*f+0.5, 0, maxValue))
+		pix[2] = T(f64.Clamp(bl*f+0.5, 0, maxValue))
+		return
+	}
+
+	max := float64(alpha)
+	pix[0] = T(f64.Clamp(r+0.5, 0, max))
+	pix[1] = T(f64.Clamp(g+0.5, 0, max))
+	pix[2] = T(f64.Clamp(bl+0.5, 0, max))
+}
+
+// setPremultiplied rounds the channel values into the 4 bytes of pix, keeping the result a valid premultiplied color.
+// Filters with negative lobes can overshoot the alpha value next to transparent pixels. Color channels are
+// limited to the alpha value and an alpha overshoot scales down the whole color, otherwise the edges would
+// show bright or colored halos once un-premultiplied.
+func setPremultiplied(pix []uint8, r, g, b, a float64) {
+	if a > 255 {
+		f := 255 / a
+		r, g, b, a = r*f, g*f, b*f, 255
+	}
+	alpha := uint8(f64.Clamp(a+0.5, 0, 255))
+	max := float64(alpha)
+	pix[0] = uint8(f64.Clamp(r+0.5, 0, max))
+	pix[1] = uint8(f64.Clamp(g+0.5, 0, max))
+	pix[2] = uint8(f64.Clamp(b+0.5, 0, max))
+	pix[3] = alpha
 }
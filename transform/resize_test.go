@@ -1,9 +1,17 @@
 package transform
 
 import (
+	"context"
 	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"runtime"
 	"testing"
 
+	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/pixel"
 	"github.com/anthonynsimon/bild/util"
 )
 
@@ -470,10 +478,10 @@ func TestResizeCatmullRom(t *testing.T) {
 				Stride: 4 * 4,
 				Rect:   image.Rect(0, 0, 4, 4),
 				Pix: []uint8{
-					0xFF, 0x0, 0x0, 0xFF, 0xD9, 0x37, 0x0, 0xFF, 0x31, 0xD3, 0x0, 0xFF, 0x0, 0xFF, 0x0, 0xFF,
-					0xCA, 0x0, 0x35, 0xFF, 0xA6, 0x30, 0x2F, 0xFA, 0x3F, 0xB5, 0x20, 0xEA, 0x1D, 0xE7, 0x18, 0xE2,
-					0x35, 0x0, 0xCA, 0xFF, 0x3F, 0x20, 0xB6, 0xEA, 0x5B, 0x7A, 0x7A, 0xAF, 0x6E, 0xA3, 0x5D, 0x92,
-					0x0, 0x0, 0xFF, 0xFF, 0xC, 0x19, 0xF9, 0xE3, 0x69, 0x5C, 0xA8, 0x91, 0x97, 0x81, 0x7F, 0x69,
+					0xFF, 0x0, 0x0, 0xFF, 0xD8, 0x37, 0x0, 0xFF, 0x2F, 0xCC, 0x0, 0xFF, 0x0, 0xFF, 0x0, 0xFF,
+					0xCA, 0x0, 0x35, 0xFF, 0xA6, 0x30, 0x2F, 0xFA, 0x3F, 0xB5, 0x20, 0xEA, 0x18, 0xE2, 0x18, 0xE2,
+					0x35, 0x0, 0xCA, 0xFF, 0x3F, 0x20, 0xB6, 0xEA, 0x5B, 0x7A, 0x7A, 0xAF, 0x5D, 0x92, 0x5D, 0x92,
+					0x0, 0x0, 0xFF, 0xFF, 0xC, 0x19, 0xE3, 0xE3, 0x69, 0x5C, 0x91, 0x91, 0x69, 0x69, 0x69, 0x69,
 				},
 			},
 		},
@@ -534,10 +542,10 @@ func TestResizeMitchell(t *testing.T) {
 				Stride: 4 * 4,
 				Rect:   image.Rect(0, 0, 4, 4),
 				Pix: []uint8{
-					0xFF, 0x0, 0x0, 0xFF, 0xC5, 0x40, 0x0, 0xFF, 0x3E, 0xC3, 0x0, 0xFF, 0x0, 0xFF, 0x0, 0xFF,
-					0xC0, 0x0, 0x3F, 0xFF, 0x99, 0x37, 0x37, 0xF7, 0x47, 0xA8, 0x27, 0xE7, 0x21, 0xE1, 0x1F, 0xDF,
-					0x3F, 0x0, 0xC0, 0xFF, 0x47, 0x28, 0xA9, 0xE8, 0x58, 0x78, 0x78, 0xB7, 0x63, 0xA2, 0x5D, 0x9C,
-					0x0, 0x0, 0xFF, 0xFF, 0x1B, 0x1F, 0xE7, 0xDF, 0x61, 0x5D, 0xA4, 0x9C, 0x88, 0x80, 0x80, 0x78,
+					0xFF, 0x0, 0x0, 0xFF, 0xC4, 0x40, 0x0, 0xFF, 0x3D, 0xC1, 0x0, 0xFF, 0x0, 0xFF, 0x0, 0xFF,
+					0xC0, 0x0, 0x3F, 0xFF, 0x99, 0x37, 0x37, 0xF7, 0x47, 0xA8, 0x27, 0xE7, 0x1F, 0xDF, 0x1F, 0xDF,
+					0x3F, 0x0, 0xC0, 0xFF, 0x47, 0x28, 0xA9, 0xE8, 0x58, 0x78, 0x78, 0xB7, 0x5D, 0x9C, 0x5D, 0x9C,
+					0x0, 0x0, 0xFF, 0xFF, 0x1B, 0x1F, 0xDF, 0xDF, 0x61, 0x5D, 0x9C, 0x9C, 0x78, 0x78, 0x78, 0x78,
 				},
 			},
 		},
@@ -598,10 +606,10 @@ func TestResizeLanczos(t *testing.T) {
 				Stride: 4 * 4,
 				Rect:   image.Rect(0, 0, 4, 4),
 				Pix: []uint8{
-					0xFF, 0x0, 0x0, 0xFF, 0xE1, 0x40, 0x0, 0xFF, 0x34, 0xD5, 0x0, 0xFF, 0x0, 0xFF, 0x0, 0xFF,
-					0xC4, 0x0, 0x3B, 0xFF, 0x9D, 0x34, 0x34, 0xF8, 0x44, 0xAD, 0x25, 0xE8, 0x23, 0xE7, 0x19, 0xDC,
-					0x3B, 0x0, 0xC4, 0xFF, 0x45, 0x25, 0xAD, 0xE8, 0x59, 0x79, 0x79, 0xB5, 0x73, 0xAE, 0x51, 0x8D,
-					0x0, 0x0, 0xFF, 0xFF, 0x1, 0x19, 0xFF, 0xDC, 0x69, 0x51, 0xBA, 0x8D, 0xB0, 0x84, 0x7D, 0x50,
+					0xFF, 0x0, 0x0, 0xFF, 0xDD, 0x3F, 0x0, 0xFF, 0x31, 0xC8, 0x0, 0xFF, 0x0, 0xFF, 0x0, 0xFF,
+					0xC4, 0x0, 0x3B, 0xFF, 0x9D, 0x34, 0x34, 0xF8, 0x44, 0xAD, 0x25, 0xE8, 0x19, 0xDC, 0x19, 0xDC,
+					0x3B, 0x0, 0xC4, 0xFF, 0x45, 0x25, 0xAD, 0xE8, 0x59, 0x79, 0x79, 0xB5, 0x51, 0x8D, 0x51, 0x8D,
+					0x0, 0x0, 0xFF, 0xFF, 0x1, 0x19, 0xDC, 0xDC, 0x69, 0x51, 0x8D, 0x8D, 0x50, 0x50, 0x50, 0x50,
 				},
 			},
 		},
@@ -792,6 +800,296 @@ func TestCrop(t *testing.T) {
 	}
 }
 
+func TestResizeWithOptions(t *testing.T) {
+	cases := []struct {
+		name     string
+		width    int
+		height   int
+		filter   ResampleFilter
+		options  *ResizeOptions
+		img      *image.RGBA
+		expected *image.RGBA
+	}{
+		{
+			name:    "nil options",
+			width:   1,
+			height:  1,
+			filter:  Linear,
+			options: nil,
+			img: &image.RGBA{
+				Stride: 2 * 4,
+				Rect:   image.Rect(0, 0, 2, 2),
+				Pix: []uint8{
+					0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Stride: 1 * 4,
+				Rect:   image.Rect(0, 0, 1, 1),
+				Pix:    []uint8{0x80, 0x80, 0x80, 0xFF},
+			},
+		},
+		{
+			name:    "linear rgb checkerboard",
+			width:   1,
+			height:  1,
+			filter:  Linear,
+			options: &ResizeOptions{ColorSpace: LinearRGB},
+			img: &image.RGBA{
+				Stride: 2 * 4,
+				Rect:   image.Rect(0, 0, 2, 2),
+				Pix: []uint8{
+					0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Stride: 1 * 4,
+				Rect:   image.Rect(0, 0, 1, 1),
+				Pix:    []uint8{0xBC, 0xBC, 0xBC, 0xFF},
+			},
+		},
+		{
+			name:    "linear rgb same size",
+			width:   2,
+			height:  1,
+			filter:  Lanczos,
+			options: &ResizeOptions{ColorSpace: LinearRGB},
+			img: &image.RGBA{
+				Stride: 2 * 4,
+				Rect:   image.Rect(0, 0, 2, 1),
+				Pix: []uint8{
+					0x10, 0x80, 0xF0, 0xFF, 0x20, 0x20, 0x20, 0x80,
+				},
+			},
+			expected: &image.RGBA{
+				Stride: 2 * 4,
+				Rect:   image.Rect(0, 0, 2, 1),
+				Pix: []uint8{
+					0x10, 0x80, 0xF0, 0xFF, 0x20, 0x20, 0x20, 0x80,
+				},
+			},
+		},
+		{
+			name:    "linear rgb transparent",
+			width:   1,
+			height:  1,
+			filter:  Linear,
+			options: &ResizeOptions{ColorSpace: LinearRGB},
+			img: &image.RGBA{
+				Stride: 2 * 4,
+				Rect:   image.Rect(0, 0, 2, 1),
+				Pix: []uint8{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			expected: &image.RGBA{
+				Stride: 1 * 4,
+				Rect:   image.Rect(0, 0, 1, 1),
+				Pix:    []uint8{0x00, 0x00, 0x00, 0x00},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual := ResizeWithOptions(c.img, c.width, c.height, c.filter, c.options)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s: expected: %#v, actual: %#v", "ResizeWithOptions "+c.name, util.RGBAToString(c.expected), util.RGBAToString(actual))
+		}
+	}
+}
+
+// transparentEdgeImage returns an image with an opaque brown square in the middle of a fully
+// transparent area, whose pixels hold a green color that must not bleed into the result.
+func transparentEdgeImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x >= w/4 && x < w*3/4 && y >= h/4 && y < h*3/4 {
+				img.SetNRGBA(x, y, color.NRGBA{0x80, 0x40, 0x00, 0xFF})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{0x00, 0xFF, 0x00, 0x00})
+			}
+		}
+	}
+	return img
+}
+
+// checkNoHalo reports an error if any pixel in img isn't the brown color of transparentEdgeImage once
+// un-premultiplied, allowing for the rounding error of the low alpha values.
+func checkNoHalo(t *testing.T, name string, img *image.RGBA) {
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+
+			tolerance := 2*255/float64(c.A) + 1
+			r := float64(c.R) * 255 / float64(c.A)
+			g := float64(c.G) * 255 / float64(c.A)
+			if c.R > c.A || c.G > c.A || c.B != 0 || math.Abs(r-0x80) > tolerance || math.Abs(g-0x40) > tolerance {
+				t.Errorf("%s: expected brown at %d,%d actual: %#v", name, x, y, c)
+				return
+			}
+		}
+	}
+}
+
+func TestResizeTransparentEdges(t *testing.T) {
+	filters := map[string]ResampleFilter{
+		"NearestNeighbor":   NearestNeighbor,
+		"Box":               Box,
+		"Linear":            Linear,
+		"Gaussian":          Gaussian,
+		"MitchellNetravali": MitchellNetravali,
+		"CatmullRom":        CatmullRom,
+		"Lanczos":           Lanczos,
+	}
+
+	img := transparentEdgeImage(16, 16)
+	for name, filter := range filters {
+		checkNoHalo(t, "Resize up "+name, Resize(img, 37, 29, filter))
+		checkNoHalo(t, "Resize down "+name, Resize(img, 7, 5, filter))
+		checkNoHalo(t, "ResizeWithOptions LinearRGB "+name, ResizeWithOptions(img, 7, 5, filter, &ResizeOptions{ColorSpace: LinearRGB}))
+	}
+}
+
+func TestResize64(t *testing.T) {
+	filters := map[string]ResampleFilter{
+		"NearestNeighbor":   NearestNeighbor,
+		"Box":               Box,
+		"Linear":            Linear,
+		"Gaussian":          Gaussian,
+		"MitchellNetravali": MitchellNetravali,
+		"CatmullRom":        CatmullRom,
+		"Lanczos":           Lanczos,
+	}
+	colorSpaces := map[string]ColorSpace{"SRGB": SRGB, "LinearRGB": LinearRGB}
+
+	// Matches the 8-bit resize, up to its rounding
+	img := transparentEdgeImage(16, 16)
+	for name, filter := range filters {
+		for csName, cs := range colorSpaces {
+			for _, size := range []image.Point{{37, 29}, {7, 5}} {
+				options := &ResizeOptions{ColorSpace: cs}
+				actual := ResizeWithOptions64(img, size.X, size.Y, filter, options)
+				expected := clone.AsRGBA64(ResizeWithOptions(img, size.X, size.Y, filter, options))
+				if !util.RGBA64ImageApproxEqual(actual, expected, 2*0x101) {
+					t.Errorf("%s: expected: %v actual: %v", "ResizeWithOptions64 "+name+" "+csName, expected.Pix, actual.Pix)
+				}
+			}
+		}
+	}
+
+	// Values between those of 8 bits are kept
+	flat := image.NewRGBA64(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			flat.SetRGBA64(x, y, color.RGBA64{0x8001, 0x1234, 0x0007, 0xFFFF})
+		}
+	}
+	for name, filter := range filters {
+		actual := Resize64(flat, 3, 5, filter)
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 3; x++ {
+				if c := actual.RGBA64At(x, y); c != (color.RGBA64{0x8001, 0x1234, 0x0007, 0xFFFF}) {
+					t.Errorf("%s: expected: %v actual: %v", "Resize64 "+name, flat.RGBA64At(0, 0), c)
+				}
+			}
+		}
+	}
+
+	if actual := Resize64(flat, 0, 5, Linear); !actual.Bounds().Empty() {
+		t.Errorf("%s: expected: %v actual: %v", "Resize64 empty", image.Rectangle{}, actual.Bounds())
+	}
+}
+
+func TestResizeNative(t *testing.T) {
+	filters := map[string]ResampleFilter{
+		"NearestNeighbor": NearestNeighbor,
+		"Linear":          Linear,
+		"Lanczos":         Lanczos,
+	}
+	colorSpaces := map[string]ColorSpace{"SRGB": SRGB, "LinearRGB": LinearRGB}
+
+	gray := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 7)
+	}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	copy(nrgba.Pix, transparentEdgeImage(16, 16).Pix)
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		nrgba.Pix[i+3] = uint8(i / 4)
+	}
+
+	for name, filter := range filters {
+		for csName, cs := range colorSpaces {
+			options := &ResizeOptions{ColorSpace: cs}
+			for _, size := range []image.Point{{37, 29}, {7, 5}} {
+				// RGBA images give the same result as ResizeWithOptions
+				rgba := clone.AsRGBA(transparentEdgeImage(16, 16))
+				expected := ResizeWithOptions(rgba, size.X, size.Y, filter, options)
+				if actual := ResizeNative(rgba, size.X, size.Y, filter, options); !util.RGBAImageEqual(actual, expected) {
+					t.Errorf("%s: expected: %v actual: %v", "ResizeNative RGBA "+name+" "+csName, expected.Pix, actual.Pix)
+				}
+
+				// Matches the luminance of the RGBA resize
+				actualGray := ResizeNative(gray, size.X, size.Y, filter, options)
+				expectedGray := ResizeWithOptions(gray, size.X, size.Y, filter, options)
+				if actualGray.Bounds() != expectedGray.Bounds() {
+					t.Fatalf("%s: expected: %v actual: %v", "ResizeNative Gray "+name, expectedGray.Bounds(), actualGray.Bounds())
+				}
+				for i, v := range actualGray.Pix {
+					if d := int(v) - int(expectedGray.Pix[i*4]); d < -1 || d > 1 {
+						t.Errorf("%s: expected: %v actual: %v", "ResizeNative Gray "+name+" "+csName, expectedGray.Pix[i*4], v)
+						break
+					}
+				}
+
+				// Non-premultiplied colors match those of the premultiplied resize, unless the negative
+				// lobes of the filter amplify the precision the latter loses between its two passes
+				if filter.Support > 2 {
+					continue
+				}
+				actualNRGBA := ResizeNative(nrgba, size.X, size.Y, filter, options)
+				expectedNRGBA := ResizeWithOptions(nrgba, size.X, size.Y, filter, options)
+				for y := 0; y < size.Y; y++ {
+					for x := 0; x < size.X; x++ {
+						e, a := color.NRGBAModel.Convert(expectedNRGBA.At(x, y)).(color.NRGBA), actualNRGBA.NRGBAAt(x, y)
+						// Translucent colors lose precision once premultiplied in RGBA
+						tolerance := 2 + 0xFF/max(int(e.A), 1)
+						for _, d := range []int{int(e.R) - int(a.R), int(e.G) - int(a.G), int(e.B) - int(a.B), int(e.A) - int(a.A)} {
+							if d < -tolerance || d > tolerance {
+								t.Errorf("%s: expected: %v actual: %v", "ResizeNative NRGBA "+name+" "+csName, e, a)
+								break
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	gray16 := image.NewGray16(image.Rect(0, 0, 8, 8))
+	for i := 0; i < len(gray16.Pix); i += 2 {
+		gray16.Pix[i], gray16.Pix[i+1] = 0x80, 0x01
+	}
+	actual := ResizeNative(gray16, 3, 5, Lanczos, nil)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 3; x++ {
+			if c := actual.Gray16At(x, y); c.Y != 0x8001 {
+				t.Errorf("%s: expected: %v actual: %v", "ResizeNative Gray16", 0x8001, c.Y)
+			}
+		}
+	}
+
+	if empty := ResizeNative(gray16, 0, 5, Lanczos, nil); !empty.Bounds().Empty() {
+		t.Errorf("%s: expected: %v actual: %v", "ResizeNative empty", image.Rectangle{}, empty.Bounds())
+	}
+}
+
 func BenchmarkResizeTenth(b *testing.B) {
 	benchResize(b, 4096, 4096, 0.1, Linear)
 }
@@ -824,6 +1122,14 @@ func BenchmarkResize16x(b *testing.B) {
 	benchResize(b, 1024, 1024, 16.0, Linear)
 }
 
+func BenchmarkResizeHalfLinearRGB(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 4096, 4096))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = ResizeWithOptions(img, 2048, 2048, Linear, &ResizeOptions{ColorSpace: LinearRGB})
+	}
+}
+
 func benchResize(b *testing.B, w, h int, scale float64, f ResampleFilter) {
 	newW := int(float64(w) * scale)
 	newH := int(float64(h) * scale)
@@ -833,3 +1139,116 @@ func benchResize(b *testing.B, w, h int, scale float64, f ResampleFilter) {
 		benchResult = Resize(img, newW, newH, f)
 	}
 }
+
+func TestResizeCtx(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 11)
+	}
+	buf := pixel.NewBuffer[uint16](image.Rect(0, 0, 16, 16), pixel.NRGBA)
+	for i := range buf.Pix {
+		buf.Pix[i] = uint16(i * 2741)
+	}
+
+	cases := []struct {
+		description string
+		filter      ResampleFilter
+		options     *ResizeOptions
+	}{
+		{"NearestNeighbor", NearestNeighbor, nil},
+		{"Linear", Linear, nil},
+		{"Lanczos LinearRGB", Lanczos, &ResizeOptions{ColorSpace: LinearRGB}},
+	}
+
+	for _, c := range cases {
+		var reports []float64
+		ctx := parallel.WithProgress(context.Background(), func(d float64) { reports = append(reports, d) })
+		actual, err := ResizeWithOptionsCtx(ctx, img, 7, 9, c.filter, c.options)
+		if err != nil || !util.RGBAImageEqual(actual, ResizeWithOptions(img, 7, 9, c.filter, c.options)) {
+			t.Errorf("%s: expected: %v actual: %v", "ResizeWithOptionsCtx "+c.description, "same result as ResizeWithOptions", err)
+		}
+		if len(reports) == 0 || math.Abs(reports[len(reports)-1]-1) > 1e-9 {
+			t.Errorf("%s: expected: %v actual: %v", "ResizeWithOptionsCtx progress "+c.description, 1.0, reports)
+		}
+
+		reports = nil
+		actualBuf, err := ResizeBufferCtx(ctx, buf, 7, 9, c.filter, c.options)
+		if err != nil || !reflect.DeepEqual(actualBuf, ResizeBuffer(buf, 7, 9, c.filter, c.options)) {
+			t.Errorf("%s: expected: %v actual: %v", "ResizeBufferCtx "+c.description, "same result as ResizeBuffer", err)
+		}
+		if len(reports) == 0 || math.Abs(reports[len(reports)-1]-1) > 1e-9 {
+			t.Errorf("%s: expected: %v actual: %v", "ResizeBufferCtx progress "+c.description, 1.0, reports)
+		}
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		if actual, err := ResizeCtx(canceled, img, 7, 9, c.filter); err != context.Canceled || actual != nil {
+			t.Errorf("%s: expected: %v actual: %v %v", "ResizeCtx canceled "+c.description, context.Canceled, err, actual)
+		}
+		if actual, err := ResizeBufferCtx(canceled, buf, 7, 9, c.filter, c.options); err != context.Canceled || actual != nil {
+			t.Errorf("%s: expected: %v actual: %v %v", "ResizeBufferCtx canceled "+c.description, context.Canceled, err, actual)
+		}
+	}
+}
+
+func TestResizeInto(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 24, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	// Transparent pixels must come out transparent even if dst isn't cleared
+	for i := 0; i < 24*4; i++ {
+		img.Pix[i] = 0
+	}
+
+	cases := []struct {
+		description string
+		filter      ResampleFilter
+		options     *ResizeOptions
+	}{
+		{"NearestNeighbor", NearestNeighbor, nil},
+		{"Linear", Linear, nil},
+		{"Lanczos LinearRGB", Lanczos, &ResizeOptions{ColorSpace: LinearRGB}},
+	}
+
+	for _, c := range cases {
+		for _, size := range []image.Point{{10, 7}, {30, 20}} {
+			expected := ResizeWithOptions(img, size.X, size.Y, c.filter, c.options)
+			for i := 0; i < 2; i++ {
+				dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+				for i := range dst.Pix {
+					dst.Pix[i] = 0xFF
+				}
+				ResizeWithOptionsInto(dst, img, c.filter, c.options)
+				if !util.RGBAImageEqual(dst, expected) {
+					t.Errorf("%s: expected: %v actual: %v", "ResizeWithOptionsInto "+c.description, expected, dst)
+				}
+			}
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	dst.Pix[0] = 0xFF
+	ResizeInto(dst, image.NewRGBA(image.Rect(0, 0, 0, 0)), Linear)
+	if expected := image.NewRGBA(dst.Rect); !util.RGBAImageEqual(dst, expected) {
+		t.Errorf("%s: expected: %v actual: %v", "ResizeInto empty source", expected, dst)
+	}
+
+	// Once the pools hold the intermediate images, resizing another image allocates no pixels.
+	// The pools may drop their images at any time, so the run allocating the least is checked.
+	for _, options := range []*ResizeOptions{nil, {ColorSpace: LinearRGB}} {
+		dst := image.NewRGBA(image.Rect(0, 0, 40, 30))
+		ResizeWithOptionsInto(dst, img, Linear, options)
+		allocated := uint64(math.MaxUint64)
+		for i := 0; i < 50; i++ {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			ResizeWithOptionsInto(dst, img, Linear, options)
+			runtime.ReadMemStats(&after)
+			allocated = min(allocated, after.TotalAlloc-before.TotalAlloc)
+		}
+		if allocated >= uint64(len(dst.Pix)) {
+			t.Errorf("%s: expected: %v actual: %v", "ResizeWithOptionsInto allocated bytes", "less than an image", allocated)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package transform
 
 import (
 	"image"
-	"image/color"
 	"math"
 
 	"github.com/anthonynsimon/bild/clone"
@@ -31,15 +30,14 @@ func Rotate(img image.Image, angle float64, options *RotationOptions) *image.RGB
 	src := clone.AsShallowRGBA(img)
 	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
 
-	supersample := false
+	filter := Linear
 	absAngle := int(math.Abs(angle) + 0.5)
 	if absAngle%360 == 0 {
 		// Return early if nothing to do
 		return src
-	} else if absAngle%90 != 0 {
-		// Supersampling is required for non-special angles
-		// Special angles = 90, 180, 270...
-		supersample = true
+	} else if absAngle%90 == 0 {
+		// Special angles = 90, 180, 270... map pixels exactly, no interpolation needed
+		filter = NearestNeighbor
 	}
 
 	// Config defaults
@@ -54,77 +52,36 @@ func Rotate(img image.Image, angle float64, options *RotationOptions) *image.RGB
 		}
 	}
 
-	if supersample {
-		// Supersample, currently hard set to 2x
-		srcW, srcH = srcW*2, srcH*2
-		src = Resize(src, srcW, srcH, NearestNeighbor)
-		pivotX, pivotY = pivotX*2, pivotY*2
-	}
-
-	// Convert to radians, positive degree maps to clockwise rotation
-	angleRadians := -angle * (math.Pi / 180)
-
 	var dstW, dstH int
-	var sin, cos = math.Sincos(angleRadians)
 	if resizeBounds {
 		// Reserve larger size in destination image for full image bounds rotation
 		// If not preserving size, always take image center as pivot
 		pivotX, pivotY = float64(srcW)/2, float64(srcH)/2
 
+		sin, cos := math.Sincos(angle * (math.Pi / 180))
 		a := math.Abs(float64(srcW) * sin)
 		b := math.Abs(float64(srcW) * cos)
 		c := math.Abs(float64(srcH) * sin)
 		d := math.Abs(float64(srcH) * cos)
 
-		dstW, dstH = int(c+b+0.5), int(a+d+0.5)
+		// Round to the nearest half pixel first, which keeps the sizes of the former
+		// 2x supersampled implementation
+		dstW, dstH = int(2*(c+b)+0.5)/2, int(2*(a+d)+0.5)/2
 	} else {
 		dstW, dstH = srcW, srcH
 	}
-	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
 
 	// Calculate offsets in case entire image is being displayed
 	// Otherwise areas clipped by rotation won't be available
-	offsetX := (dstW - srcW) / 2
-	offsetY := (dstH - srcH) / 2
-
-	parallel.Line(srcH, func(start, end int) {
-		// Correct range to include the pixels visible in new bounds
-		// Note that cannot be done in parallelize function input height, otherwise ranges would overlap
-		yStart := int((float64(start)/float64(srcH))*float64(dstH)) - offsetY
-		yEnd := int((float64(end)/float64(srcH))*float64(dstH)) - offsetY
-		xStart := -offsetX
-		xEnd := srcW + offsetX
-
-		for y := yStart; y < yEnd; y++ {
-			dy := float64(y) - pivotY + 0.5
-			for x := xStart; x < xEnd; x++ {
-				dx := float64(x) - pivotX + 0.5
-
-				ix := int((cos*dx - sin*dy + pivotX))
-				iy := int((sin*dx + cos*dy + pivotY))
-
-				if ix < 0 || ix >= srcW || iy < 0 || iy >= srcH {
-					continue
-				}
-
-				red, green, blue, alpha := src.At(ix, iy).RGBA()
-
-				dst.Set(x+offsetX, y+offsetY, color.RGBA64{
-					R: uint16(red),
-					G: uint16(green),
-					B: uint16(blue),
-					A: uint16(alpha),
-				})
-			}
-		}
-	})
+	offsetX := float64(dstW-srcW) / 2
+	offsetY := float64(dstH-srcH) / 2
 
-	if supersample {
-		// Downsample to original bounds as part of the Supersampling
-		dst = Resize(dst, dstW/2, dstH/2, Linear)
-	}
+	m := IdentityAffine().
+		Translate(-pivotX, -pivotY).
+		Rotate(angle).
+		Translate(pivotX+offsetX, pivotY+offsetY)
 
-	return dst
+	return AffineWarp(src, m, &AffineOptions{Filter: &filter, Bounds: image.Rect(0, 0, dstW, dstH)})
 }
 
 // FlipH returns a horizontally flipped version of the image.
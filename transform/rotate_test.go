@@ -36,6 +36,28 @@ func TestRotate(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "angle 90.0 resize bounds odd size",
+			angle:       90.0,
+			options:     &RotationOptions{ResizeBounds: true},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 2),
+				Stride: 3 * 4,
+				Pix: []uint8{
+					0x01, 0x01, 0x01, 0xFF, 0x02, 0x02, 0x02, 0xFF, 0x03, 0x03, 0x03, 0xFF,
+					0x04, 0x04, 0x04, 0xFF, 0x05, 0x05, 0x05, 0xFF, 0x06, 0x06, 0x06, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 3),
+				Stride: 2 * 4,
+				Pix: []uint8{
+					0x04, 0x04, 0x04, 0xFF, 0x01, 0x01, 0x01, 0xFF,
+					0x05, 0x05, 0x05, 0xFF, 0x02, 0x02, 0x02, 0xFF,
+					0x06, 0x06, 0x06, 0xFF, 0x03, 0x03, 0x03, 0xFF,
+				},
+			},
+		},
 		{
 			description: "angle 90.0 at center",
 			angle:       90.0,
@@ -159,11 +181,11 @@ func TestRotate(t *testing.T) {
 				Rect:   image.Rect(0, 0, 5, 5),
 				Stride: 5 * 4,
 				Pix: []uint8{
-					0x5C, 0x5C, 0x5C, 0x87, 0x85, 0x85, 0x85, 0xF7, 0x81, 0x81, 0x81, 0xFF, 0x8C, 0x8C, 0x8C, 0xD1, 0x33, 0x33, 0x33, 0x33,
-					0xF0, 0xF0, 0xF0, 0xF7, 0xD3, 0xD3, 0xD3, 0xFF, 0xAD, 0xAD, 0xAD, 0xFF, 0xEF, 0xEF, 0xEF, 0xFD, 0x95, 0x95, 0x95, 0x95,
-					0xDF, 0xDE, 0xDE, 0xDE, 0xFD, 0xDA, 0xDA, 0xDB, 0xFC, 0xB3, 0xB3, 0xB7, 0xF6, 0xEC, 0xEC, 0xEC, 0x7E, 0x7E, 0x7E, 0x7E,
-					0x35, 0x2B, 0x2B, 0x2B, 0xC9, 0x6F, 0x6F, 0x6F, 0xF5, 0x7C, 0x7C, 0x7C, 0x82, 0x54, 0x54, 0x54, 0xA, 0xA, 0xA, 0xA,
-					0x00, 0x00, 0x00, 0x00, 0x35, 0x1B, 0x1B, 0x1B, 0x79, 0x3D, 0x3D, 0x3D, 0xA, 0x5, 0x5, 0x5, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x30, 0x30, 0x30, 0x61, 0x80, 0x80, 0x80, 0xFF, 0x30, 0x30, 0x30, 0x61, 0x00, 0x00, 0x00, 0x00,
+					0x61, 0x61, 0x61, 0x61, 0xC0, 0xC0, 0xC0, 0xFF, 0x80, 0x80, 0x80, 0xFF, 0xC0, 0xC0, 0xC0, 0xFF, 0x61, 0x61, 0x61, 0x61,
+					0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xDF, 0xC0, 0xC0, 0xDF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0x61, 0x61, 0x61, 0x61, 0xC0, 0xC0, 0xC0, 0xC0, 0x80, 0x80, 0x80, 0x80, 0xC0, 0xC0, 0xC0, 0xC0, 0x61, 0x61, 0x61, 0x61,
+					0x00, 0x00, 0x00, 0x00, 0x30, 0x30, 0x30, 0x30, 0x80, 0x80, 0x80, 0x80, 0x30, 0x30, 0x30, 0x30, 0x00, 0x00, 0x00, 0x00,
 				},
 			},
 		},
@@ -171,12 +193,11 @@ func TestRotate(t *testing.T) {
 
 	for _, c := range cases {
 		actual := Rotate(c.value, c.angle, c.options)
-		// Non-right-angle rotations use supersampling with nearest-neighbor lookup,
-		// which can produce platform-dependent boundary pixels due to floating-point
-		// truncation differences (e.g. arm64 vs amd64).
+		// Non-right-angle rotations interpolate between samples, which can produce
+		// platform-dependent rounding due to floating-point differences (e.g. arm64 vs amd64).
 		tolerance := 0
 		if int(c.angle+0.5)%90 != 0 {
-			tolerance = 11
+			tolerance = 1
 		}
 		if !util.RGBAImageApproxEqual(actual, c.expected, tolerance) {
 			t.Errorf("%s:\nexpected:%v\nactual:%v", "Rotate "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
@@ -326,6 +347,14 @@ func TestFlipV(t *testing.T) {
 	}
 }
 
+func TestRotateTransparentEdges(t *testing.T) {
+	img := transparentEdgeImage(16, 16)
+	for _, angle := range []float64{30, 45, 90, 135} {
+		checkNoHalo(t, "Rotate", Rotate(img, angle, nil))
+		checkNoHalo(t, "Rotate resize bounds", Rotate(img, angle, &RotationOptions{ResizeBounds: true}))
+	}
+}
+
 func BenchmarkRotation256(b *testing.B) {
 	benchRotate(256, 256, 90.0, b)
 }
@@ -0,0 +1,310 @@
+package transform
+
+import (
+	"image"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/effect"
+	"github.com/anthonynsimon/bild/math/integer"
+	"github.com/anthonynsimon/bild/parallel"
+)
+
+// seamMaskEnergy is the energy added to the pixels of the protect mask, and subtracted from
+// the pixels of the remove mask, at full mask intensity. It's orders of magnitude larger than
+// the energy of any pixel, so that the masks take precedence over the image content.
+const seamMaskEnergy = 1e7
+
+// SeamCarveOptions are the seam carving parameters.
+// Protect is a mask of the areas that seams should avoid, such as faces. Remove is a mask of the
+// areas that seams should go through first, so they disappear when the image is made smaller.
+// Both masks are in the coordinates of the source image and the intensity of each pixel sets
+// how strongly it is protected or removed, with 0 having no effect. Default of nil applies no mask.
+type SeamCarveOptions struct {
+	Protect *image.Gray
+	Remove  *image.Gray
+}
+
+// SeamCarve returns a new image resized to the provided width and height by removing or inserting
+// the connected paths of pixels (seams) with the least energy, which keeps the important content of
+// the image undistorted. The energy of each pixel is the magnitude of the Sobel gradient.
+// The width is adjusted first, followed by the height.
+// Default parameters are used if a nil *SeamCarveOptions is passed.
+//
+// Usage example:
+//
+//	// Make a 1600x900 image square without squashing the faces in the protect mask
+//	result := transform.SeamCarve(img, 900, 900, &transform.SeamCarveOptions{Protect: faces})
+func SeamCarve(img image.Image, width, height int, options *SeamCarveOptions) *image.RGBA {
+	if width <= 0 || height <= 0 || img.Bounds().Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	c := newCarver(clone.AsShallowRGBA(img), options)
+
+	c.resize(width)
+	c = c.transpose()
+	c.resize(height)
+	c = c.transpose()
+
+	return &image.RGBA{Pix: c.pix, Stride: c.w * 4, Rect: image.Rect(0, 0, c.w, c.h)}
+}
+
+// carver holds the pixels of the image being carved, four bytes per pixel in RGBA order, along with
+// the Sobel gradient magnitude and the energy bias given by the masks of each pixel.
+// Seams always run from the top to the bottom, the image is transposed to carve in the other direction.
+type carver struct {
+	pix   []uint8
+	edges []float64
+	bias  []float64
+	w, h  int
+}
+
+func newCarver(src *image.RGBA, options *SeamCarveOptions) *carver {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	c := &carver{pix: make([]uint8, w*h*4), bias: make([]float64, w*h), w: w, h: h}
+
+	for y := 0; y < h; y++ {
+		copy(c.pix[y*w*4:(y+1)*w*4], src.Pix[y*src.Stride:y*src.Stride+w*4])
+	}
+
+	if options != nil {
+		addMaskBias(c.bias, options.Protect, bounds, seamMaskEnergy)
+		addMaskBias(c.bias, options.Remove, bounds, -seamMaskEnergy)
+	}
+
+	return c
+}
+
+// addMaskBias adds the mask intensity scaled by energy to the bias of each pixel within bounds.
+func addMaskBias(bias []float64, mask *image.Gray, bounds image.Rectangle, energy float64) {
+	if mask == nil {
+		return
+	}
+
+	w := bounds.Dx()
+	area := bounds.Intersect(mask.Bounds())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			v := mask.Pix[mask.PixOffset(x, y)]
+			bias[(y-bounds.Min.Y)*w+x-bounds.Min.X] += float64(v) / 255 * energy
+		}
+	}
+}
+
+// resize removes or inserts vertical seams until the image has the provided width.
+func (c *carver) resize(width int) {
+	c.edges = make([]float64, c.w*c.h)
+	c.updateEdges(0, c.w)
+
+	for c.w > width {
+		c.carveSeam()
+	}
+
+	// Seams are inserted in batches of at most half of the width, so the same
+	// low energy areas aren't stretched over and over again.
+	for c.w < width {
+		n := width - c.w
+		if limit := (c.w + 1) / 2; n > limit {
+			n = limit
+		}
+		c.insertSeams(n)
+	}
+}
+
+// updateEdges recomputes the gradient magnitude of the columns from x0 (inclusive) to x1 (exclusive).
+func (c *carver) updateEdges(x0, x1 int) {
+	x0, x1 = integer.Max(x0, 0), integer.Min(x1, c.w)
+	if x0 >= x1 {
+		return
+	}
+
+	// The Sobel operator needs a column of context on each side of the area
+	lo, hi := integer.Max(x0-1, 0), integer.Min(x1+1, c.w)
+	img := &image.RGBA{Pix: c.pix[lo*4:], Stride: c.w * 4, Rect: image.Rect(0, 0, hi-lo, c.h)}
+	edges := effect.Sobel(img)
+
+	parallel.Line(c.h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := x0; x < x1; x++ {
+				pos := y*edges.Stride + (x-lo)*4
+				c.edges[y*c.w+x] = float64(edges.Pix[pos]) + float64(edges.Pix[pos+1]) + float64(edges.Pix[pos+2])
+			}
+		}
+	})
+}
+
+// energy returns the energy of each pixel in row-major order.
+// The gradient of the horizontal neighbours is added to the gradient of each pixel,
+// as the Sobel operator is zero in the middle of lines one pixel wide.
+func (c *carver) energy() []float64 {
+	energy := make([]float64, c.w*c.h)
+	parallel.Line(c.h, func(start, end int) {
+		for y := start; y < end; y++ {
+			row := c.edges[y*c.w : (y+1)*c.w]
+			for x := range row {
+				e := row[x]
+				if x > 0 {
+					e += row[x-1] / 2
+				}
+				if x < c.w-1 {
+					e += row[x+1] / 2
+				}
+				energy[y*c.w+x] = e + c.bias[y*c.w+x]
+			}
+		}
+	})
+
+	return energy
+}
+
+// carveSeam removes the seam with the least energy and returns it.
+func (c *carver) carveSeam() []int {
+	seam := c.findSeam(c.energy())
+	c.removeSeam(seam)
+
+	// Only the gradient of the pixels next to the seam changes
+	x0, x1 := seam[0], seam[0]
+	for _, x := range seam {
+		x0, x1 = integer.Min(x0, x), integer.Max(x1, x)
+	}
+	c.updateEdges(x0-2, x1+2)
+
+	return seam
+}
+
+// findSeam returns the column of each row of the connected top-to-bottom path with the least total energy.
+func (c *carver) findSeam(energy []float64) []int {
+	w, h := c.w, c.h
+
+	// Each value holds the least energy of any path reaching the pixel from the top row
+	cost := make([]float64, w*h)
+	copy(cost[:w], energy[:w])
+	for y := 1; y < h; y++ {
+		for x := 0; x < w; x++ {
+			prev := cost[(y-1)*w+x]
+			if x > 0 {
+				prev = math.Min(prev, cost[(y-1)*w+x-1])
+			}
+			if x < w-1 {
+				prev = math.Min(prev, cost[(y-1)*w+x+1])
+			}
+			cost[y*w+x] = energy[y*w+x] + prev
+		}
+	}
+
+	seam := make([]int, h)
+	last := (h - 1) * w
+	for x := 1; x < w; x++ {
+		if cost[last+x] < cost[last+seam[h-1]] {
+			seam[h-1] = x
+		}
+	}
+
+	// Walk back up through the cheapest of the three upper neighbours
+	for y := h - 2; y >= 0; y-- {
+		x := seam[y+1]
+		best := x
+		if x > 0 && cost[y*w+x-1] < cost[y*w+best] {
+			best = x - 1
+		}
+		if x < w-1 && cost[y*w+x+1] < cost[y*w+best] {
+			best = x + 1
+		}
+		seam[y] = best
+	}
+
+	return seam
+}
+
+// removeSeam removes the pixel at the seam column of each row, making the image one pixel narrower.
+func (c *carver) removeSeam(seam []int) {
+	w := c.w - 1
+	pix := make([]uint8, w*c.h*4)
+	edges := make([]float64, w*c.h)
+	bias := make([]float64, w*c.h)
+
+	for y := 0; y < c.h; y++ {
+		x := seam[y]
+		src, dst := y*c.w, y*w
+		copy(pix[dst*4:(dst+x)*4], c.pix[src*4:(src+x)*4])
+		copy(pix[(dst+x)*4:(dst+w)*4], c.pix[(src+x+1)*4:(src+c.w)*4])
+		copy(edges[dst:dst+x], c.edges[src:src+x])
+		copy(edges[dst+x:dst+w], c.edges[src+x+1:src+c.w])
+		copy(bias[dst:dst+x], c.bias[src:src+x])
+		copy(bias[dst+x:dst+w], c.bias[src+x+1:src+c.w])
+	}
+
+	c.pix, c.edges, c.bias, c.w = pix, edges, bias, w
+}
+
+// insertSeams finds the n seams that would be removed first and duplicates each of them,
+// making the image n pixels wider. The inserted pixels are the average of the seam and its right neighbour.
+func (c *carver) insertSeams(n int) {
+	// Seams are found on a copy of the image, keeping track of the original column of each pixel
+	tmp := &carver{pix: c.pix, edges: c.edges, bias: c.bias, w: c.w, h: c.h}
+	columns := make([][]int, c.h)
+	for y := range columns {
+		columns[y] = make([]int, c.w)
+		for x := range columns[y] {
+			columns[y][x] = x
+		}
+	}
+
+	duplicate := make([]bool, c.w*c.h)
+	for i := 0; i < n; i++ {
+		seam := tmp.carveSeam()
+		for y, x := range seam {
+			duplicate[y*c.w+columns[y][x]] = true
+			columns[y] = append(columns[y][:x], columns[y][x+1:]...)
+		}
+	}
+
+	w := c.w + n
+	pix := make([]uint8, w*c.h*4)
+	bias := make([]float64, w*c.h)
+
+	for y := 0; y < c.h; y++ {
+		dst := y * w
+		for x := 0; x < c.w; x++ {
+			src := y*c.w + x
+			copy(pix[dst*4:dst*4+4], c.pix[src*4:src*4+4])
+			bias[dst] = c.bias[src]
+			dst++
+
+			if !duplicate[src] {
+				continue
+			}
+
+			next := src
+			if x < c.w-1 {
+				next++
+			}
+			for i := 0; i < 4; i++ {
+				pix[dst*4+i] = uint8((int(c.pix[src*4+i]) + int(c.pix[next*4+i]) + 1) / 2)
+			}
+			bias[dst] = c.bias[src]
+			dst++
+		}
+	}
+
+	c.pix, c.bias, c.w = pix, bias, w
+	c.edges = make([]float64, c.w*c.h)
+	c.updateEdges(0, c.w)
+}
+
+// transpose returns a copy of the carver with its rows and columns swapped.
+func (c *carver) transpose() *carver {
+	t := &carver{pix: make([]uint8, len(c.pix)), bias: make([]float64, len(c.bias)), w: c.h, h: c.w}
+
+	for y := 0; y < c.h; y++ {
+		for x := 0; x < c.w; x++ {
+			src, dst := y*c.w+x, x*t.w+y
+			copy(t.pix[dst*4:dst*4+4], c.pix[src*4:src*4+4])
+			t.bias[dst] = c.bias[src]
+		}
+	}
+
+	return t
+}
@@ -0,0 +1,143 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+// stripeImage returns a flat gray image of size w and h, with a red column at x if vertical
+// is true or a red row at y otherwise.
+func stripeImage(w, h, pos int, vertical bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (vertical && x == pos) || (!vertical && y == pos) {
+				img.SetRGBA(x, y, color.RGBA{0xFF, 0x00, 0x00, 0xFF})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{0x80, 0x80, 0x80, 0xFF})
+			}
+		}
+	}
+	return img
+}
+
+// maskImage returns a mask of size w and h with the columns in cols fully set.
+func maskImage(w, h int, cols ...int) *image.Gray {
+	mask := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for _, x := range cols {
+			mask.SetGray(x, y, color.Gray{0xFF})
+		}
+	}
+	return mask
+}
+
+// countRed returns the number of red pixels in img.
+func countRed(img *image.RGBA) int {
+	var n int
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] == 0xFF && img.Pix[i+1] == 0x00 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSeamCarve(t *testing.T) {
+	cases := []struct {
+		description    string
+		width, height  int
+		options        *SeamCarveOptions
+		value          image.Image
+		expectedBounds image.Rectangle
+		expectedRed    int
+	}{
+		{
+			description:    "narrower keeps the high energy column",
+			width:          5,
+			height:         3,
+			value:          stripeImage(8, 3, 6, true),
+			expectedBounds: image.Rect(0, 0, 5, 3),
+			expectedRed:    3,
+		},
+		{
+			description:    "shorter keeps the high energy row",
+			width:          3,
+			height:         5,
+			value:          stripeImage(3, 8, 1, false),
+			expectedBounds: image.Rect(0, 0, 3, 5),
+			expectedRed:    3,
+		},
+		{
+			description:    "remove mask goes through the high energy column",
+			width:          7,
+			height:         3,
+			options:        &SeamCarveOptions{Remove: maskImage(8, 3, 6)},
+			value:          stripeImage(8, 3, 6, true),
+			expectedBounds: image.Rect(0, 0, 7, 3),
+			expectedRed:    0,
+		},
+		{
+			description:    "wider duplicates low energy seams",
+			width:          12,
+			height:         3,
+			value:          stripeImage(8, 3, 6, true),
+			expectedBounds: image.Rect(0, 0, 12, 3),
+			expectedRed:    3,
+		},
+		{
+			description:    "empty size",
+			width:          0,
+			height:         3,
+			value:          stripeImage(8, 3, 6, true),
+			expectedBounds: image.Rect(0, 0, 0, 0),
+			expectedRed:    0,
+		},
+	}
+
+	for _, c := range cases {
+		actual := SeamCarve(c.value, c.width, c.height, c.options)
+		if actual.Bounds() != c.expectedBounds {
+			t.Errorf("%s: expected bounds: %v actual: %v", "SeamCarve "+c.description, c.expectedBounds, actual.Bounds())
+		}
+		if red := countRed(actual); red != c.expectedRed {
+			t.Errorf("%s: expected %d red pixels, actual: %d\n%v", "SeamCarve "+c.description, c.expectedRed, red, util.RGBAToString(actual))
+		}
+	}
+}
+
+func TestSeamCarveProtect(t *testing.T) {
+	value := &image.RGBA{
+		Rect:   image.Rect(0, 0, 4, 2),
+		Stride: 16,
+		Pix: []uint8{
+			0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF, 0x30, 0x30, 0x30, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+			0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF, 0x30, 0x30, 0x30, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+		},
+	}
+
+	expected := &image.RGBA{
+		Rect:   image.Rect(0, 0, 3, 2),
+		Stride: 12,
+		Pix: []uint8{
+			0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+			0x10, 0x10, 0x10, 0xFF, 0x20, 0x20, 0x20, 0xFF, 0x40, 0x40, 0x40, 0xFF,
+		},
+	}
+
+	actual := SeamCarve(value, 3, 2, &SeamCarveOptions{Protect: maskImage(4, 2, 0, 1, 3)})
+	if !util.RGBAImageEqual(actual, expected) {
+		t.Errorf("%s:\nexpected:%v\nactual:%v", "SeamCarve protect", util.RGBAToString(expected), util.RGBAToString(actual))
+	}
+}
+
+func BenchmarkSeamCarve(b *testing.B) {
+	img := stripeImage(256, 256, 128, true)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = SeamCarve(img, 224, 224, nil)
+	}
+}
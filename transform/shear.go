@@ -5,7 +5,6 @@ import (
 	"math"
 
 	"github.com/anthonynsimon/bild/clone"
-	"github.com/anthonynsimon/bild/parallel"
 )
 
 // ShearH applies a shear linear transformation along the horizontal axis,
@@ -15,121 +14,47 @@ func ShearH(img image.Image, angle float64) *image.RGBA {
 	src := clone.AsShallowRGBA(img)
 	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
 
-	// Supersample, currently hard set to 2x
-	srcW, srcH = srcW*2, srcH*2
-	src = Resize(src, srcW, srcH, NearestNeighbor)
+	kx := shearFactor(angle)
+	dstW, dstH := srcW+shearExtent(srcH, kx), srcH
 
-	// Calculate shear factor
-	kx := math.Tan(angle * (math.Pi / 180))
-	if r := math.Round(kx); math.Abs(kx-r) < 1e-10 {
-		kx = r
-	}
-
-	dstW, dstH := srcW+int(math.Round(float64(srcH)*math.Abs(kx))), srcH
-	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
-
-	pivotX := float64(dstW) / 2
-	pivotY := float64(dstH) / 2
-
-	// Calculate offset since we are resizing the bounds to
-	// fit the sheared image.
-	dx := (dstW - srcW) / 2
-	dy := (dstH - srcH) / 2
-
-	parallel.Line(dstH, func(start, end int) {
-		for y := start; y < end; y++ {
-			for x := 0; x < dstW; x++ {
-				// Move positions to revolve around pivot
-				ix := x - int(pivotX) - dx
-				iy := y - int(pivotY) - dy
-
-				// Apply linear transformation
-				ix = ix + int(float64(iy)*kx)
+	m := IdentityAffine().
+		Translate(-float64(srcW)/2, -float64(srcH)/2).
+		Shear(-kx, 0).
+		Translate(float64(dstW)/2, float64(dstH)/2)
 
-				// Move positions back to image coordinates
-				ix += int(pivotX)
-				iy += int(pivotY)
-
-				if ix < 0 || ix >= srcW || iy < 0 || iy >= srcH {
-					continue
-				}
-
-				srcPos := iy*src.Stride + ix*4
-				dstPos := y*dst.Stride + x*4
-
-				dst.Pix[dstPos+0] = src.Pix[srcPos+0]
-				dst.Pix[dstPos+1] = src.Pix[srcPos+1]
-				dst.Pix[dstPos+2] = src.Pix[srcPos+2]
-				dst.Pix[dstPos+3] = src.Pix[srcPos+3]
-			}
-		}
-	})
-
-	// Downsample to original bounds as part of the Supersampling
-	dst = Resize(dst, dstW/2, dstH/2, Linear)
-
-	return dst
+	return AffineWarp(src, m, &AffineOptions{Bounds: image.Rect(0, 0, dstW, dstH)})
 }
 
 // ShearV applies a shear linear transformation along the vertical axis,
 // the parameter angle is the shear angle to be applied.
 // The transformation will be applied with the center of the image as the pivot.
 func ShearV(img image.Image, angle float64) *image.RGBA {
-	src := clone.AsRGBA(img)
+	src := clone.AsShallowRGBA(img)
 	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
 
-	// Supersample, currently hard set to 2x
-	srcW, srcH = srcW*2, srcH*2
-	src = Resize(src, srcW, srcH, NearestNeighbor)
+	ky := shearFactor(angle)
+	dstW, dstH := srcW, srcH+shearExtent(srcW, ky)
 
-	// Calculate shear factor
-	ky := math.Tan(angle * (math.Pi / 180))
-	if r := math.Round(ky); math.Abs(ky-r) < 1e-10 {
-		ky = r
-	}
-
-	dstW, dstH := srcW, srcH+int(math.Round(float64(srcW)*math.Abs(ky)))
-	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
-
-	pivotX := float64(dstW) / 2
-	pivotY := float64(dstH) / 2
-
-	// Calculate offset since we are resizing the bounds to
-	// fit the sheared image.
-	dx := (dstW - srcW) / 2
-	dy := (dstH - srcH) / 2
-
-	parallel.Line(dstH, func(start, end int) {
-		for y := start; y < end; y++ {
-			for x := 0; x < dstW; x++ {
-				// Move positions to revolve around pivot
-				ix := x - int(pivotX) - dx
-				iy := y - int(pivotY) - dy
-
-				// Apply linear transformation
-				iy = iy + int(float64(ix)*ky)
+	m := IdentityAffine().
+		Translate(-float64(srcW)/2, -float64(srcH)/2).
+		Shear(0, -ky).
+		Translate(float64(dstW)/2, float64(dstH)/2)
 
-				// Move positions back to image coordinates
-				ix += int(pivotX)
-				iy += int(pivotY)
-
-				if ix < 0 || ix >= srcW || iy < 0 || iy >= srcH {
-					continue
-				}
-
-				srcPos := iy*src.Stride + ix*4
-				dstPos := y*dst.Stride + x*4
-
-				dst.Pix[dstPos+0] = src.Pix[srcPos+0]
-				dst.Pix[dstPos+1] = src.Pix[srcPos+1]
-				dst.Pix[dstPos+2] = src.Pix[srcPos+2]
-				dst.Pix[dstPos+3] = src.Pix[srcPos+3]
-			}
-		}
-	})
+	return AffineWarp(src, m, &AffineOptions{Bounds: image.Rect(0, 0, dstW, dstH)})
+}
 
-	// Downsample to original bounds as part of the Supersampling
-	dst = Resize(dst, dstW/2, dstH/2, Linear)
+// shearFactor returns the shear factor for the angle in degrees.
+func shearFactor(angle float64) float64 {
+	k := math.Tan(angle * (math.Pi / 180))
+	if r := math.Round(k); math.Abs(k-r) < 1e-10 {
+		k = r
+	}
+	return k
+}
 
-	return dst
+// shearExtent returns the number of pixels added to the size of an image by a shear factor k applied
+// along a side of n pixels. The extent is rounded to half pixels first, which keeps the sizes of the
+// former 2x supersampled implementation.
+func shearExtent(n int, k float64) int {
+	return int(math.Round(2*float64(n)*math.Abs(k))) / 2
 }
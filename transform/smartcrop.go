@@ -0,0 +1,189 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/clone"
+	"github.com/anthonynsimon/bild/effect"
+	"github.com/anthonynsimon/bild/math/integer"
+	"github.com/anthonynsimon/bild/parallel"
+	"github.com/anthonynsimon/bild/util"
+)
+
+// smartCropAnalysisSize is the size of the longest side of the downscaled copy of the source image
+// in which the crop candidates are scored.
+const smartCropAnalysisSize = 256
+
+// Weights of each of the heuristics in the interest score of a pixel.
+const (
+	smartCropEdgeWeight       = 1.0
+	smartCropSaturationWeight = 0.3
+	smartCropSkinWeight       = 1.8
+)
+
+// SmartCrop returns a new image of the provided width and height containing the most interesting area
+// of the source image. The largest area with the aspect ratio of width and height is cropped at the position
+// that scores best by edge energy, color saturation and skin tones, and it's then scaled to the result size.
+//
+// Usage example:
+//
+//	// Social preview image
+//	result := transform.SmartCrop(img, 1200, 630)
+func SmartCrop(img image.Image, width, height int) *image.RGBA {
+	if width <= 0 || height <= 0 || img.Bounds().Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	rect := SmartCropRect(img, width, height)
+	src := clone.AsShallowRGBA(img).SubImage(rect)
+
+	return Resize(src, width, height, Linear)
+}
+
+// SmartCropRect returns the rectangle of the source image that SmartCrop would crop for the
+// provided width and height, before scaling it. The rectangle is in the coordinates of the source image.
+// An empty rectangle is returned if the width, height or source image are empty.
+//
+// Usage example:
+//
+//	rect := transform.SmartCropRect(img, 1200, 630)
+//	result := transform.Crop(img, rect)
+func SmartCropRect(img image.Image, width, height int) image.Rectangle {
+	bounds := img.Bounds()
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return image.Rectangle{}
+	}
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	cropW, cropH := fitSize(width, height, srcW, srcH)
+
+	// The crop window covers the whole image, so there are no candidates to choose from
+	if cropW == srcW && cropH == srcH {
+		return bounds
+	}
+
+	factor := math.Min(1, float64(smartCropAnalysisSize)/float64(integer.Max(srcW, srcH)))
+	analysisW := integer.Clamp(int(float64(srcW)*factor+0.5), 1, srcW)
+	analysisH := integer.Clamp(int(float64(srcH)*factor+0.5), 1, srcH)
+	scaleX, scaleY := float64(analysisW)/float64(srcW), float64(analysisH)/float64(srcH)
+
+	analysis := img
+	if analysisW != srcW || analysisH != srcH {
+		analysis = Resize(img, analysisW, analysisH, Box)
+	}
+	sums := newSummedArea(interestMap(analysis))
+
+	winW := integer.Clamp(int(float64(cropW)*scaleX+0.5), 1, analysisW)
+	winH := integer.Clamp(int(float64(cropH)*scaleY+0.5), 1, analysisH)
+
+	// The score of the window adds up nested areas around its center, so that
+	// interesting areas are kept away from the edges of the crop.
+	bestScore := math.Inf(-1)
+	var bestX, bestY int
+	bestDist := math.Inf(1)
+	for y := 0; y <= analysisH-winH; y++ {
+		for x := 0; x <= analysisW-winW; x++ {
+			score := sums.sum(x, y, x+winW, y+winH) +
+				sums.sum(x+winW/4, y+winH/4, x+winW-winW/4, y+winH-winH/4) +
+				sums.sum(x+winW*3/8, y+winH*3/8, x+winW-winW*3/8, y+winH-winH*3/8)
+
+			// Ties are solved in favour of the most centered window
+			dist := math.Abs(float64(2*x+winW-analysisW)) + math.Abs(float64(2*y+winH-analysisH))
+			if score > bestScore || (score == bestScore && dist < bestDist) {
+				bestScore, bestX, bestY, bestDist = score, x, y, dist
+			}
+		}
+	}
+
+	x := integer.Clamp(int(float64(bestX)/scaleX+0.5), 0, srcW-cropW)
+	y := integer.Clamp(int(float64(bestY)/scaleY+0.5), 0, srcH-cropH)
+
+	origin := bounds.Min.Add(image.Pt(x, y))
+	return image.Rectangle{origin, origin.Add(image.Pt(cropW, cropH))}
+}
+
+// interestMap returns the interest score of each pixel of img in row-major order, along with the image size.
+func interestMap(img image.Image) ([]float64, int, int) {
+	src := clone.AsShallowRGBA(img)
+	edges := effect.Sobel(src)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	scores := make([]float64, w*h)
+	parallel.Line(h, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < w; x++ {
+				pos := y*src.Stride + x*4
+				c := color.RGBA{src.Pix[pos], src.Pix[pos+1], src.Pix[pos+2], src.Pix[pos+3]}
+				if c.A == 0 {
+					continue
+				}
+
+				epos := y*edges.Stride + x*4
+				edge := (0.299*float64(edges.Pix[epos]) + 0.587*float64(edges.Pix[epos+1]) + 0.114*float64(edges.Pix[epos+2])) / 255
+				scores[y*w+x] = smartCropEdgeWeight*edge +
+					smartCropSaturationWeight*saturationScore(c) +
+					smartCropSkinWeight*skinScore(c)
+			}
+		}
+	})
+
+	return scores, w, h
+}
+
+// saturationScore returns a value between 0.0 and 1.0 that grows with the saturation of vivid colors.
+func saturationScore(c color.RGBA) float64 {
+	_, s, l := util.RGBToHSL(c)
+	if s < 0.4 || l < 0.05 || l > 0.9 {
+		return 0
+	}
+	return (s - 0.4) / 0.6
+}
+
+// skinScore returns a value between 0.0 and 1.0 for how close c is to a skin tone.
+func skinScore(c color.RGBA) float64 {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	mag := math.Sqrt(r*r + g*g + b*b)
+	if mag == 0 {
+		return 0
+	}
+
+	_, _, l := util.RGBToHSL(c)
+	if l < 0.2 {
+		return 0
+	}
+
+	// Distance between the chromaticity of c and a reference skin tone
+	dr, dg, db := r/mag-0.78, g/mag-0.57, b/mag-0.44
+	skin := 1 - math.Sqrt(dr*dr+dg*dg+db*db)
+	if skin < 0.8 {
+		return 0
+	}
+	return (skin - 0.8) / 0.2
+}
+
+// summedArea is a summed-area table, which allows to get the sum of any rectangle of values in constant time.
+type summedArea struct {
+	values []float64
+	stride int
+}
+
+// newSummedArea returns the summed-area table of the values of a row-major w by h grid.
+func newSummedArea(values []float64, w, h int) *summedArea {
+	stride := w + 1
+	s := &summedArea{values: make([]float64, stride*(h+1)), stride: stride}
+	for y := 0; y < h; y++ {
+		var row float64
+		for x := 0; x < w; x++ {
+			row += values[y*w+x]
+			s.values[(y+1)*stride+x+1] = s.values[y*stride+x+1] + row
+		}
+	}
+	return s
+}
+
+// sum returns the sum of the values in the rectangle from x0, y0 (inclusive) to x1, y1 (exclusive).
+func (s *summedArea) sum(x0, y0, x1, y1 int) float64 {
+	return s.values[y1*s.stride+x1] - s.values[y0*s.stride+x1] - s.values[y1*s.stride+x0] + s.values[y0*s.stride+x0]
+}
@@ -0,0 +1,132 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// detailImage returns a flat gray image of size w and h with a checkered area of size d at x, y.
+func detailImage(w, h, x, y, d int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for iy := 0; iy < h; iy++ {
+		for ix := 0; ix < w; ix++ {
+			c := color.RGBA{0x80, 0x80, 0x80, 0xFF}
+			if ix >= x && ix < x+d && iy >= y && iy < y+d && (ix+iy)%2 == 0 {
+				c = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+			}
+			img.SetRGBA(ix, iy, c)
+		}
+	}
+	return img
+}
+
+func TestSmartCropRect(t *testing.T) {
+	cases := []struct {
+		description   string
+		width, height int
+		value         image.Image
+		expected      image.Rectangle
+	}{
+		{
+			description: "detail on the right",
+			width:       100,
+			height:      100,
+			value:       detailImage(300, 100, 230, 40, 20),
+			expected:    image.Rect(188, 0, 288, 100),
+		},
+		{
+			description: "detail at the top",
+			width:       200,
+			height:      100,
+			value:       detailImage(100, 200, 40, 10, 20),
+			expected:    image.Rect(0, 0, 100, 50),
+		},
+		{
+			description: "detail near the edge keeps the window inside the image",
+			width:       50,
+			height:      100,
+			value:       detailImage(300, 100, 0, 40, 10),
+			expected:    image.Rect(0, 0, 50, 100),
+		},
+		{
+			description: "flat image is cropped at the center",
+			width:       100,
+			height:      100,
+			value:       detailImage(300, 100, 0, 0, 0),
+			expected:    image.Rect(100, 0, 200, 100),
+		},
+		{
+			description: "same aspect ratio",
+			width:       30,
+			height:      10,
+			value:       detailImage(300, 100, 0, 0, 0),
+			expected:    image.Rect(0, 0, 300, 100),
+		},
+		{
+			description: "offset bounds",
+			width:       100,
+			height:      100,
+			value:       detailImage(400, 100, 330, 40, 20).SubImage(image.Rect(100, 0, 400, 100)),
+			expected:    image.Rect(288, 0, 388, 100),
+		},
+		{
+			description: "large image is analysed downscaled",
+			width:       100,
+			height:      100,
+			value:       detailImage(1200, 400, 100, 150, 80),
+			expected:    image.Rect(0, 0, 400, 400),
+		},
+		{
+			description: "empty size",
+			width:       0,
+			height:      100,
+			value:       detailImage(300, 100, 0, 0, 0),
+			expected:    image.Rectangle{},
+		},
+	}
+
+	for _, c := range cases {
+		actual := SmartCropRect(c.value, c.width, c.height)
+		if actual != c.expected {
+			t.Errorf("%s: expected: %v actual: %v", "SmartCropRect "+c.description, c.expected, actual)
+		}
+	}
+}
+
+func TestSmartCrop(t *testing.T) {
+	img := detailImage(300, 100, 230, 40, 20)
+	actual := SmartCrop(img, 50, 50)
+
+	if actual.Bounds() != image.Rect(0, 0, 50, 50) {
+		t.Errorf("SmartCrop: expected bounds: %v actual: %v", image.Rect(0, 0, 50, 50), actual.Bounds())
+	}
+}
+
+func TestSkinScore(t *testing.T) {
+	cases := []struct {
+		value    color.RGBA
+		expected bool
+	}{
+		{value: color.RGBA{0xE0, 0xAC, 0x69, 0xFF}, expected: true},
+		{value: color.RGBA{0xC6, 0x86, 0x42, 0xFF}, expected: true},
+		{value: color.RGBA{0x20, 0x80, 0xF0, 0xFF}, expected: false},
+		{value: color.RGBA{0x80, 0x80, 0x80, 0xFF}, expected: false},
+		{value: color.RGBA{0x10, 0x0A, 0x08, 0xFF}, expected: false},
+	}
+
+	for _, c := range cases {
+		actual := skinScore(c.value) > 0
+		if actual != c.expected {
+			t.Errorf("%s: %v expected: %v actual: %v", "skinScore", c.value, c.expected, actual)
+		}
+	}
+}
+
+func BenchmarkSmartCrop(b *testing.B) {
+	img := detailImage(1600, 900, 1200, 300, 100)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchResult = SmartCrop(img, 256, 256)
+	}
+}
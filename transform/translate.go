@@ -4,7 +4,6 @@ import (
 	"image"
 
 	"github.com/anthonynsimon/bild/clone"
-	"github.com/anthonynsimon/bild/parallel"
 )
 
 // Translate repositions a copy of the provided image by dx on the x-axis and
@@ -19,25 +18,7 @@ func Translate(img image.Image, dx, dy int) *image.RGBA {
 		return src
 	}
 
-	w, h := src.Bounds().Dx(), src.Bounds().Dy()
-	dst := image.NewRGBA(src.Bounds())
+	m := IdentityAffine().Translate(float64(dx), -float64(dy))
 
-	parallel.Line(h, func(start, end int) {
-		for y := start; y < end; y++ {
-			for x := 0; x < w; x++ {
-				ix, iy := x-dx, y+dy
-
-				if ix < 0 || ix >= w || iy < 0 || iy >= h {
-					continue
-				}
-
-				srcPos := iy*src.Stride + ix*4
-				dstPos := y*src.Stride + x*4
-
-				copy(dst.Pix[dstPos:dstPos+4], src.Pix[srcPos:srcPos+4])
-			}
-		}
-	})
-
-	return dst
+	return AffineWarp(src, m, &AffineOptions{Filter: &NearestNeighbor})
 }
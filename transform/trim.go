@@ -0,0 +1,117 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/anthonynsimon/bild/clone"
+)
+
+// TrimOptions are the trim parameters.
+// Border is the color of the margins to be trimmed. Default of the color of the top-left pixel
+// is used if nil is passed. Transparent margins are trimmed regardless of the color of their pixels.
+// Tolerance is of the range 0 to 255 and it represents the max amount of difference between a pixel
+// and the border color for it to be considered part of the margin, as in paint.FloodFill.
+type TrimOptions struct {
+	Border    color.Color
+	Tolerance uint8
+}
+
+// Trim returns a new image without the uniform margins around its content, along with the
+// rectangle of the source image that was kept, as it would be passed to Crop.
+// If the whole image matches the border color, an empty image and rectangle are returned.
+// Default parameters are used if a nil *TrimOptions is passed.
+//
+// Usage example:
+//
+//	// Remove the white margins of a scanned page, ignoring slight noise
+//	result, rect := transform.Trim(img, &transform.TrimOptions{Border: color.White, Tolerance: 16})
+func Trim(img image.Image, options *TrimOptions) (*image.RGBA, image.Rectangle) {
+	src := clone.AsShallowRGBA(img)
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0)), image.Rectangle{}
+	}
+
+	var border color.RGBA
+	var tolerance uint8
+	if options != nil && options.Border != nil {
+		border = color.RGBAModel.Convert(options.Border).(color.RGBA)
+	} else {
+		border = src.RGBAAt(bounds.Min.X, bounds.Min.Y)
+	}
+	if options != nil {
+		tolerance = options.Tolerance
+	}
+
+	rect := trimRect(src, border, tolerance)
+	if rect.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0)), image.Rectangle{}
+	}
+
+	return Crop(src, rect), rect
+}
+
+// trimRect returns the bounding box of the pixels of src that don't match the border color.
+func trimRect(src *image.RGBA, border color.RGBA, tolerance uint8) image.Rectangle {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	t := int(tolerance) * int(tolerance)
+
+	isBorder := func(x, y int) bool {
+		pos := y*src.Stride + x*4
+		// Fully transparent pixels are part of the margins whatever the border color
+		if src.Pix[pos+3] == 0 {
+			return true
+		}
+		dr := int(src.Pix[pos+0]) - int(border.R)
+		dg := int(src.Pix[pos+1]) - int(border.G)
+		db := int(src.Pix[pos+2]) - int(border.B)
+		da := int(src.Pix[pos+3]) - int(border.A)
+		return dr*dr+dg*dg+db*db+da*da <= t
+	}
+
+	rowIsBorder := func(y int) bool {
+		for x := 0; x < w; x++ {
+			if !isBorder(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	top := 0
+	for top < h && rowIsBorder(top) {
+		top++
+	}
+	if top == h {
+		return image.Rectangle{}
+	}
+
+	bottom := h
+	for rowIsBorder(bottom - 1) {
+		bottom--
+	}
+
+	// Only the rows with content need to be scanned for the side margins
+	colIsBorder := func(x int) bool {
+		for y := top; y < bottom; y++ {
+			if !isBorder(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	left := 0
+	for colIsBorder(left) {
+		left++
+	}
+
+	right := w
+	for colIsBorder(right - 1) {
+		right--
+	}
+
+	return image.Rect(left, top, right, bottom).Add(bounds.Min)
+}
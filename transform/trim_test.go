@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/anthonynsimon/bild/util"
+)
+
+func TestTrim(t *testing.T) {
+	cases := []struct {
+		description  string
+		options      *TrimOptions
+		value        image.Image
+		expected     *image.RGBA
+		expectedRect image.Rectangle
+	}{
+		{
+			description: "white margins, default border color",
+			options:     nil,
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 4, 3),
+				Stride: 16,
+				Pix: []uint8{
+					0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0x10, 0x20, 0x30, 0xFF, 0x40, 0x50, 0x60, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(1, 1, 3, 2),
+				Stride: 8,
+				Pix: []uint8{
+					0x10, 0x20, 0x30, 0xFF, 0x40, 0x50, 0x60, 0xFF,
+				},
+			},
+			expectedRect: image.Rect(1, 1, 3, 2),
+		},
+		{
+			description: "noisy margins within tolerance",
+			options:     &TrimOptions{Border: color.White, Tolerance: 16},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 3, 2),
+				Stride: 12,
+				Pix: []uint8{
+					0xF8, 0xF8, 0xF8, 0xFF, 0x80, 0x80, 0x80, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+					0xFF, 0xFF, 0xFF, 0xFF, 0x80, 0x80, 0x80, 0xFF, 0xFA, 0xFF, 0xFF, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(1, 0, 2, 2),
+				Stride: 4,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF,
+					0x80, 0x80, 0x80, 0xFF,
+				},
+			},
+			expectedRect: image.Rect(1, 0, 2, 2),
+		},
+		{
+			description: "transparent margins with any color",
+			options:     &TrimOptions{Border: color.Transparent},
+			value: &image.NRGBA{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 12,
+				Pix: []uint8{
+					0xFF, 0x00, 0x00, 0x00, 0x80, 0x80, 0x80, 0xFF, 0x00, 0xFF, 0x00, 0x00,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(1, 0, 2, 1),
+				Stride: 4,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF,
+				},
+			},
+			expectedRect: image.Rect(1, 0, 2, 1),
+		},
+		{
+			description: "transparent and white margins, white border color",
+			options:     &TrimOptions{Border: color.White},
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 4, 1),
+				Stride: 16,
+				Pix: []uint8{
+					0x00, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0x80, 0x80, 0x80, 0xFF, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(2, 0, 3, 1),
+				Stride: 4,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF,
+				},
+			},
+			expectedRect: image.Rect(2, 0, 3, 1),
+		},
+		{
+			description: "offset bounds",
+			options:     nil,
+			value: (&image.RGBA{
+				Rect:   image.Rect(0, 0, 4, 1),
+				Stride: 16,
+				Pix: []uint8{
+					0x00, 0x00, 0x00, 0xFF, 0x10, 0x10, 0x10, 0xFF, 0x10, 0x10, 0x10, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+				},
+			}).SubImage(image.Rect(1, 0, 4, 1)),
+			expected: &image.RGBA{
+				Rect:   image.Rect(3, 0, 4, 1),
+				Stride: 4,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF,
+				},
+			},
+			expectedRect: image.Rect(3, 0, 4, 1),
+		},
+		{
+			description: "uniform image",
+			options:     nil,
+			value: &image.RGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 8,
+				Pix: []uint8{
+					0x80, 0x80, 0x80, 0xFF, 0x80, 0x80, 0x80, 0xFF,
+				},
+			},
+			expected: &image.RGBA{
+				Rect:   image.Rect(0, 0, 0, 0),
+				Stride: 0,
+				Pix:    []uint8{},
+			},
+			expectedRect: image.Rectangle{},
+		},
+	}
+
+	for _, c := range cases {
+		actual, rect := Trim(c.value, c.options)
+		if !util.RGBAImageEqual(actual, c.expected) {
+			t.Errorf("%s:\nexpected:%v\nactual:%v", "Trim "+c.description, util.RGBAToString(c.expected), util.RGBAToString(actual))
+		}
+		if rect != c.expectedRect {
+			t.Errorf("%s: expected rect: %v actual: %v", "Trim "+c.description, c.expectedRect, rect)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/parallel"
+)
+
+// maxFilterTaps is the number of filter weights that can be cached per dimension
+// without allocating, enough for all the provided filters.
+const maxFilterTaps = 16
+
+// warp returns a new image of the given width and height in which every pixel is
+// sampled from src at the position returned by fn.
+// Positions are continuous coordinates where the center of pixel (x, y) lies at (x+0.5, y+0.5).
+// Pixels for which fn returns false are set to bg, and so are the sample points that fall outside of src,
+// which blends the edges of the warped image into the background.
+func warp(src *image.RGBA, width, height int, fn func(x, y float64) (float64, float64, bool), filter ResampleFilter, bg color.RGBA) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bgValues := [4]float64{float64(bg.R), float64(bg.G), float64(bg.B), float64(bg.A)}
+
+	// The cost of the pixels varies with the area of src they map onto, and with whether they fall
+	// outside of it, so tiles balance the work better than whole rows
+	parallel.Tiles(dst.Bounds(), func(tile image.Rectangle) {
+		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+			for x := tile.Min.X; x < tile.Max.X; x++ {
+				pos := y*dst.Stride + x*4
+
+				c := bgValues
+				if ix, iy, ok := fn(float64(x)+0.5, float64(y)+0.5); ok {
+					c = sample(src, ix-0.5, iy-0.5, filter, bgValues)
+				}
+
+				setPremultiplied(dst.Pix[pos:pos+4], c[0], c[1], c[2], c[3])
+			}
+		}
+	})
+
+	return dst
+}
+
+// sample returns the RGBA values of src at the pixel position x, y interpolated using the filter.
+// Integer positions fall on the pixel centers. Sample points outside of the image take the bg values.
+func sample(src *image.RGBA, x, y float64, filter ResampleFilter, bg [4]float64) [4]float64 {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// Also catches NaN positions, as any comparison with them is false
+	if !(x > -filter.Support-0.5 && x < float64(srcW)+filter.Support-0.5 &&
+		y > -filter.Support-0.5 && y < float64(srcH)+filter.Support-0.5) {
+		return bg
+	}
+
+	// NearestNeighbor is a special case, simply pick the closest pixel.
+	if filter.Support <= 0 {
+		ix, iy := int(math.Floor(x+0.5)), int(math.Floor(y+0.5))
+		if ix < 0 || ix >= srcW || iy < 0 || iy >= srcH {
+			return bg
+		}
+		pos := iy*src.Stride + ix*4
+		return [4]float64{
+			float64(src.Pix[pos+0]),
+			float64(src.Pix[pos+1]),
+			float64(src.Pix[pos+2]),
+			float64(src.Pix[pos+3]),
+		}
+	}
+
+	xstart, xend := int(math.Ceil(x-filter.Support)), int(math.Floor(x+filter.Support))
+	ystart, yend := int(math.Ceil(y-filter.Support)), int(math.Floor(y+filter.Support))
+
+	var wxCache [maxFilterTaps]float64
+	wx := wxCache[:0]
+	if xend-xstart+1 > maxFilterTaps {
+		wx = make([]float64, 0, xend-xstart+1)
+	}
+	for kx := xstart; kx <= xend; kx++ {
+		wx = append(wx, filter.Fn(float64(kx)-x))
+	}
+
+	var c [4]float64
+	var sum float64
+	for ky := ystart; ky <= yend; ky++ {
+		wy := filter.Fn(float64(ky) - y)
+		if wy == 0 {
+			continue
+		}
+		for i, kx := 0, xstart; kx <= xend; i, kx = i+1, kx+1 {
+			w := wx[i] * wy
+			if w == 0 {
+				continue
+			}
+			sum += w
+
+			if kx < 0 || kx >= srcW || ky < 0 || ky >= srcH {
+				c[0] += bg[0] * w
+				c[1] += bg[1] * w
+				c[2] += bg[2] * w
+				c[3] += bg[3] * w
+				continue
+			}
+
+			pos := ky*src.Stride + kx*4
+			c[0] += float64(src.Pix[pos+0]) * w
+			c[1] += float64(src.Pix[pos+1]) * w
+			c[2] += float64(src.Pix[pos+2]) * w
+			c[3] += float64(src.Pix[pos+3]) * w
+		}
+	}
+
+	if sum == 0 {
+		return sample(src, x, y, NearestNeighbor, bg)
+	}
+
+	c[0] /= sum
+	c[1] /= sum
+	c[2] /= sum
+	c[3] /= sum
+
+	return c
+}
@@ -4,7 +4,6 @@ import (
 	"image"
 
 	"github.com/anthonynsimon/bild/clone"
-	"github.com/anthonynsimon/bild/parallel"
 )
 
 // ZoomOptions are the zoom parameters.
@@ -54,39 +53,15 @@ func Zoom(img image.Image, factor float64, options *ZoomOptions) *image.RGBA {
 	} else {
 		dstW, dstH = srcW, srcH
 	}
-	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
 
 	// Calculate offsets when resizing bounds to center the zoomed content
-	offsetX := (dstW - srcW) / 2
-	offsetY := (dstH - srcH) / 2
+	offsetX := float64((dstW - srcW) / 2)
+	offsetY := float64((dstH - srcH) / 2)
 
-	invFactor := 1.0 / factor
+	m := IdentityAffine().
+		Translate(-pivotX, -pivotY).
+		Scale(factor, factor).
+		Translate(pivotX+offsetX, pivotY+offsetY)
 
-	parallel.Line(dstH, func(start, end int) {
-		for y := start; y < end; y++ {
-			for x := 0; x < dstW; x++ {
-				// Map destination pixel back to source using inverse zoom
-				// Adjust for offset when bounds are resized
-				dx := float64(x-offsetX) - pivotX + 0.5
-				dy := float64(y-offsetY) - pivotY + 0.5
-
-				ix := int(dx*invFactor + pivotX)
-				iy := int(dy*invFactor + pivotY)
-
-				if ix < 0 || ix >= srcW || iy < 0 || iy >= srcH {
-					continue
-				}
-
-				srcPos := iy*src.Stride + ix*4
-				dstPos := y*dst.Stride + x*4
-
-				dst.Pix[dstPos+0] = src.Pix[srcPos+0]
-				dst.Pix[dstPos+1] = src.Pix[srcPos+1]
-				dst.Pix[dstPos+2] = src.Pix[srcPos+2]
-				dst.Pix[dstPos+3] = src.Pix[srcPos+3]
-			}
-		}
-	})
-
-	return dst
+	return AffineWarp(src, m, &AffineOptions{Filter: &NearestNeighbor, Bounds: image.Rect(0, 0, dstW, dstH)})
 }
@@ -193,6 +193,14 @@ func TestZoom(t *testing.T) {
 	}
 }
 
+func TestZoomTransparentEdges(t *testing.T) {
+	img := transparentEdgeImage(16, 16)
+	for _, factor := range []float64{0.5, 1.5, 3} {
+		checkNoHalo(t, "Zoom", Zoom(img, factor, nil))
+		checkNoHalo(t, "Zoom resize bounds", Zoom(img, factor, &ZoomOptions{ResizeBounds: true}))
+	}
+}
+
 func BenchmarkZoom256(b *testing.B) {
 	benchZoom(256, 256, 2.0, b)
 }
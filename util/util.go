@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
+
+	"github.com/anthonynsimon/bild/fcolor"
 )
 
 // SortRGBA sorts a slice of RGBA values.
@@ -122,3 +125,50 @@ func RGBAImageApproxEqual(a, b *image.RGBA, tolerance int) bool {
 	}
 	return true
 }
+
+// RGBA64ImageEqual returns true if the parameter images a and b match
+// or false if otherwise.
+func RGBA64ImageEqual(a, b *image.RGBA64) bool {
+	return RGBA64ImageApproxEqual(a, b, 0)
+}
+
+// RGBA64ImageApproxEqual returns true if all 16-bit pixel channel values in images a and b
+// differ by at most the given tolerance, or false otherwise.
+func RGBA64ImageApproxEqual(a, b *image.RGBA64, tolerance int) bool {
+	if !a.Rect.Eq(b.Rect) {
+		return false
+	}
+
+	for y := 0; y < a.Bounds().Dy(); y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			posA, posB := y*a.Stride+x*8, y*b.Stride+x*8
+			for c := 0; c < 8; c += 2 {
+				d := (int(a.Pix[posA+c])<<8 | int(a.Pix[posA+c+1])) - (int(b.Pix[posB+c])<<8 | int(b.Pix[posB+c+1]))
+				if d < -tolerance || d > tolerance {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// F64ImageApproxEqual returns true if all pixel channel values in the float images a and b
+// differ by at most the given tolerance, or false otherwise.
+func F64ImageApproxEqual(a, b *fcolor.Image, tolerance float64) bool {
+	if !a.Rect.Eq(b.Rect) {
+		return false
+	}
+
+	for y := 0; y < a.Bounds().Dy(); y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			posA, posB := y*a.Stride+x*4, y*b.Stride+x*4
+			for c := 0; c < 4; c++ {
+				if !(math.Abs(a.Pix[posA+c]-b.Pix[posB+c]) <= tolerance) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
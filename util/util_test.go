@@ -5,6 +5,8 @@ import (
 	"image/color"
 	"math"
 	"testing"
+
+	"github.com/anthonynsimon/bild/fcolor"
 )
 
 func TestQuickSortRGBA(t *testing.T) {
@@ -344,3 +346,97 @@ func TestRGBAImageEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestF64ImageApproxEqual(t *testing.T) {
+	cases := []struct {
+		a         *fcolor.Image
+		b         *fcolor.Image
+		tolerance float64
+		expected  bool
+	}{
+		{
+			a:         &fcolor.Image{},
+			b:         &fcolor.Image{},
+			tolerance: 0,
+			expected:  true,
+		},
+		{
+			a:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{1.5, 0, 0, 1}},
+			b:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{1.5001, 0, 0, 1}},
+			tolerance: 0.001,
+			expected:  true,
+		},
+		{
+			a:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{1.5, 0, 0, 1}},
+			b:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{1.6, 0, 0, 1}},
+			tolerance: 0.001,
+			expected:  false,
+		},
+		{
+			a:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{0, 0, math.NaN(), 1}},
+			b:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{0, 0, 0, 1}},
+			tolerance: 1,
+			expected:  false,
+		},
+		{
+			a:         &fcolor.Image{Rect: image.Rect(0, 0, 1, 1), Stride: 4, Pix: []float64{0, 0, 0, 1}},
+			b:         &fcolor.Image{Rect: image.Rect(1, 1, 2, 2), Stride: 4, Pix: []float64{0, 0, 0, 1}},
+			tolerance: 1,
+			expected:  false,
+		},
+	}
+
+	for _, c := range cases {
+		actual := F64ImageApproxEqual(c.a, c.b, c.tolerance)
+		if actual != c.expected {
+			t.Errorf("%s: expected: %v actual: %v", "F64ImageApproxEqual", c.expected, actual)
+		}
+	}
+}
+
+func TestRGBA64ImageApproxEqual(t *testing.T) {
+	cases := []struct {
+		a         *image.RGBA64
+		b         *image.RGBA64
+		tolerance int
+		expected  bool
+	}{
+		{
+			a:         &image.RGBA64{},
+			b:         &image.RGBA64{},
+			tolerance: 0,
+			expected:  true,
+		},
+		{
+			a:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x12, 0x34, 0, 0, 0, 0, 0xFF, 0xFF}},
+			b:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x12, 0x34, 0, 0, 0, 0, 0xFF, 0xFF}},
+			tolerance: 0,
+			expected:  true,
+		},
+		{
+			a:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x12, 0x34, 0, 0, 0, 0, 0xFF, 0xFF}},
+			b:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x12, 0x36, 0, 0, 0, 0, 0xFF, 0xFF}},
+			tolerance: 2,
+			expected:  true,
+		},
+		{
+			a:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x12, 0x34, 0, 0, 0, 0, 0xFF, 0xFF}},
+			b:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0x13, 0x34, 0, 0, 0, 0, 0xFF, 0xFF}},
+			tolerance: 2,
+			expected:  false,
+		},
+		{
+			a:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0, 0, 0, 0, 0, 0, 0xFF, 0xFF}},
+			b:         &image.RGBA64{Rect: image.Rect(0, 0, 1, 1), Stride: 8, Pix: []uint8{0, 0, 0, 0, 0, 0, 0xFF, 0xFE}},
+			tolerance: 0,
+			expected:  false,
+		},
+	}
+
+	for _, c := range cases {
+		actual := RGBA64ImageApproxEqual(c.a, c.b, c.tolerance)
+		if actual != c.expected {
+			t.Errorf("%s: expected: %v actual: %v", "RGBA64ImageApproxEqual", c.expected, actual)
+		}
+	}
+}